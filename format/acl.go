@@ -305,8 +305,7 @@ func (acls ACLs) formatTextByPrincipal(w *tabwriter.Writer) error {
 
 // FormatText implements the Formatter interface for ACLs
 func (acls ACLs) FormatText(config Config) error {
-	w := new(tabwriter.Writer)
-	w.Init(config.Output, 2, 8, 1, '\t', 0)
+	w := newTabWriter(config.Output)
 	var err error
 	switch config.ACLOrder {
 	case "resource":
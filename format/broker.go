@@ -4,7 +4,6 @@ import (
 	"encoding/json"
 	"fmt"
 	"github.com/Shopify/sarama"
-	"text/tabwriter"
 )
 
 // Brokers is a type alias
@@ -12,8 +11,7 @@ type Brokers []*sarama.Broker
 
 // FormatText outputs the broker overview tab separated
 func (brokers Brokers) FormatText(config Config) error {
-	w := new(tabwriter.Writer)
-	w.Init(config.Output, 0, 8, 0, '\t', 0)
+	w := newTabWriter(config.Output)
 	_, err := fmt.Fprintln(w, "Id\tAddress\tRack")
 	if err != nil {
 		return err
@@ -0,0 +1,64 @@
+package format
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// outcomes of a topic in a bulk operation
+const (
+	StatusCreated   = "created"
+	StatusDeleted   = "deleted"
+	StatusValidated = "validated"
+	StatusFailed    = "failed"
+	StatusCanceled  = "canceled"
+)
+
+// TopicResult is the outcome of a bulk operation for a single topic
+type TopicResult struct {
+	Topic  string `json:"topic"`
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// BulkResult holds the outcome for each topic of a bulk operation in the order they were given
+type BulkResult struct {
+	Operation string        `json:"operation"`
+	Results   []TopicResult `json:"results"`
+}
+
+// Failed returns the number of topics that failed or were canceled
+func (b BulkResult) Failed() int {
+	failed := 0
+	for _, r := range b.Results {
+		if r.Status == StatusFailed || r.Status == StatusCanceled {
+			failed++
+		}
+	}
+	return failed
+}
+
+// FormatText outputs the outcome per topic tab separated
+func (b BulkResult) FormatText(config Config) error {
+	w := newTabWriter(config.Output)
+	_, err := fmt.Fprintln(w, "Topic\tStatus\tError")
+	if err != nil {
+		return err
+	}
+	for _, r := range b.Results {
+		_, err := fmt.Fprintf(w, "%s\t%s\t%s\n", r.Topic, r.Status, r.Error)
+		if err != nil {
+			return err
+		}
+	}
+	return w.Flush()
+}
+
+// FormatJSON implements the Formatter interface for BulkResult
+func (b BulkResult) FormatJSON(config Config) error {
+	enc := json.NewEncoder(config.Output)
+	if err := enc.Encode(b); err != nil {
+		return err
+	}
+	return nil
+}
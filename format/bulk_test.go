@@ -0,0 +1,36 @@
+package format_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/izolight/kafkalib/format"
+)
+
+func TestBulkResult_Format(t *testing.T) {
+	result := format.BulkResult{Operation: "create", Results: []format.TopicResult{
+		{Topic: "newTopic", Status: format.StatusCreated},
+		{Topic: "simpleTopic", Status: format.StatusFailed, Error: "topic exists"},
+	}}
+	testCases := []struct {
+		format   string
+		expected string
+	}{
+		{"text", "Topic\t\tStatus\tError\n" +
+			"newTopic\tcreated\t\n" +
+			"simpleTopic\tfailed\ttopic exists"},
+		{"json", `{"operation":"create","results":[{"topic":"newTopic","status":"created"},{"topic":"simpleTopic","status":"failed","error":"topic exists"}]}`},
+	}
+	for _, tc := range testCases {
+		output := new(bytes.Buffer)
+		format.Format(result, format.Config{Output: output, Format: tc.format})
+		got := strings.TrimSuffix(output.String(), "\n")
+		if got != tc.expected {
+			t.Errorf("result.Format(%s):\nGot:\t%q\nWant:\t%q", tc.format, got, tc.expected)
+		}
+	}
+	if result.Failed() != 1 {
+		t.Errorf("Expected 1 failed topic but got %d", result.Failed())
+	}
+}
@@ -0,0 +1,70 @@
+package format
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+)
+
+// CloneACL is an acl of the source topic that is recreated for the clone
+type CloneACL struct {
+	Principal  string     `json:"principal"`
+	Host       string     `json:"host"`
+	Operation  Operation  `json:"operation"`
+	Permission Permission `json:"permission"`
+}
+
+// TopicClone describes the topic created from a source topic
+type TopicClone struct {
+	Source            string            `json:"source"`
+	Topic             string            `json:"topic"`
+	Partitions        int32             `json:"partitions"`
+	ReplicationFactor int16             `json:"replicationFactor"`
+	Config            map[string]string `json:"config"`
+	// ACLs holds the copied acls, if copying failed only the ones created before the failure
+	ACLs []CloneACL `json:"acls,omitempty"`
+	// DryRun is set if the clone was only validated and not created
+	DryRun bool `json:"dryRun"`
+	// Incomplete is set if the topic was created but copying its acls failed
+	Incomplete bool `json:"incomplete,omitempty"`
+}
+
+// FormatText outputs the definition of the clone as tab separated key value pairs
+func (t TopicClone) FormatText(config Config) error {
+	w := newTabWriter(config.Output)
+	lines := []string{
+		fmt.Sprintf("Source\t%s", t.Source),
+		fmt.Sprintf("Topic\t%s", t.Topic),
+		fmt.Sprintf("Partitions\t%d", t.Partitions),
+		fmt.Sprintf("Replication Factor\t%d", t.ReplicationFactor),
+	}
+	names := make([]string, 0, len(t.Config))
+	for name := range t.Config {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		lines = append(lines, fmt.Sprintf("Config\t%s=%s", name, t.Config[name]))
+	}
+	for _, acl := range t.ACLs {
+		lines = append(lines, fmt.Sprintf("ACL\t%s %s@%s %s", acl.Permission, acl.Principal, acl.Host, acl.Operation))
+	}
+	if t.Incomplete {
+		lines = append(lines, "Incomplete\tnot all acls were copied")
+	}
+	for _, line := range lines {
+		if _, err := fmt.Fprintln(w, line); err != nil {
+			return err
+		}
+	}
+	return w.Flush()
+}
+
+// FormatJSON implements the Formatter interface for TopicClone
+func (t TopicClone) FormatJSON(config Config) error {
+	enc := json.NewEncoder(config.Output)
+	if err := enc.Encode(t); err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,51 @@
+package format_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/Shopify/sarama"
+	"github.com/izolight/kafkalib/format"
+)
+
+func TestTopicClone_FormatText(t *testing.T) {
+	clone := format.TopicClone{
+		Source:            "orders",
+		Topic:             "orders-v2",
+		Partitions:        6,
+		ReplicationFactor: 3,
+		Config:            map[string]string{"retention.ms": "1000", "cleanup.policy": "compact"},
+		ACLs: []format.CloneACL{
+			{Principal: "User:app", Host: "*", Operation: format.Operation(sarama.AclOperationRead), Permission: format.Permission(sarama.AclPermissionAllow)},
+		},
+	}
+	expected := "Source\t\t\torders\n" +
+		"Topic\t\t\torders-v2\n" +
+		"Partitions\t\t6\n" +
+		"Replication Factor\t3\n" +
+		"Config\t\t\tcleanup.policy=compact\n" +
+		"Config\t\t\tretention.ms=1000\n" +
+		"ACL\t\t\tAllow User:app@* Read"
+	output := new(bytes.Buffer)
+	format.Format(clone, format.Config{Output: output, Format: "text"})
+	got := strings.TrimSuffix(output.String(), "\n")
+	if got != expected {
+		t.Errorf("clone.FormatText():\nGot:\t%q\nWant:\t%q", got, expected)
+	}
+}
+
+func TestTopicClone_FormatTextIncomplete(t *testing.T) {
+	clone := format.TopicClone{Source: "orders", Topic: "orders-v2", Partitions: 1, ReplicationFactor: 1, Incomplete: true}
+	expected := "Source\t\t\torders\n" +
+		"Topic\t\t\torders-v2\n" +
+		"Partitions\t\t1\n" +
+		"Replication Factor\t1\n" +
+		"Incomplete\t\tnot all acls were copied"
+	output := new(bytes.Buffer)
+	format.Format(clone, format.Config{Output: output, Format: "text"})
+	got := strings.TrimSuffix(output.String(), "\n")
+	if got != expected {
+		t.Errorf("clone.FormatText():\nGot:\t%q\nWant:\t%q", got, expected)
+	}
+}
@@ -2,6 +2,7 @@ package format
 
 import (
 	"io"
+	"text/tabwriter"
 )
 
 // Formatter provides some methods for outputting the kafka metadata
@@ -29,3 +30,9 @@ func Format(f Formatter, config Config) error {
 		return f.FormatText(config)
 	}
 }
+
+// newTabWriter returns the tabwriter of the text formatters, cells are separated by at least one tab
+// so a cell as wide as the tab width doesn't run into the next one
+func newTabWriter(output io.Writer) *tabwriter.Writer {
+	return new(tabwriter.Writer).Init(output, 0, 8, 1, '\t', 0)
+}
@@ -0,0 +1,118 @@
+package format
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/Shopify/sarama"
+)
+
+// config sources as shown in the output
+const (
+	ConfigSourceDefault              = "default"
+	ConfigSourceTopic                = "dynamic topic"
+	ConfigSourceDynamicBroker        = "dynamic broker"
+	ConfigSourceDynamicDefaultBroker = "dynamic default broker"
+	ConfigSourceStaticBroker         = "static broker"
+	ConfigSourceUnknown              = "unknown"
+)
+
+var configSourceToString = map[sarama.ConfigSource]string{
+	sarama.SourceTopic:                ConfigSourceTopic,
+	sarama.SourceDynamicBroker:        ConfigSourceDynamicBroker,
+	sarama.SourceDynamicDefaultBroker: ConfigSourceDynamicDefaultBroker,
+	sarama.SourceStaticBroker:         ConfigSourceStaticBroker,
+	sarama.SourceDefault:              ConfigSourceDefault,
+}
+
+// ConfigEntry is a single config value and where it comes from
+type ConfigEntry struct {
+	Name string `json:"name"`
+	// Value is empty for sensitive entries as the brokers don't return them
+	Value     string `json:"value"`
+	Source    string `json:"source"`
+	ReadOnly  bool   `json:"readOnly"`
+	Sensitive bool   `json:"sensitive"`
+}
+
+// Override reports if the entry is set on the topic itself
+func (e ConfigEntry) Override() bool {
+	return e.Source == ConfigSourceTopic
+}
+
+// TopicConfig holds all config entries of a topic sorted by name
+type TopicConfig struct {
+	Topic   string        `json:"topic"`
+	Entries []ConfigEntry `json:"entries"`
+}
+
+// FromConfigEntries converts the entries returned by sarama for a topic. Brokers before 1.1 only
+// report whether an entry is a default, so all other entries are reported as topic overrides, even
+// the ones set in the static config of the broker.
+func FromConfigEntries(topic string, entries []sarama.ConfigEntry) *TopicConfig {
+	config := &TopicConfig{Topic: topic, Entries: make([]ConfigEntry, 0, len(entries))}
+	for _, e := range entries {
+		source, ok := configSourceToString[e.Source]
+		if !ok {
+			source = ConfigSourceUnknown
+			if e.Source == sarama.SourceUnknown {
+				source = ConfigSourceTopic
+				if e.Default {
+					source = ConfigSourceDefault
+				}
+			}
+		}
+		config.Entries = append(config.Entries, ConfigEntry{
+			Name:      e.Name,
+			Value:     e.Value,
+			Source:    source,
+			ReadOnly:  e.ReadOnly,
+			Sensitive: e.Sensitive,
+		})
+	}
+	sort.Slice(config.Entries, func(i, j int) bool {
+		return config.Entries[i].Name < config.Entries[j].Name
+	})
+	return config
+}
+
+// Overrides returns the entries set on the topic itself
+func (c TopicConfig) Overrides() []ConfigEntry {
+	var overrides []ConfigEntry
+	for _, e := range c.Entries {
+		if e.Override() {
+			overrides = append(overrides, e)
+		}
+	}
+	return overrides
+}
+
+// FormatText outputs the config entries tab separated
+func (c TopicConfig) FormatText(config Config) error {
+	w := newTabWriter(config.Output)
+	_, err := fmt.Fprintln(w, "Name\tValue\tSource\tReadOnly\tSensitive")
+	if err != nil {
+		return err
+	}
+	for _, e := range c.Entries {
+		value := e.Value
+		if e.Sensitive {
+			value = "(sensitive)"
+		}
+		_, err := fmt.Fprintf(w, "%s\t%s\t%s\t%t\t%t\n", e.Name, value, e.Source, e.ReadOnly, e.Sensitive)
+		if err != nil {
+			return err
+		}
+	}
+	return w.Flush()
+}
+
+// FormatJSON implements the Formatter interface for TopicConfig
+func (c TopicConfig) FormatJSON(config Config) error {
+	enc := json.NewEncoder(config.Output)
+	if err := enc.Encode(c); err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+package format_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/Shopify/sarama"
+	"github.com/izolight/kafkalib/format"
+)
+
+func TestTopicConfig_Format(t *testing.T) {
+	config := format.FromConfigEntries("simpleTopic", []sarama.ConfigEntry{
+		{Name: "retention.ms", Value: "1000", Source: sarama.SourceTopic},
+		{Name: "cleanup.policy", Value: "delete", Default: true, Source: sarama.SourceDefault},
+		{Name: "password", Sensitive: true, Source: sarama.SourceStaticBroker},
+	})
+	testCases := []struct {
+		format   string
+		expected string
+	}{
+		{
+			"text",
+			"Name\t\tValue\t\tSource\t\tReadOnly\tSensitive\n" +
+				"cleanup.policy\tdelete\t\tdefault\t\tfalse\t\tfalse\n" +
+				"password\t(sensitive)\tstatic broker\tfalse\t\ttrue\n" +
+				"retention.ms\t1000\t\tdynamic topic\tfalse\t\tfalse",
+		},
+		{
+			"json",
+			`{"topic":"simpleTopic","entries":[` +
+				`{"name":"cleanup.policy","value":"delete","source":"default","readOnly":false,"sensitive":false},` +
+				`{"name":"password","value":"","source":"static broker","readOnly":false,"sensitive":true},` +
+				`{"name":"retention.ms","value":"1000","source":"dynamic topic","readOnly":false,"sensitive":false}]}`,
+		},
+	}
+	for _, tc := range testCases {
+		output := new(bytes.Buffer)
+		format.Format(config, format.Config{Output: output, Format: tc.format})
+		got := strings.TrimSuffix(output.String(), "\n")
+		if got != tc.expected {
+			t.Errorf("config.Format(%s):\nGot:\t%q\nWant:\t%q", tc.format, got, tc.expected)
+		}
+	}
+}
+
+func TestFromConfigEntries_Version0(t *testing.T) {
+	config := format.FromConfigEntries("simpleTopic", []sarama.ConfigEntry{
+		{Name: "retention.ms", Value: "1000"},
+		{Name: "cleanup.policy", Value: "delete", Default: true},
+	})
+	if len(config.Overrides()) != 1 || config.Overrides()[0].Name != "retention.ms" {
+		t.Errorf("Expected retention.ms to be the only override but got %+v", config.Overrides())
+	}
+}
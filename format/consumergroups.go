@@ -3,7 +3,6 @@ package format
 import (
 	"encoding/json"
 	"fmt"
-	"text/tabwriter"
 )
 
 // ConsumerGroups is a type alias
@@ -20,8 +19,7 @@ func (cg ConsumerGroups) FormatJSON(config Config) error {
 
 // FormatText implements the FormatText interface
 func (cg ConsumerGroups) FormatText(config Config) error {
-	w := new(tabwriter.Writer)
-	w.Init(config.Output, 0, 8, 0, '\t', 0)
+	w := newTabWriter(config.Output)
 	_, err := fmt.Fprintln(w, "Consumergroup\tConsumer")
 	if err != nil {
 		return err
@@ -0,0 +1,92 @@
+package format
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// CheckStatus is the outcome of a single diagnostic check
+type CheckStatus string
+
+// possible outcomes of a check
+const (
+	CheckOK      CheckStatus = "ok"
+	CheckWarning CheckStatus = "warning"
+	CheckFailed  CheckStatus = "failed"
+	CheckSkipped CheckStatus = "skipped"
+)
+
+// Check is the result of one step when connecting to a broker, e.g. dns or tls
+type Check struct {
+	Name     string        `json:"name"`
+	Status   CheckStatus   `json:"status"`
+	Detail   string        `json:"detail,omitempty"`
+	Duration time.Duration `json:"duration"`
+}
+
+// BrokerDiagnosis holds the checks for one broker address
+type BrokerDiagnosis struct {
+	Address string `json:"address"`
+	// ID is the broker id for advertised listeners, -1 for bootstrap brokers
+	ID int32 `json:"id"`
+	// Advertised is set for the listeners advertised in the cluster metadata
+	Advertised bool    `json:"advertised"`
+	Checks     []Check `json:"checks"`
+}
+
+// OK reports if no check of the broker failed
+func (b BrokerDiagnosis) OK() bool {
+	for _, c := range b.Checks {
+		if c.Status == CheckFailed {
+			return false
+		}
+	}
+	return true
+}
+
+// Diagnosis is the report of checking the connection to a cluster
+type Diagnosis struct {
+	Brokers []BrokerDiagnosis `json:"brokers"`
+}
+
+// OK reports if no check failed for any broker
+func (d Diagnosis) OK() bool {
+	for _, b := range d.Brokers {
+		if !b.OK() {
+			return false
+		}
+	}
+	return true
+}
+
+// FormatText outputs the checks of all brokers tab separated
+func (d Diagnosis) FormatText(config Config) error {
+	w := newTabWriter(config.Output)
+	_, err := fmt.Fprintln(w, "Broker\tCheck\tStatus\tTime\tDetail")
+	if err != nil {
+		return err
+	}
+	for _, b := range d.Brokers {
+		address := b.Address
+		if b.Advertised {
+			address = fmt.Sprintf("%s (id %d)", b.Address, b.ID)
+		}
+		for _, c := range b.Checks {
+			_, err := fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", address, c.Name, c.Status, c.Duration.Round(time.Millisecond), c.Detail)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return w.Flush()
+}
+
+// FormatJSON implements the Formatter interface for Diagnosis
+func (d Diagnosis) FormatJSON(config Config) error {
+	enc := json.NewEncoder(config.Output)
+	if err := enc.Encode(d); err != nil {
+		return err
+	}
+	return nil
+}
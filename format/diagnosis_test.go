@@ -0,0 +1,57 @@
+package format_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/izolight/kafkalib/format"
+)
+
+func TestDiagnosis_Format(t *testing.T) {
+	diagnosis := format.Diagnosis{
+		Brokers: []format.BrokerDiagnosis{
+			{Address: "kafka:9092", ID: -1, Checks: []format.Check{
+				{Name: "dns", Status: format.CheckOK, Detail: "10.0.0.1", Duration: time.Millisecond},
+				{Name: "tcp", Status: format.CheckFailed, Detail: "connection refused"},
+			}},
+			{Address: "broker1:9092", ID: 1, Advertised: true, Checks: []format.Check{
+				{Name: "dns", Status: format.CheckOK, Detail: "10.0.0.2"},
+			}},
+		},
+	}
+	testCases := []struct {
+		format   string
+		expected string
+	}{
+		{
+			"text",
+			"Broker\t\t\tCheck\tStatus\tTime\tDetail\n" +
+				"kafka:9092\t\tdns\tok\t1ms\t10.0.0.1\n" +
+				"kafka:9092\t\ttcp\tfailed\t0s\tconnection refused\n" +
+				"broker1:9092 (id 1)\tdns\tok\t0s\t10.0.0.2",
+		},
+		{
+			"json",
+			`{"brokers":[{"address":"kafka:9092","id":-1,"advertised":false,"checks":[` +
+				`{"name":"dns","status":"ok","detail":"10.0.0.1","duration":1000000},` +
+				`{"name":"tcp","status":"failed","detail":"connection refused","duration":0}]},` +
+				`{"address":"broker1:9092","id":1,"advertised":true,"checks":[{"name":"dns","status":"ok","detail":"10.0.0.2","duration":0}]}]}`,
+		},
+	}
+	for _, tc := range testCases {
+		output := new(bytes.Buffer)
+		err := format.Format(diagnosis, format.Config{Output: output, Format: tc.format})
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := strings.TrimSuffix(output.String(), "\n")
+		if got != tc.expected {
+			t.Errorf("diagnosis.Format(%s):\nGot:\t%q\nWant:\t%q", tc.format, got, tc.expected)
+		}
+	}
+	if diagnosis.OK() {
+		t.Errorf("Expected diagnosis with failed check not to be OK")
+	}
+}
@@ -0,0 +1,76 @@
+package format
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// kinds of leader elections
+const (
+	ElectionPreferred = "preferred"
+	ElectionUnclean   = "unclean"
+)
+
+// outcomes of a partition in a leader election
+const (
+	ElectionPending = "pending"
+	ElectionElected = "elected"
+	ElectionFailed  = "failed"
+)
+
+// PartitionElection is a partition that needs a leader election and its outcome
+type PartitionElection struct {
+	Topic     string `json:"topic"`
+	Partition int32  `json:"partition"`
+	// Leader is the leader before the election, -1 if the partition is offline
+	Leader    int32   `json:"leader"`
+	Preferred int32   `json:"preferred"`
+	Replicas  []int32 `json:"replicas"`
+	ISR       []int32 `json:"isr"`
+	Status    string  `json:"status"`
+	Error     string  `json:"error,omitempty"`
+}
+
+// LeaderElection lists the partitions of a leader election sorted by topic and partition
+type LeaderElection struct {
+	Type       string              `json:"type"`
+	DryRun     bool                `json:"dryRun"`
+	Partitions []PartitionElection `json:"partitions"`
+}
+
+// Failed returns the number of partitions the election failed for
+func (l LeaderElection) Failed() int {
+	failed := 0
+	for _, p := range l.Partitions {
+		if p.Status == ElectionFailed {
+			failed++
+		}
+	}
+	return failed
+}
+
+// FormatText outputs the partitions tab separated
+func (l LeaderElection) FormatText(config Config) error {
+	w := newTabWriter(config.Output)
+	_, err := fmt.Fprintln(w, "Topic\tPartition\tLeader\tPreferred\tReplicas\tISR\tStatus\tError")
+	if err != nil {
+		return err
+	}
+	for _, p := range l.Partitions {
+		_, err := fmt.Fprintf(w, "%s\t%d\t%d\t%d\t%s\t%s\t%s\t%s\n", p.Topic, p.Partition, p.Leader, p.Preferred,
+			brokerIDs(p.Replicas), brokerIDs(p.ISR), p.Status, p.Error)
+		if err != nil {
+			return err
+		}
+	}
+	return w.Flush()
+}
+
+// FormatJSON implements the Formatter interface for LeaderElection
+func (l LeaderElection) FormatJSON(config Config) error {
+	enc := json.NewEncoder(config.Output)
+	if err := enc.Encode(l); err != nil {
+		return err
+	}
+	return nil
+}
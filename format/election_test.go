@@ -0,0 +1,29 @@
+package format_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/izolight/kafkalib/format"
+)
+
+func TestLeaderElection_FormatText(t *testing.T) {
+	election := format.LeaderElection{Type: format.ElectionPreferred, DryRun: true, Partitions: []format.PartitionElection{
+		{Topic: "orders", Partition: 0, Leader: 2, Preferred: 1, Replicas: []int32{1, 2, 3}, ISR: []int32{1, 2, 3}, Status: format.ElectionPending},
+		{Topic: "orders", Partition: 1, Leader: 3, Preferred: 2, Replicas: []int32{2, 3, 1}, ISR: []int32{3, 1},
+			Status: format.ElectionFailed, Error: "preferred replica 2 is not in sync"},
+	}}
+	expected := "Topic\tPartition\tLeader\tPreferred\tReplicas\tISR\tStatus\tError\n" +
+		"orders\t0\t\t2\t1\t\t1,2,3\t\t1,2,3\tpending\t\n" +
+		"orders\t1\t\t3\t2\t\t2,3,1\t\t3,1\tfailed\tpreferred replica 2 is not in sync"
+	output := new(bytes.Buffer)
+	format.Format(election, format.Config{Output: output, Format: "text"})
+	got := strings.TrimSuffix(output.String(), "\n")
+	if got != expected {
+		t.Errorf("election.FormatText():\nGot:\t%q\nWant:\t%q", got, expected)
+	}
+	if election.Failed() != 1 {
+		t.Errorf("Expected 1 failed partition but got %d", election.Failed())
+	}
+}
@@ -0,0 +1,137 @@
+package format
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// problems reported for a partition
+const (
+	IssueOffline            = "offline"
+	IssueUnderMinISR        = "under-min-isr"
+	IssueUnderReplicated    = "under-replicated"
+	IssueNonPreferredLeader = "non-preferred-leader"
+)
+
+// exit codes of ClusterHealth, following the nagios plugin convention
+const (
+	HealthOK       = 0
+	HealthWarning  = 1
+	HealthCritical = 2
+)
+
+// PartitionHealth is a partition with at least one issue
+type PartitionHealth struct {
+	Topic     string   `json:"topic"`
+	Partition int32    `json:"partition"`
+	Leader    int32    `json:"leader"`
+	Replicas  []int32  `json:"replicas"`
+	ISR       []int32  `json:"isr"`
+	MinISR    int      `json:"minIsr"`
+	Issues    []string `json:"issues"`
+}
+
+// HealthSummary counts the partitions per issue
+type HealthSummary struct {
+	Topics             int `json:"topics"`
+	Partitions         int `json:"partitions"`
+	Offline            int `json:"offline"`
+	UnderMinISR        int `json:"underMinIsr"`
+	UnderReplicated    int `json:"underReplicated"`
+	NonPreferredLeader int `json:"nonPreferredLeader"`
+}
+
+// ClusterHealth holds the partitions with issues and a summary over all partitions
+type ClusterHealth struct {
+	Summary    HealthSummary     `json:"summary"`
+	Partitions []PartitionHealth `json:"partitions"`
+}
+
+// Add checks a partition and records it if it has issues
+func (h *ClusterHealth) Add(topic string, partition PartitionDescription, minISR int) {
+	h.Summary.Partitions++
+	var issues []string
+	if partition.Leader < 0 {
+		issues = append(issues, IssueOffline)
+		h.Summary.Offline++
+	}
+	if len(partition.ISR) < minISR {
+		issues = append(issues, IssueUnderMinISR)
+		h.Summary.UnderMinISR++
+	}
+	if len(partition.ISR) < len(partition.Replicas) {
+		issues = append(issues, IssueUnderReplicated)
+		h.Summary.UnderReplicated++
+	}
+	if partition.Leader >= 0 && len(partition.Replicas) > 0 && partition.Leader != partition.Replicas[0] {
+		issues = append(issues, IssueNonPreferredLeader)
+		h.Summary.NonPreferredLeader++
+	}
+	if len(issues) == 0 {
+		return
+	}
+	h.Partitions = append(h.Partitions, PartitionHealth{
+		Topic:     topic,
+		Partition: partition.ID,
+		Leader:    partition.Leader,
+		Replicas:  partition.Replicas,
+		ISR:       partition.ISR,
+		MinISR:    minISR,
+		Issues:    issues,
+	})
+}
+
+// ExitCode returns HealthCritical if partitions are offline or below min.insync.replicas,
+// HealthWarning if partitions are under-replicated or not led by their preferred replica
+// and HealthOK otherwise
+func (h ClusterHealth) ExitCode() int {
+	switch {
+	case h.Summary.Offline > 0 || h.Summary.UnderMinISR > 0:
+		return HealthCritical
+	case h.Summary.UnderReplicated > 0 || h.Summary.NonPreferredLeader > 0:
+		return HealthWarning
+	default:
+		return HealthOK
+	}
+}
+
+// String returns a one line summary, e.g. for monitoring scripts
+func (h ClusterHealth) String() string {
+	status := map[int]string{HealthOK: "OK", HealthWarning: "WARNING", HealthCritical: "CRITICAL"}[h.ExitCode()]
+	s := h.Summary
+	return fmt.Sprintf("%s - %d topics, %d partitions: %d offline, %d under min isr, %d under-replicated, %d non-preferred leader",
+		status, s.Topics, s.Partitions, s.Offline, s.UnderMinISR, s.UnderReplicated, s.NonPreferredLeader)
+}
+
+// FormatText outputs the summary followed by the partitions with issues tab separated
+func (h ClusterHealth) FormatText(config Config) error {
+	if _, err := fmt.Fprintln(config.Output, h.String()); err != nil {
+		return err
+	}
+	if len(h.Partitions) == 0 {
+		return nil
+	}
+	w := newTabWriter(config.Output)
+	_, err := fmt.Fprintln(w, "Topic\tPartition\tLeader\tReplicas\tISR\tMinISR\tIssues")
+	if err != nil {
+		return err
+	}
+	for _, p := range h.Partitions {
+		_, err := fmt.Fprintf(w, "%s\t%d\t%d\t%s\t%s\t%d\t%s\n", p.Topic, p.Partition, p.Leader,
+			brokerIDs(p.Replicas), brokerIDs(p.ISR), p.MinISR, strings.Join(p.Issues, ","))
+		if err != nil {
+			return err
+		}
+	}
+	return w.Flush()
+}
+
+// FormatJSON implements the Formatter interface for ClusterHealth
+func (h ClusterHealth) FormatJSON(config Config) error {
+	enc := json.NewEncoder(config.Output)
+	if err := enc.Encode(h); err != nil {
+		return err
+	}
+	return nil
+}
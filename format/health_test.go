@@ -0,0 +1,43 @@
+package format_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/izolight/kafkalib/format"
+)
+
+func TestClusterHealth_Format(t *testing.T) {
+	health := format.ClusterHealth{Summary: format.HealthSummary{Topics: 1}}
+	health.Add("simpleTopic", format.PartitionDescription{ID: 0, Leader: 1, Replicas: []int32{1, 2}, ISR: []int32{1, 2}}, 1)
+	health.Add("simpleTopic", format.PartitionDescription{ID: 1, Leader: 2, Replicas: []int32{1, 2}, ISR: []int32{2}}, 2)
+	testCases := []struct {
+		format   string
+		expected string
+	}{
+		{
+			"text",
+			"CRITICAL - 1 topics, 2 partitions: 0 offline, 1 under min isr, 1 under-replicated, 1 non-preferred leader\n" +
+				"Topic\t\tPartition\tLeader\tReplicas\tISR\tMinISR\tIssues\n" +
+				"simpleTopic\t1\t\t2\t1,2\t\t2\t2\tunder-min-isr,under-replicated,non-preferred-leader",
+		},
+		{
+			"json",
+			`{"summary":{"topics":1,"partitions":2,"offline":0,"underMinIsr":1,"underReplicated":1,"nonPreferredLeader":1},` +
+				`"partitions":[{"topic":"simpleTopic","partition":1,"leader":2,"replicas":[1,2],"isr":[2],"minIsr":2,` +
+				`"issues":["under-min-isr","under-replicated","non-preferred-leader"]}]}`,
+		},
+	}
+	for _, tc := range testCases {
+		output := new(bytes.Buffer)
+		format.Format(health, format.Config{Output: output, Format: tc.format})
+		got := strings.TrimSuffix(output.String(), "\n")
+		if got != tc.expected {
+			t.Errorf("health.Format(%s):\nGot:\t%q\nWant:\t%q", tc.format, got, tc.expected)
+		}
+	}
+	if health.ExitCode() != format.HealthCritical {
+		t.Errorf("Expected exit code %d but got %d", format.HealthCritical, health.ExitCode())
+	}
+}
@@ -0,0 +1,111 @@
+package format
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+)
+
+// formats read by MessageReader
+const (
+	// InputText reads a message per line, empty lines are skipped
+	InputText = "text"
+	// InputJSON reads messages in the JSON format written by Message.FormatJSON, one per line
+	InputJSON = "json"
+	// InputRaw reads the whole input as the value of a single message
+	InputRaw = "raw"
+)
+
+// MessageReader reads messages to produce. The partition of a message is -1 unless the input sets it.
+type MessageReader struct {
+	format       string
+	keySeparator string
+	lines        *bufio.Reader
+	decoder      *json.Decoder
+	raw          io.Reader
+}
+
+// NewMessageReader reads messages in inputFormat from r. For text input a non-empty keySeparator splits
+// each line into key and value at its first occurrence.
+func NewMessageReader(r io.Reader, inputFormat, keySeparator string) (*MessageReader, error) {
+	reader := &MessageReader{format: inputFormat, keySeparator: keySeparator}
+	switch inputFormat {
+	case InputText:
+		reader.lines = bufio.NewReader(r)
+	case InputJSON:
+		reader.decoder = json.NewDecoder(r)
+	case InputRaw:
+		reader.raw = r
+	default:
+		return nil, fmt.Errorf("Unknown input format %s, must be one of %s, %s or %s", inputFormat, InputText, InputJSON, InputRaw)
+	}
+	return reader, nil
+}
+
+// Read returns the next message and io.EOF at the end of the input
+func (r *MessageReader) Read() (Message, error) {
+	switch r.format {
+	case InputText:
+		return r.readLine()
+	case InputJSON:
+		return r.readJSON()
+	default:
+		return r.readRaw()
+	}
+}
+
+func (r *MessageReader) readLine() (Message, error) {
+	for {
+		line, err := r.lines.ReadString('\n')
+		if err != nil && (err != io.EOF || len(line) == 0) {
+			return Message{}, err
+		}
+		line = strings.TrimRight(line, "\r\n")
+		if len(line) == 0 {
+			continue
+		}
+		message := Message{Partition: -1, Value: line}
+		if len(r.keySeparator) != 0 {
+			if i := strings.Index(line, r.keySeparator); i >= 0 {
+				message.Key = line[:i]
+				message.Value = line[i+len(r.keySeparator):]
+			}
+		}
+		return message, nil
+	}
+}
+
+func (r *MessageReader) readJSON() (Message, error) {
+	// partition is a pointer to tell a missing partition from partition 0
+	var m struct {
+		Message
+		Partition *int32 `json:"partition"`
+	}
+	if err := r.decoder.Decode(&m); err != nil {
+		if err == io.EOF {
+			return Message{}, err
+		}
+		return Message{}, fmt.Errorf("Error parsing message: %s", err)
+	}
+	message := m.Message
+	message.Partition = -1
+	if m.Partition != nil {
+		message.Partition = *m.Partition
+	}
+	return message, nil
+}
+
+func (r *MessageReader) readRaw() (Message, error) {
+	if r.raw == nil {
+		return Message{}, io.EOF
+	}
+	value, err := ioutil.ReadAll(r.raw)
+	r.raw = nil
+	if err != nil {
+		return Message{}, err
+	}
+	return Message{Partition: -1, Value: string(value)}, nil
+}
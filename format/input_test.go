@@ -0,0 +1,74 @@
+package format_test
+
+import (
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/izolight/kafkalib/format"
+)
+
+func TestMessageReader(t *testing.T) {
+	testCases := []struct {
+		name         string
+		format       string
+		keySeparator string
+		input        string
+		expected     []format.Message
+	}{
+		{"text", format.InputText, "", "first\r\n\nsecond", []format.Message{
+			{Partition: -1, Value: "first"},
+			{Partition: -1, Value: "second"},
+		}},
+		{"text with keys", format.InputText, ":", "a:1\nb:2:3\nno key\n", []format.Message{
+			{Partition: -1, Key: "a", Value: "1"},
+			{Partition: -1, Key: "b", Value: "2:3"},
+			{Partition: -1, Value: "no key"},
+		}},
+		{"json", format.InputJSON, "", `{"key":"a","value":"1","partition":0,"timestamp":"2019-01-01T00:00:00Z","headers":[{"key":"h","value":"v"}]}
+{"value":"2"}`, []format.Message{
+			{Partition: 0, Key: "a", Value: "1", Timestamp: time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC), Headers: []format.Header{{Key: "h", Value: "v"}}},
+			{Partition: -1, Value: "2"},
+		}},
+		{"raw", format.InputRaw, "", "line 1\nline 2\n", []format.Message{
+			{Partition: -1, Value: "line 1\nline 2\n"},
+		}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			reader, err := format.NewMessageReader(strings.NewReader(tc.input), tc.format, tc.keySeparator)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var messages []format.Message
+			for {
+				m, err := reader.Read()
+				if err == io.EOF {
+					break
+				}
+				if err != nil {
+					t.Fatal(err)
+				}
+				messages = append(messages, m)
+			}
+			if !reflect.DeepEqual(messages, tc.expected) {
+				t.Errorf("Expected %v but got %v", tc.expected, messages)
+			}
+		})
+	}
+}
+
+func TestMessageReader_Invalid(t *testing.T) {
+	if _, err := format.NewMessageReader(strings.NewReader(""), "xml", ""); err == nil {
+		t.Error("Expected an error for an unknown format")
+	}
+	reader, err := format.NewMessageReader(strings.NewReader(`{"value":`), format.InputJSON, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := reader.Read(); err == nil || err == io.EOF {
+		t.Errorf("Expected a parse error but got %v", err)
+	}
+}
@@ -0,0 +1,71 @@
+package format
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Shopify/sarama"
+)
+
+// Header is a record header, the order and duplicate keys of the record are kept
+type Header struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+// Message is a consumed record. Key and Value hold the raw bytes, invalid UTF-8 is replaced in the JSON output.
+type Message struct {
+	Topic     string    `json:"topic"`
+	Partition int32     `json:"partition"`
+	Offset    int64     `json:"offset"`
+	Timestamp time.Time `json:"timestamp"`
+	Key       string    `json:"key"`
+	Value     string    `json:"value"`
+	Headers   []Header  `json:"headers,omitempty"`
+}
+
+// FromConsumerMessage converts a message received by a sarama consumer
+func FromConsumerMessage(m *sarama.ConsumerMessage) Message {
+	message := Message{
+		Topic:     m.Topic,
+		Partition: m.Partition,
+		Offset:    m.Offset,
+		Timestamp: m.Timestamp,
+		Key:       string(m.Key),
+		Value:     string(m.Value),
+	}
+	for _, h := range m.Headers {
+		if h == nil {
+			continue
+		}
+		message.Headers = append(message.Headers, Header{Key: string(h.Key), Value: string(h.Value)})
+	}
+	return message
+}
+
+// FormatText outputs the message as a single tab separated line of partition, offset, timestamp, key,
+// value and headers, so a stream of messages can be written one by one
+func (m Message) FormatText(config Config) error {
+	headers := make([]string, 0, len(m.Headers))
+	for _, h := range m.Headers {
+		headers = append(headers, h.Key+"="+h.Value)
+	}
+	timestamp := "-"
+	if !m.Timestamp.IsZero() {
+		timestamp = m.Timestamp.UTC().Format(time.RFC3339Nano)
+	}
+	_, err := fmt.Fprintf(config.Output, "%d\t%d\t%s\t%s\t%s\t%s\n", m.Partition, m.Offset, timestamp,
+		m.Key, m.Value, strings.Join(headers, ","))
+	return err
+}
+
+// FormatJSON outputs the message as a single line, a stream of messages is written as JSON lines
+func (m Message) FormatJSON(config Config) error {
+	enc := json.NewEncoder(config.Output)
+	if err := enc.Encode(m); err != nil {
+		return err
+	}
+	return nil
+}
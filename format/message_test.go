@@ -0,0 +1,37 @@
+package format_test
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/izolight/kafkalib/format"
+)
+
+func TestMessage_Format(t *testing.T) {
+	message := format.FromConsumerMessage(&sarama.ConsumerMessage{
+		Topic:     "orders",
+		Partition: 2,
+		Offset:    42,
+		Timestamp: time.Date(2019, 1, 1, 12, 0, 0, 0, time.UTC),
+		Key:       []byte("order-1"),
+		Value:     []byte(`{"amount":10}`),
+		Headers:   []*sarama.RecordHeader{{Key: []byte("source"), Value: []byte("web")}, {Key: []byte("trace"), Value: []byte("abc")}},
+	})
+	testCases := []struct {
+		format   string
+		expected string
+	}{
+		{"text", "2\t42\t2019-01-01T12:00:00Z\torder-1\t{\"amount\":10}\tsource=web,trace=abc\n"},
+		{"json", `{"topic":"orders","partition":2,"offset":42,"timestamp":"2019-01-01T12:00:00Z","key":"order-1","value":"{\"amount\":10}",` +
+			`"headers":[{"key":"source","value":"web"},{"key":"trace","value":"abc"}]}` + "\n"},
+	}
+	for _, tc := range testCases {
+		output := new(bytes.Buffer)
+		format.Format(message, format.Config{Output: output, Format: tc.format})
+		if got := output.String(); got != tc.expected {
+			t.Errorf("message.Format(%s):\nGot:\t%q\nWant:\t%q", tc.format, got, tc.expected)
+		}
+	}
+}
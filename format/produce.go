@@ -0,0 +1,57 @@
+package format
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// ProducedRecord is the outcome of producing a single record, partition and offset are -1 if it failed.
+// The offset is also -1 if it is unknown because the producer didn't wait for acks.
+type ProducedRecord struct {
+	Key       string `json:"key"`
+	Partition int32  `json:"partition"`
+	Offset    int64  `json:"offset"`
+	Error     string `json:"error,omitempty"`
+}
+
+// ProduceResult holds the outcome of each record in the order they were read
+type ProduceResult struct {
+	Topic   string           `json:"topic"`
+	Records []ProducedRecord `json:"records"`
+}
+
+// Failed returns the number of records that couldn't be produced
+func (p ProduceResult) Failed() int {
+	failed := 0
+	for _, r := range p.Records {
+		if len(r.Error) != 0 {
+			failed++
+		}
+	}
+	return failed
+}
+
+// FormatText outputs the partition and offset of each record tab separated
+func (p ProduceResult) FormatText(config Config) error {
+	w := newTabWriter(config.Output)
+	_, err := fmt.Fprintln(w, "Partition\tOffset\tKey\tError")
+	if err != nil {
+		return err
+	}
+	for _, r := range p.Records {
+		_, err := fmt.Fprintf(w, "%d\t%d\t%s\t%s\n", r.Partition, r.Offset, r.Key, r.Error)
+		if err != nil {
+			return err
+		}
+	}
+	return w.Flush()
+}
+
+// FormatJSON implements the Formatter interface for ProduceResult
+func (p ProduceResult) FormatJSON(config Config) error {
+	enc := json.NewEncoder(config.Output)
+	if err := enc.Encode(p); err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,27 @@
+package format_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/izolight/kafkalib/format"
+)
+
+func TestProduceResult_FormatText(t *testing.T) {
+	result := format.ProduceResult{Topic: "orders", Records: []format.ProducedRecord{
+		{Key: "a", Partition: 0, Offset: 42},
+		{Key: "b", Partition: -1, Offset: -1, Error: "failed"},
+	}}
+	expected := "Partition\tOffset\tKey\tError\n" +
+		"0\t\t42\ta\t\n" +
+		"-1\t\t-1\tb\tfailed"
+	output := new(strings.Builder)
+	format.Format(result, format.Config{Output: output, Format: "text"})
+	got := strings.TrimSuffix(output.String(), "\n")
+	if got != expected {
+		t.Errorf("result.FormatText():\nGot:\t%q\nWant:\t%q", got, expected)
+	}
+	if result.Failed() != 1 {
+		t.Errorf("Expected 1 failed record but got %d", result.Failed())
+	}
+}
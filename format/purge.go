@@ -0,0 +1,62 @@
+package format
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// PartitionPurge holds the offsets of a partition before purging and the offset records are deleted before
+type PartitionPurge struct {
+	Partition     int32 `json:"partition"`
+	LowWatermark  int64 `json:"lowWatermark"`
+	HighWatermark int64 `json:"highWatermark"`
+	DeleteBefore  int64 `json:"deleteBefore"`
+	// Messages is the number of deleted offsets, it is an upper bound for compacted or transactional topics
+	Messages int64 `json:"messages"`
+}
+
+// Purge is the result of deleting records from a topic
+type Purge struct {
+	Topic      string           `json:"topic"`
+	DryRun     bool             `json:"dryRun"`
+	Partitions []PartitionPurge `json:"partitions"`
+}
+
+// Messages returns the number of deleted messages of all partitions
+func (p Purge) Messages() int64 {
+	var total int64
+	for _, partition := range p.Partitions {
+		total += partition.Messages
+	}
+	return total
+}
+
+// FormatText outputs the deleted messages per partition tab separated
+func (p Purge) FormatText(config Config) error {
+	w := newTabWriter(config.Output)
+	header := "Deleted"
+	if p.DryRun {
+		header = "To Delete"
+	}
+	_, err := fmt.Fprintf(w, "Topic\tPartition\tLow\tHigh\tDelete Before\t%s\n", header)
+	if err != nil {
+		return err
+	}
+	for _, partition := range p.Partitions {
+		_, err := fmt.Fprintf(w, "%s\t%d\t%d\t%d\t%d\t%d\n", p.Topic, partition.Partition,
+			partition.LowWatermark, partition.HighWatermark, partition.DeleteBefore, partition.Messages)
+		if err != nil {
+			return err
+		}
+	}
+	return w.Flush()
+}
+
+// FormatJSON implements the Formatter interface for Purge
+func (p Purge) FormatJSON(config Config) error {
+	enc := json.NewEncoder(config.Output)
+	if err := enc.Encode(p); err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,28 @@
+package format_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/izolight/kafkalib/format"
+)
+
+func TestPurge_FormatText(t *testing.T) {
+	purge := format.Purge{Topic: "simpleTopic", DryRun: true, Partitions: []format.PartitionPurge{
+		{Partition: 0, LowWatermark: 10, HighWatermark: 100, DeleteBefore: 42, Messages: 32},
+		{Partition: 1, LowWatermark: 5, HighWatermark: 5, DeleteBefore: 5},
+	}}
+	expected := "Topic\t\tPartition\tLow\tHigh\tDelete Before\tTo Delete\n" +
+		"simpleTopic\t0\t\t10\t100\t42\t\t32\n" +
+		"simpleTopic\t1\t\t5\t5\t5\t\t0"
+	output := new(bytes.Buffer)
+	format.Format(purge, format.Config{Output: output, Format: "text"})
+	got := strings.TrimSuffix(output.String(), "\n")
+	if got != expected {
+		t.Errorf("purge.FormatText():\nGot:\t%q\nWant:\t%q", got, expected)
+	}
+	if purge.Messages() != 32 {
+		t.Errorf("Expected 32 messages but got %d", purge.Messages())
+	}
+}
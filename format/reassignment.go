@@ -0,0 +1,177 @@
+package format
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+)
+
+// PartitionReassignment holds the current and proposed replicas of a partition
+type PartitionReassignment struct {
+	Topic     string  `json:"topic"`
+	Partition int32   `json:"partition"`
+	Current   []int32 `json:"current"`
+	Proposed  []int32 `json:"proposed"`
+}
+
+// Changed reports if the proposed replicas differ from the current ones, including their order
+func (p PartitionReassignment) Changed() bool {
+	if len(p.Current) != len(p.Proposed) {
+		return true
+	}
+	for i := range p.Current {
+		if p.Current[i] != p.Proposed[i] {
+			return true
+		}
+	}
+	return false
+}
+
+// Reassignment is a plan for moving partition replicas. FormatJSON writes the proposed
+// replicas in the format of kafka-reassign-partitions --reassignment-json-file.
+type Reassignment struct {
+	Partitions []PartitionReassignment `json:"partitions"`
+}
+
+// ReassignmentJSON is the file format used by kafka-reassign-partitions
+type ReassignmentJSON struct {
+	Version    int                     `json:"version"`
+	Partitions []ReassignmentJSONEntry `json:"partitions"`
+}
+
+// ReassignmentJSONEntry holds the replicas of one partition in a ReassignmentJSON
+type ReassignmentJSONEntry struct {
+	Topic     string   `json:"topic"`
+	Partition int32    `json:"partition"`
+	Replicas  []int32  `json:"replicas"`
+	LogDirs   []string `json:"log_dirs,omitempty"`
+}
+
+// Sort orders the partitions by topic and partition
+func (r *Reassignment) Sort() {
+	sort.Slice(r.Partitions, func(i, j int) bool {
+		if r.Partitions[i].Topic != r.Partitions[j].Topic {
+			return r.Partitions[i].Topic < r.Partitions[j].Topic
+		}
+		return r.Partitions[i].Partition < r.Partitions[j].Partition
+	})
+}
+
+// Rollback returns the plan that moves the replicas back to the current ones
+func (r Reassignment) Rollback() Reassignment {
+	rollback := Reassignment{Partitions: make([]PartitionReassignment, len(r.Partitions))}
+	for i, p := range r.Partitions {
+		rollback.Partitions[i] = PartitionReassignment{Topic: p.Topic, Partition: p.Partition, Current: p.Proposed, Proposed: p.Current}
+	}
+	return rollback
+}
+
+// Proposed returns the changed partitions in the kafka-reassign-partitions format
+func (r Reassignment) Proposed() ReassignmentJSON {
+	file := ReassignmentJSON{Version: 1, Partitions: []ReassignmentJSONEntry{}}
+	for _, p := range r.Partitions {
+		if p.Changed() {
+			file.Partitions = append(file.Partitions, ReassignmentJSONEntry{Topic: p.Topic, Partition: p.Partition, Replicas: p.Proposed})
+		}
+	}
+	return file
+}
+
+// ParseReassignmentJSON reads a file in the kafka-reassign-partitions format, it lacks the current
+// replicas, kafka.Conn.PlanFromReassignmentJSON adds them to get a Reassignment
+func ParseReassignmentJSON(b []byte) (*ReassignmentJSON, error) {
+	file := &ReassignmentJSON{}
+	if err := json.Unmarshal(b, file); err != nil {
+		return nil, fmt.Errorf("Error parsing reassignment json: %s", err)
+	}
+	if file.Version != 1 {
+		return nil, fmt.Errorf("Reassignment json version %d is not supported", file.Version)
+	}
+	return file, nil
+}
+
+// FormatText outputs the current and proposed replicas of the changed partitions tab separated
+func (r Reassignment) FormatText(config Config) error {
+	w := newTabWriter(config.Output)
+	_, err := fmt.Fprintln(w, "Topic\tPartition\tCurrent\tProposed")
+	if err != nil {
+		return err
+	}
+	for _, p := range r.Partitions {
+		if !p.Changed() {
+			continue
+		}
+		_, err := fmt.Fprintf(w, "%s\t%d\t%s\t%s\n", p.Topic, p.Partition, brokerIDs(p.Current), brokerIDs(p.Proposed))
+		if err != nil {
+			return err
+		}
+	}
+	return w.Flush()
+}
+
+// FormatJSON outputs the proposed replicas in the kafka-reassign-partitions format
+func (r Reassignment) FormatJSON(config Config) error {
+	enc := json.NewEncoder(config.Output)
+	if err := enc.Encode(r.Proposed()); err != nil {
+		return err
+	}
+	return nil
+}
+
+// states of a partition during a reassignment
+const (
+	ReassignmentPending    = "pending"
+	ReassignmentInProgress = "in progress"
+	ReassignmentCompleted  = "completed"
+)
+
+// PartitionReassignmentStatus compares the replicas of a partition with the target of a reassignment
+type PartitionReassignmentStatus struct {
+	Topic     string  `json:"topic"`
+	Partition int32   `json:"partition"`
+	Replicas  []int32 `json:"replicas"`
+	ISR       []int32 `json:"isr"`
+	Target    []int32 `json:"target"`
+	State     string  `json:"state"`
+}
+
+// ReassignmentStatus holds the progress of a reassignment
+type ReassignmentStatus struct {
+	Partitions []PartitionReassignmentStatus `json:"partitions"`
+}
+
+// Done reports if all partitions reached their target replicas
+func (s ReassignmentStatus) Done() bool {
+	for _, p := range s.Partitions {
+		if p.State != ReassignmentCompleted {
+			return false
+		}
+	}
+	return true
+}
+
+// FormatText outputs the state of each partition tab separated
+func (s ReassignmentStatus) FormatText(config Config) error {
+	w := newTabWriter(config.Output)
+	_, err := fmt.Fprintln(w, "Topic\tPartition\tReplicas\tISR\tTarget\tState")
+	if err != nil {
+		return err
+	}
+	for _, p := range s.Partitions {
+		_, err := fmt.Fprintf(w, "%s\t%d\t%s\t%s\t%s\t%s\n", p.Topic, p.Partition,
+			brokerIDs(p.Replicas), brokerIDs(p.ISR), brokerIDs(p.Target), p.State)
+		if err != nil {
+			return err
+		}
+	}
+	return w.Flush()
+}
+
+// FormatJSON implements the Formatter interface for ReassignmentStatus
+func (s ReassignmentStatus) FormatJSON(config Config) error {
+	enc := json.NewEncoder(config.Output)
+	if err := enc.Encode(s); err != nil {
+		return err
+	}
+	return nil
+}
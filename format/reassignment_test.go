@@ -0,0 +1,58 @@
+package format_test
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/izolight/kafkalib/format"
+)
+
+func TestReassignment_Format(t *testing.T) {
+	plan := format.Reassignment{Partitions: []format.PartitionReassignment{
+		{Topic: "simpleTopic", Partition: 0, Current: []int32{1, 2}, Proposed: []int32{1, 2}},
+		{Topic: "simpleTopic", Partition: 1, Current: []int32{2, 3}, Proposed: []int32{2, 4}},
+	}}
+	testCases := []struct {
+		format   string
+		expected string
+	}{
+		{
+			"text",
+			"Topic\t\tPartition\tCurrent\tProposed\n" +
+				"simpleTopic\t1\t\t2,3\t2,4",
+		},
+		{
+			"json",
+			`{"version":1,"partitions":[{"topic":"simpleTopic","partition":1,"replicas":[2,4]}]}`,
+		},
+	}
+	for _, tc := range testCases {
+		output := new(bytes.Buffer)
+		format.Format(plan, format.Config{Output: output, Format: tc.format})
+		got := strings.TrimSuffix(output.String(), "\n")
+		if got != tc.expected {
+			t.Errorf("plan.Format(%s):\nGot:\t%q\nWant:\t%q", tc.format, got, tc.expected)
+		}
+	}
+
+	output := new(bytes.Buffer)
+	format.Format(plan.Rollback(), format.Config{Output: output, Format: "json"})
+	parsed, err := format.ParseReassignmentJSON(output.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []format.ReassignmentJSONEntry{{Topic: "simpleTopic", Partition: 1, Replicas: []int32{2, 3}}}
+	if !reflect.DeepEqual(parsed.Partitions, expected) {
+		t.Errorf("Expected rollback %+v but got %+v", expected, parsed.Partitions)
+	}
+}
+
+func TestParseReassignmentJSON_Invalid(t *testing.T) {
+	for _, input := range []string{`{"version":2,"partitions":[]}`, `{"version":`} {
+		if _, err := format.ParseReassignmentJSON([]byte(input)); err == nil {
+			t.Errorf("Expected an error for %s", input)
+		}
+	}
+}
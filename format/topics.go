@@ -4,7 +4,8 @@ import (
 	"encoding/json"
 	"fmt"
 	"sort"
-	"text/tabwriter"
+	"strconv"
+	"strings"
 
 	"github.com/Shopify/sarama"
 	log "github.com/sirupsen/logrus"
@@ -13,6 +14,17 @@ import (
 // Topics holds the topic metadata received
 type Topics map[string]sarama.TopicDetail
 
+// Filter returns the topics for which match returns true
+func (t Topics) Filter(match func(name string, detail sarama.TopicDetail) bool) Topics {
+	filtered := Topics{}
+	for name, detail := range t {
+		if match(name, detail) {
+			filtered[name] = detail
+		}
+	}
+	return filtered
+}
+
 // Topic is a type alias
 type Topic sarama.TopicDetail
 
@@ -48,8 +60,7 @@ func (t *Topic) Diff(other *Topic) (TopicDiff, bool) {
 
 // FormatText outputs the topic overview tab separated
 func (topics Topics) FormatText(config Config) error {
-	w := new(tabwriter.Writer)
-	w.Init(config.Output, 0, 8, 0, '\t', 0)
+	w := newTabWriter(config.Output)
 	_, err := fmt.Fprintln(w, "Topic\tPartitions\tReplicationFactor")
 	if err != nil {
 		return err
@@ -78,8 +89,7 @@ func (topics Topics) FormatJSON(config Config) error {
 
 // FormatWide provides all output that is cut from normal FormatText
 func (topics Topics) FormatWide(config Config) {
-	w := new(tabwriter.Writer)
-	w.Init(config.Output, 0, 8, 0, '\t', 0)
+	w := newTabWriter(config.Output)
 	_, err := fmt.Fprintln(w, "Topic\tPartitions\tReplicationfactor\tConfig Entries\tReplica Assignment")
 	if err != nil {
 		log.Fatal(err)
@@ -113,3 +123,83 @@ func (topics Topics) Sort() []string {
 	sort.Strings(sorted)
 	return sorted
 }
+
+// PartitionDescription holds the replica state of a single partition
+type PartitionDescription struct {
+	ID       int32   `json:"id"`
+	Leader   int32   `json:"leader"`
+	Replicas []int32 `json:"replicas"`
+	ISR      []int32 `json:"isr"`
+	// OfflineReplicas is only filled by brokers since 1.0
+	OfflineReplicas []int32 `json:"offlineReplicas"`
+	// Error is set if the broker reported a problem with the partition, e.g. no leader
+	Error string `json:"error,omitempty"`
+}
+
+// TopicDescription holds the partition level metadata of a topic
+type TopicDescription struct {
+	Name       string                 `json:"name"`
+	Internal   bool                   `json:"internal"`
+	Partitions []PartitionDescription `json:"partitions"`
+}
+
+// FromTopicMetadata converts the sarama metadata of a topic, partitions are sorted by id
+func FromTopicMetadata(metadata *sarama.TopicMetadata) *TopicDescription {
+	description := &TopicDescription{
+		Name:       metadata.Name,
+		Internal:   metadata.IsInternal,
+		Partitions: make([]PartitionDescription, 0, len(metadata.Partitions)),
+	}
+	for _, p := range metadata.Partitions {
+		partition := PartitionDescription{
+			ID:              p.ID,
+			Leader:          p.Leader,
+			Replicas:        p.Replicas,
+			ISR:             p.Isr,
+			OfflineReplicas: p.OfflineReplicas,
+		}
+		if p.Err != sarama.ErrNoError {
+			partition.Error = p.Err.Error()
+		}
+		description.Partitions = append(description.Partitions, partition)
+	}
+	sort.Slice(description.Partitions, func(i, j int) bool {
+		return description.Partitions[i].ID < description.Partitions[j].ID
+	})
+	return description
+}
+
+// FormatText outputs the partitions of the topic tab separated
+func (t TopicDescription) FormatText(config Config) error {
+	w := newTabWriter(config.Output)
+	_, err := fmt.Fprintln(w, "Topic\tPartition\tLeader\tReplicas\tISR\tOffline")
+	if err != nil {
+		return err
+	}
+	for _, p := range t.Partitions {
+		_, err := fmt.Fprintf(w, "%s\t%d\t%d\t%s\t%s\t%s\n", t.Name, p.ID, p.Leader,
+			brokerIDs(p.Replicas), brokerIDs(p.ISR), brokerIDs(p.OfflineReplicas))
+		if err != nil {
+			return err
+		}
+	}
+	return w.Flush()
+}
+
+// FormatJSON implements the Formatter interface for TopicDescription
+func (t TopicDescription) FormatJSON(config Config) error {
+	enc := json.NewEncoder(config.Output)
+	if err := enc.Encode(t); err != nil {
+		return err
+	}
+	return nil
+}
+
+// brokerIDs joins broker ids with commas
+func brokerIDs(ids []int32) string {
+	s := make([]string, len(ids))
+	for i, id := range ids {
+		s[i] = strconv.Itoa(int(id))
+	}
+	return strings.Join(s, ",")
+}
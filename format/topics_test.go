@@ -70,3 +70,38 @@ func TestTopics_FormatText(t *testing.T) {
 		}
 	}
 }
+
+func TestTopicDescription_Format(t *testing.T) {
+	description := format.FromTopicMetadata(&sarama.TopicMetadata{
+		Name: "simpleTopic",
+		Partitions: []*sarama.PartitionMetadata{
+			{ID: 1, Leader: -1, Replicas: []int32{2, 3}, Isr: []int32{}, OfflineReplicas: []int32{2, 3}, Err: sarama.ErrLeaderNotAvailable},
+			{ID: 0, Leader: 1, Replicas: []int32{1, 2}, Isr: []int32{1, 2}},
+		},
+	})
+	testCases := []struct {
+		format   string
+		expected string
+	}{
+		{
+			"text",
+			"Topic\t\tPartition\tLeader\tReplicas\tISR\tOffline\n" +
+				"simpleTopic\t0\t\t1\t1,2\t\t1,2\t\n" +
+				"simpleTopic\t1\t\t-1\t2,3\t\t\t2,3",
+		},
+		{
+			"json",
+			`{"name":"simpleTopic","internal":false,"partitions":[` +
+				`{"id":0,"leader":1,"replicas":[1,2],"isr":[1,2],"offlineReplicas":null},` +
+				`{"id":1,"leader":-1,"replicas":[2,3],"isr":[],"offlineReplicas":[2,3],"error":"kafka server: In the middle of a leadership election, there is currently no leader for this partition and hence it is unavailable for writes."}]}`,
+		},
+	}
+	for _, tc := range testCases {
+		output := new(bytes.Buffer)
+		format.Format(description, format.Config{Output: output, Format: tc.format})
+		got := strings.TrimSuffix(output.String(), "\n")
+		if got != tc.expected {
+			t.Errorf("description.Format(%s):\nGot:\t%q\nWant:\t%q", tc.format, got, tc.expected)
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package format
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+)
+
+// keys Usage can be sorted by, counts and sizes are sorted descending
+const (
+	SortByName     = "name"
+	SortByMessages = "messages"
+	SortBySize     = "size"
+)
+
+// PartitionUsage holds the watermarks of a partition and the space it uses
+type PartitionUsage struct {
+	Partition     int32 `json:"partition"`
+	LowWatermark  int64 `json:"lowWatermark"`
+	HighWatermark int64 `json:"highWatermark"`
+	// Messages is the difference of the watermarks, it is an upper bound for compacted or transactional topics
+	Messages int64 `json:"messages"`
+	// Sizes holds the size in bytes of the partition per broker, it is empty if the brokers can't report it
+	Sizes map[int32]int64 `json:"sizes,omitempty"`
+}
+
+// TopicUsage holds the usage of each partition of a topic
+type TopicUsage struct {
+	Topic      string           `json:"topic"`
+	Partitions []PartitionUsage `json:"partitions"`
+}
+
+// Messages returns the number of messages in all partitions
+func (t TopicUsage) Messages() int64 {
+	var total int64
+	for _, p := range t.Partitions {
+		total += p.Messages
+	}
+	return total
+}
+
+// Size returns the bytes used by all replicas of the topic and false if a partition has no sizes
+func (t TopicUsage) Size() (int64, bool) {
+	var total int64
+	for _, p := range t.Partitions {
+		if len(p.Sizes) == 0 {
+			return 0, false
+		}
+		for _, size := range p.Sizes {
+			total += size
+		}
+	}
+	return total, true
+}
+
+// Usage lists the usage of topics
+type Usage []TopicUsage
+
+// Sort sorts the topics by name, messages or size. Ties and unknown sizes are sorted by name.
+func (u Usage) Sort(by string) error {
+	var less func(a, b TopicUsage) bool
+	switch by {
+	case SortByName:
+		less = func(a, b TopicUsage) bool { return false }
+	case SortByMessages:
+		less = func(a, b TopicUsage) bool { return a.Messages() > b.Messages() }
+	case SortBySize:
+		less = func(a, b TopicUsage) bool {
+			sa, _ := a.Size()
+			sb, _ := b.Size()
+			return sa > sb
+		}
+	default:
+		return fmt.Errorf("Unknown sort key %s, must be one of %s, %s or %s", by, SortByName, SortByMessages, SortBySize)
+	}
+	sort.SliceStable(u, func(i, j int) bool { return u[i].Topic < u[j].Topic })
+	sort.SliceStable(u, func(i, j int) bool { return less(u[i], u[j]) })
+	return nil
+}
+
+// FormatText outputs the usage per topic tab separated, unknown sizes are shown as -
+func (u Usage) FormatText(config Config) error {
+	w := newTabWriter(config.Output)
+	_, err := fmt.Fprintln(w, "Topic\tPartitions\tMessages\tSize")
+	if err != nil {
+		return err
+	}
+	for _, t := range u {
+		size := "-"
+		if s, ok := t.Size(); ok {
+			size = fmt.Sprintf("%d", s)
+		}
+		_, err := fmt.Fprintf(w, "%s\t%d\t%d\t%s\n", t.Topic, len(t.Partitions), t.Messages(), size)
+		if err != nil {
+			return err
+		}
+	}
+	return w.Flush()
+}
+
+// FormatJSON implements the Formatter interface for Usage
+func (u Usage) FormatJSON(config Config) error {
+	enc := json.NewEncoder(config.Output)
+	if err := enc.Encode(u); err != nil {
+		return err
+	}
+	return nil
+}
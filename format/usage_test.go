@@ -0,0 +1,59 @@
+package format_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/izolight/kafkalib/format"
+)
+
+func TestUsage_Sort(t *testing.T) {
+	newUsage := func() format.Usage {
+		return format.Usage{
+			{Topic: "b", Partitions: []format.PartitionUsage{{Messages: 5, Sizes: map[int32]int64{1: 100, 2: 100}}}},
+			{Topic: "c", Partitions: []format.PartitionUsage{{Messages: 50}}},
+			{Topic: "a", Partitions: []format.PartitionUsage{{Messages: 5, Sizes: map[int32]int64{1: 300}}}},
+		}
+	}
+	testCases := []struct {
+		by       string
+		expected string
+	}{
+		{format.SortByName, "abc"},
+		{format.SortByMessages, "cab"},
+		{format.SortBySize, "abc"},
+	}
+	for _, tc := range testCases {
+		usage := newUsage()
+		if err := usage.Sort(tc.by); err != nil {
+			t.Fatal(err)
+		}
+		got := ""
+		for _, u := range usage {
+			got += u.Topic
+		}
+		if got != tc.expected {
+			t.Errorf("Sort(%s): expected %s but got %s", tc.by, tc.expected, got)
+		}
+	}
+	if err := newUsage().Sort("unknown"); err == nil {
+		t.Error("Expected an error for an unknown sort key")
+	}
+}
+
+func TestUsage_FormatText(t *testing.T) {
+	usage := format.Usage{
+		{Topic: "simpleTopic", Partitions: []format.PartitionUsage{{Messages: 90, Sizes: map[int32]int64{1: 2048}}}},
+		{Topic: "topicWithPartitions", Partitions: []format.PartitionUsage{{Messages: 10}, {Messages: 60}}},
+	}
+	expected := "Topic\t\t\tPartitions\tMessages\tSize\n" +
+		"simpleTopic\t\t1\t\t90\t\t2048\n" +
+		"topicWithPartitions\t2\t\t70\t\t-"
+	output := new(bytes.Buffer)
+	format.Format(usage, format.Config{Output: output, Format: "text"})
+	got := strings.TrimSuffix(output.String(), "\n")
+	if got != expected {
+		t.Errorf("usage.FormatText():\nGot:\t%q\nWant:\t%q", got, expected)
+	}
+}
@@ -1,6 +1,7 @@
 package kafka
 
 import (
+	"context"
 	"fmt"
 	"github.com/Shopify/sarama"
 	"github.com/izolight/kafkalib/format"
@@ -8,22 +9,100 @@ import (
 
 // GetACLs returns ACLs according to filter
 func (c Conn) GetACLs(filter *sarama.AclFilter) (*format.ACLs, error) {
-	var err error
-	acls, err := c.AdminClient.ListAcls(*filter)
+	return c.GetACLsContext(context.Background(), filter)
+}
+
+// GetACLsContext is GetACLs with a context
+func (c Conn) GetACLsContext(ctx context.Context, filter *sarama.AclFilter) (*format.ACLs, error) {
+	var acls []sarama.ResourceAcls
+	err := call(ctx, "getting acls", "", func() error {
+		var err error
+		acls, err = c.admin(ctx).ListAcls(*filter)
+		return err
+	})
 	if err != nil {
-		return nil, fmt.Errorf("Error getting acls: %s", err)
+		return nil, newError("getting acls", "", err)
 	}
 	return format.FromResourceAcls(acls), nil
 }
 
-// CreateACL creates a new acl
+// CreateACL creates a new acl, it returns an error matching ErrACLExists if the acl is already present
 func (c Conn) CreateACL(acl *sarama.AclCreation) error {
-	err := c.AdminClient.CreateACL(acl.Resource, acl.Acl)
-	return err
+	return c.CreateACLContext(context.Background(), acl)
+}
+
+// CreateACLContext is CreateACL with a context
+func (c Conn) CreateACLContext(ctx context.Context, acl *sarama.AclCreation) error {
+	resource := aclResourceString(acl.Resource)
+	var exists bool
+	err := call(ctx, "creating acl", resource, func() error {
+		var err error
+		exists, err = c.aclExists(ctx, acl)
+		return err
+	})
+	if err != nil {
+		return newError("creating acl", resource, err)
+	}
+	if exists {
+		return newKindError("creating acl", resource, ErrACLExists)
+	}
+	err = call(ctx, "creating acl", resource, func() error {
+		return c.admin(ctx).CreateACL(acl.Resource, acl.Acl)
+	})
+	return newError("creating acl", resource, err)
 }
 
 // DeleteACL deletes a ACL according to filter
 func (c Conn) DeleteACL(filter *sarama.AclFilter) ([]sarama.MatchingAcl, error) {
-	acls, err := c.AdminClient.DeleteACL(*filter, false)
-	return acls, err
+	return c.DeleteACLContext(context.Background(), filter)
+}
+
+// DeleteACLContext is DeleteACL with a context
+func (c Conn) DeleteACLContext(ctx context.Context, filter *sarama.AclFilter) ([]sarama.MatchingAcl, error) {
+	var acls []sarama.MatchingAcl
+	err := call(ctx, "deleting acls", "", func() error {
+		var err error
+		acls, err = c.admin(ctx).DeleteACL(*filter, false)
+		return err
+	})
+	if err != nil {
+		return nil, newError("deleting acls", "", err)
+	}
+	return acls, nil
+}
+
+// aclExists checks if exactly the same acl is already present
+func (c Conn) aclExists(ctx context.Context, acl *sarama.AclCreation) (bool, error) {
+	name := acl.ResourceName
+	principal := acl.Principal
+	host := acl.Host
+	filter := sarama.AclFilter{
+		ResourceType:   acl.ResourceType,
+		ResourceName:   &name,
+		Principal:      &principal,
+		Host:           &host,
+		Operation:      acl.Operation,
+		PermissionType: acl.PermissionType,
+	}
+	rAcls, err := c.admin(ctx).ListAcls(filter)
+	if err != nil {
+		return false, err
+	}
+	for _, r := range rAcls {
+		if r.ResourceType != acl.ResourceType || r.ResourceName != acl.ResourceName {
+			continue
+		}
+		for _, a := range r.Acls {
+			if a.Principal == acl.Principal && a.Host == acl.Host &&
+				a.Operation == acl.Operation && a.PermissionType == acl.PermissionType {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+// aclResourceString formats a resource for error messages
+func aclResourceString(r sarama.Resource) string {
+	return fmt.Sprintf("%s/%s", format.ResourceType(r.ResourceType), r.ResourceName)
 }
@@ -1,6 +1,8 @@
 package kafka_test
 
 import (
+	"errors"
+
 	"github.com/Shopify/sarama"
 	"github.com/izolight/kafkalib/kafka"
 	"testing"
@@ -47,14 +49,14 @@ func TestACL_Create(t *testing.T) {
 		success bool
 	}{
 		{sarama.AclCreation{
-			sarama.Resource{ResourceName: "simpleTopic", ResourceType: sarama.AclResourceTopic},
-			sarama.Acl{Operation: sarama.AclOperationRead, Principal: "User:test", PermissionType: sarama.AclPermissionAllow},
+			Resource: sarama.Resource{ResourceName: "simpleTopic", ResourceType: sarama.AclResourceTopic},
+			Acl:      sarama.Acl{Operation: sarama.AclOperationRead, Principal: "User:test", PermissionType: sarama.AclPermissionAllow},
 		},
 			true,
 		},
 		{sarama.AclCreation{
-			sarama.Resource{ResourceName: "topicDoesNotExist", ResourceType: sarama.AclResourceTopic},
-			sarama.Acl{Operation: sarama.AclOperationWrite, Principal: "User:test", PermissionType: sarama.AclPermissionAllow},
+			Resource: sarama.Resource{ResourceName: "topicDoesNotExist", ResourceType: sarama.AclResourceTopic},
+			Acl:      sarama.Acl{Operation: sarama.AclOperationWrite, Principal: "User:test", PermissionType: sarama.AclPermissionAllow},
 		},
 			false,
 		},
@@ -76,3 +78,40 @@ func TestACL_Create(t *testing.T) {
 func TestACL_Delete(t *testing.T) {
 
 }
+
+func TestNewConn_ACLErrors(t *testing.T) {
+	broker := newMockCluster(t)
+	defer broker.Close()
+	c, err := kafka.NewConn(&kafka.Config{BrokerList: []string{broker.Addr()}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	name := "simpleTopic"
+	filter := &sarama.AclFilter{ResourceType: sarama.AclResourceTopic, ResourceName: &name}
+	testCases := []struct {
+		kerr     sarama.KError
+		expected error
+	}{
+		{sarama.ErrNoError, nil},
+		{sarama.ErrClusterAuthorizationFailed, kafka.ErrAuthorizationDenied},
+		{sarama.ErrSecurityDisabled, sarama.ErrSecurityDisabled},
+	}
+	for _, tc := range testCases {
+		broker.SetHandlerByMap(map[string]sarama.MockResponse{
+			"DescribeAclsRequest": sarama.NewMockWrapper(&sarama.DescribeAclsResponse{Err: tc.kerr}),
+			"DeleteAclsRequest": sarama.NewMockWrapper(&sarama.DeleteAclsResponse{
+				FilterResponses: []*sarama.FilterResponse{{Err: tc.kerr}},
+			}),
+		})
+		_, err := c.GetACLs(filter)
+		if !errors.Is(err, tc.expected) || (tc.expected == nil) != (err == nil) {
+			t.Errorf("Getting acls with %s returned %v", tc.kerr, err)
+		}
+		_, err = c.DeleteACL(filter)
+		if !errors.Is(err, tc.expected) || (tc.expected == nil) != (err == nil) {
+			t.Errorf("Deleting acls with %s returned %v", tc.kerr, err)
+		}
+	}
+}
@@ -0,0 +1,540 @@
+package kafka
+
+import (
+	"errors"
+	"math/rand"
+	"sync"
+
+	"github.com/Shopify/sarama"
+)
+
+// clusterAdmin implements sarama.ClusterAdmin on top of an existing sarama.Client.
+// sarama only offers NewClusterAdmin which always opens its own client, so this is
+// a port of sarama's clusterAdmin that shares the client with the rest of the Conn.
+type clusterAdmin struct {
+	client sarama.Client
+	conf   *sarama.Config
+	// ownsClient is set if closing the admin also closes the client
+	ownsClient bool
+}
+
+// newClusterAdminFromClient creates a sarama.ClusterAdmin which uses the given client,
+// closing the admin leaves the client open
+func newClusterAdminFromClient(client sarama.Client) (sarama.ClusterAdmin, error) {
+	// make sure we can retrieve the controller
+	_, err := client.Controller()
+	if err != nil {
+		return nil, err
+	}
+	return &clusterAdmin{
+		client: client,
+		conf:   client.Config(),
+	}, nil
+}
+
+// newClusterAdmin creates a sarama.ClusterAdmin with its own client, like sarama.NewClusterAdmin,
+// and returns the client too
+func newClusterAdmin(addrs []string, conf *sarama.Config) (sarama.ClusterAdmin, sarama.Client, error) {
+	client, err := sarama.NewClient(addrs, conf)
+	if err != nil {
+		return nil, nil, err
+	}
+	admin, err := newClusterAdminFromClient(client)
+	if err != nil {
+		client.Close()
+		return nil, nil, err
+	}
+	admin.(*clusterAdmin).ownsClient = true
+	return admin, client, nil
+}
+
+// Close implements the sarama.ClusterAdmin interface, a shared client is closed by its owner
+func (ca *clusterAdmin) Close() error {
+	if ca.ownsClient {
+		return ca.client.Close()
+	}
+	return nil
+}
+
+func (ca *clusterAdmin) controller() (*sarama.Broker, error) {
+	return ca.client.Controller()
+}
+
+func (ca *clusterAdmin) findAnyBroker() (*sarama.Broker, error) {
+	brokers := ca.client.Brokers()
+	if len(brokers) > 0 {
+		index := rand.Intn(len(brokers))
+		return brokers[index], nil
+	}
+	return nil, errors.New("no available broker")
+}
+
+// CreateTopic implements the sarama.ClusterAdmin interface
+func (ca *clusterAdmin) CreateTopic(topic string, detail *sarama.TopicDetail, validateOnly bool) error {
+	if topic == "" {
+		return sarama.ErrInvalidTopic
+	}
+	if detail == nil {
+		return errors.New("you must specify topic details")
+	}
+
+	request := &sarama.CreateTopicsRequest{
+		TopicDetails: map[string]*sarama.TopicDetail{topic: detail},
+		ValidateOnly: validateOnly,
+		Timeout:      ca.conf.Admin.Timeout,
+	}
+	if ca.conf.Version.IsAtLeast(sarama.V0_11_0_0) {
+		request.Version = 1
+	}
+	if ca.conf.Version.IsAtLeast(sarama.V1_0_0_0) {
+		request.Version = 2
+	}
+
+	b, err := ca.controller()
+	if err != nil {
+		return err
+	}
+	rsp, err := b.CreateTopics(request)
+	if err != nil {
+		return err
+	}
+	topicErr, ok := rsp.TopicErrors[topic]
+	if !ok {
+		return sarama.ErrIncompleteResponse
+	}
+	if topicErr.Err != sarama.ErrNoError {
+		return topicErr
+	}
+	return nil
+}
+
+// DescribeTopics implements the sarama.ClusterAdmin interface
+func (ca *clusterAdmin) DescribeTopics(topics []string) ([]*sarama.TopicMetadata, error) {
+	b, err := ca.controller()
+	if err != nil {
+		return nil, err
+	}
+
+	request := &sarama.MetadataRequest{
+		Topics:                 topics,
+		AllowAutoTopicCreation: false,
+	}
+	if ca.conf.Version.IsAtLeast(sarama.V1_0_0_0) {
+		request.Version = 5
+	} else if ca.conf.Version.IsAtLeast(sarama.V0_11_0_0) {
+		request.Version = 4
+	}
+
+	response, err := b.GetMetadata(request)
+	if err != nil {
+		return nil, err
+	}
+	return response.Topics, nil
+}
+
+// DescribeCluster implements the sarama.ClusterAdmin interface
+func (ca *clusterAdmin) DescribeCluster() ([]*sarama.Broker, int32, error) {
+	b, err := ca.controller()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	request := &sarama.MetadataRequest{
+		Topics: []string{},
+	}
+	if ca.conf.Version.IsAtLeast(sarama.V0_10_0_0) {
+		request.Version = 1
+	}
+
+	response, err := b.GetMetadata(request)
+	if err != nil {
+		return nil, 0, err
+	}
+	return response.Brokers, response.ControllerID, nil
+}
+
+// ListTopics implements the sarama.ClusterAdmin interface
+func (ca *clusterAdmin) ListTopics() (map[string]sarama.TopicDetail, error) {
+	// In order to build TopicDetails we need to first get the list of all
+	// topics using a MetadataRequest and then get their configs using a
+	// single DescribeConfigsRequest.
+	b, err := ca.findAnyBroker()
+	if err != nil {
+		return nil, err
+	}
+	_ = b.Open(ca.conf)
+
+	metadataResp, err := b.GetMetadata(&sarama.MetadataRequest{})
+	if err != nil {
+		return nil, err
+	}
+
+	topicsDetailsMap := make(map[string]sarama.TopicDetail)
+	var describeConfigsResources []*sarama.ConfigResource
+	for _, topic := range metadataResp.Topics {
+		topicDetails := sarama.TopicDetail{
+			NumPartitions: int32(len(topic.Partitions)),
+		}
+		if len(topic.Partitions) > 0 {
+			topicDetails.ReplicaAssignment = map[int32][]int32{}
+			for _, partition := range topic.Partitions {
+				topicDetails.ReplicaAssignment[partition.ID] = partition.Replicas
+			}
+			topicDetails.ReplicationFactor = int16(len(topic.Partitions[0].Replicas))
+		}
+		topicsDetailsMap[topic.Name] = topicDetails
+
+		describeConfigsResources = append(describeConfigsResources, &sarama.ConfigResource{
+			Type: sarama.TopicResource,
+			Name: topic.Name,
+		})
+	}
+
+	describeConfigsResp, err := b.DescribeConfigs(&sarama.DescribeConfigsRequest{
+		Resources: describeConfigsResources,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, resource := range describeConfigsResp.Resources {
+		topicDetails := topicsDetailsMap[resource.Name]
+		topicDetails.ConfigEntries = make(map[string]*string)
+		for _, entry := range resource.Configs {
+			// only include non-default non-sensitive config
+			if entry.Default || entry.Sensitive {
+				continue
+			}
+			value := entry.Value
+			topicDetails.ConfigEntries[entry.Name] = &value
+		}
+		topicsDetailsMap[resource.Name] = topicDetails
+	}
+
+	return topicsDetailsMap, nil
+}
+
+// DeleteTopic implements the sarama.ClusterAdmin interface
+func (ca *clusterAdmin) DeleteTopic(topic string) error {
+	if topic == "" {
+		return sarama.ErrInvalidTopic
+	}
+
+	request := &sarama.DeleteTopicsRequest{
+		Topics:  []string{topic},
+		Timeout: ca.conf.Admin.Timeout,
+	}
+	if ca.conf.Version.IsAtLeast(sarama.V0_11_0_0) {
+		request.Version = 1
+	}
+
+	b, err := ca.controller()
+	if err != nil {
+		return err
+	}
+	rsp, err := b.DeleteTopics(request)
+	if err != nil {
+		return err
+	}
+	topicErr, ok := rsp.TopicErrorCodes[topic]
+	if !ok {
+		return sarama.ErrIncompleteResponse
+	}
+	if topicErr != sarama.ErrNoError {
+		return topicErr
+	}
+	return nil
+}
+
+// CreatePartitions implements the sarama.ClusterAdmin interface
+func (ca *clusterAdmin) CreatePartitions(topic string, count int32, assignment [][]int32, validateOnly bool) error {
+	if topic == "" {
+		return sarama.ErrInvalidTopic
+	}
+
+	request := &sarama.CreatePartitionsRequest{
+		TopicPartitions: map[string]*sarama.TopicPartition{
+			topic: {Count: count, Assignment: assignment},
+		},
+		Timeout:      ca.conf.Admin.Timeout,
+		ValidateOnly: validateOnly,
+	}
+
+	b, err := ca.controller()
+	if err != nil {
+		return err
+	}
+	rsp, err := b.CreatePartitions(request)
+	if err != nil {
+		return err
+	}
+	topicErr, ok := rsp.TopicPartitionErrors[topic]
+	if !ok {
+		return sarama.ErrIncompleteResponse
+	}
+	if topicErr.Err != sarama.ErrNoError {
+		return topicErr
+	}
+	return nil
+}
+
+// DeleteRecords implements the sarama.ClusterAdmin interface
+func (ca *clusterAdmin) DeleteRecords(topic string, partitionOffsets map[int32]int64) error {
+	if topic == "" {
+		return sarama.ErrInvalidTopic
+	}
+
+	// records can only be deleted by the leader of a partition, so send one request per leader
+	byLeader := map[*sarama.Broker]map[int32]int64{}
+	for partition, offset := range partitionOffsets {
+		leader, err := ca.client.Leader(topic, partition)
+		if err != nil {
+			return err
+		}
+		if byLeader[leader] == nil {
+			byLeader[leader] = map[int32]int64{}
+		}
+		byLeader[leader][partition] = offset
+	}
+
+	for leader, offsets := range byLeader {
+		request := &sarama.DeleteRecordsRequest{
+			Topics: map[string]*sarama.DeleteRecordsRequestTopic{
+				topic: {PartitionOffsets: offsets},
+			},
+			Timeout: ca.conf.Admin.Timeout,
+		}
+		rsp, err := leader.DeleteRecords(request)
+		if err != nil {
+			return err
+		}
+		rspTopic, ok := rsp.Topics[topic]
+		if !ok {
+			return sarama.ErrIncompleteResponse
+		}
+		for partition := range offsets {
+			rspPartition, ok := rspTopic.Partitions[partition]
+			if !ok {
+				return sarama.ErrIncompleteResponse
+			}
+			if rspPartition.Err != sarama.ErrNoError {
+				return &sarama.TopicPartitionError{Err: rspPartition.Err}
+			}
+		}
+	}
+	return nil
+}
+
+// DescribeConfig implements the sarama.ClusterAdmin interface
+func (ca *clusterAdmin) DescribeConfig(resource sarama.ConfigResource) ([]sarama.ConfigEntry, error) {
+	request := &sarama.DescribeConfigsRequest{
+		Resources: []*sarama.ConfigResource{&resource},
+	}
+	// v1 reports the source of each entry instead of only whether it is a default
+	if ca.conf.Version.IsAtLeast(sarama.V1_1_0_0) {
+		request.Version = 1
+		request.IncludeSynonyms = true
+	}
+
+	b, err := ca.controller()
+	if err != nil {
+		return nil, err
+	}
+	rsp, err := b.DescribeConfigs(request)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []sarama.ConfigEntry
+	for _, rspResource := range rsp.Resources {
+		if rspResource.Name == resource.Name {
+			if rspResource.ErrorCode != int16(sarama.ErrNoError) {
+				msg := rspResource.ErrorMsg
+				return nil, &sarama.TopicError{Err: sarama.KError(rspResource.ErrorCode), ErrMsg: &msg}
+			}
+			if rspResource.ErrorMsg != "" {
+				return nil, errors.New(rspResource.ErrorMsg)
+			}
+			for _, cfgEntry := range rspResource.Configs {
+				entries = append(entries, *cfgEntry)
+			}
+		}
+	}
+	return entries, nil
+}
+
+// AlterConfig implements the sarama.ClusterAdmin interface
+func (ca *clusterAdmin) AlterConfig(resourceType sarama.ConfigResourceType, name string, entries map[string]*string, validateOnly bool) error {
+	request := &sarama.AlterConfigsRequest{
+		Resources: []*sarama.AlterConfigsResource{
+			{Type: resourceType, Name: name, ConfigEntries: entries},
+		},
+		ValidateOnly: validateOnly,
+	}
+
+	b, err := ca.controller()
+	if err != nil {
+		return err
+	}
+	rsp, err := b.AlterConfigs(request)
+	if err != nil {
+		return err
+	}
+	for _, rspResource := range rsp.Resources {
+		if rspResource.Name == name && rspResource.ErrorCode != int16(sarama.ErrNoError) {
+			msg := rspResource.ErrorMsg
+			return &sarama.TopicError{Err: sarama.KError(rspResource.ErrorCode), ErrMsg: &msg}
+		}
+		if rspResource.Name == name && rspResource.ErrorMsg != "" {
+			return errors.New(rspResource.ErrorMsg)
+		}
+	}
+	return nil
+}
+
+// CreateACL implements the sarama.ClusterAdmin interface
+func (ca *clusterAdmin) CreateACL(resource sarama.Resource, acl sarama.Acl) error {
+	request := &sarama.CreateAclsRequest{
+		AclCreations: []*sarama.AclCreation{{Resource: resource, Acl: acl}},
+	}
+
+	b, err := ca.controller()
+	if err != nil {
+		return err
+	}
+	rsp, err := b.CreateAcls(request)
+	if err != nil {
+		return err
+	}
+	for _, r := range rsp.AclCreationResponses {
+		if r.Err != sarama.ErrNoError {
+			return r.Err
+		}
+	}
+	return nil
+}
+
+// ListAcls implements the sarama.ClusterAdmin interface
+func (ca *clusterAdmin) ListAcls(filter sarama.AclFilter) ([]sarama.ResourceAcls, error) {
+	b, err := ca.controller()
+	if err != nil {
+		return nil, err
+	}
+	rsp, err := b.DescribeAcls(&sarama.DescribeAclsRequest{AclFilter: filter})
+	if err != nil {
+		return nil, err
+	}
+	if rsp.Err != sarama.ErrNoError {
+		return nil, rsp.Err
+	}
+
+	var acls []sarama.ResourceAcls
+	for _, rACL := range rsp.ResourceAcls {
+		acls = append(acls, *rACL)
+	}
+	return acls, nil
+}
+
+// DeleteACL implements the sarama.ClusterAdmin interface
+func (ca *clusterAdmin) DeleteACL(filter sarama.AclFilter, validateOnly bool) ([]sarama.MatchingAcl, error) {
+	b, err := ca.controller()
+	if err != nil {
+		return nil, err
+	}
+	rsp, err := b.DeleteAcls(&sarama.DeleteAclsRequest{Filters: []*sarama.AclFilter{&filter}})
+	if err != nil {
+		return nil, err
+	}
+
+	var acls []sarama.MatchingAcl
+	for _, fr := range rsp.FilterResponses {
+		if fr.Err != sarama.ErrNoError {
+			return nil, fr.Err
+		}
+		for _, mACL := range fr.MatchingAcls {
+			acls = append(acls, *mACL)
+		}
+	}
+	return acls, nil
+}
+
+// DescribeConsumerGroups implements the sarama.ClusterAdmin interface
+func (ca *clusterAdmin) DescribeConsumerGroups(groups []string) ([]*sarama.GroupDescription, error) {
+	groupsPerBroker := make(map[*sarama.Broker][]string)
+	for _, group := range groups {
+		coordinator, err := ca.client.Coordinator(group)
+		if err != nil {
+			return nil, err
+		}
+		groupsPerBroker[coordinator] = append(groupsPerBroker[coordinator], group)
+	}
+
+	var result []*sarama.GroupDescription
+	for broker, brokerGroups := range groupsPerBroker {
+		response, err := broker.DescribeGroups(&sarama.DescribeGroupsRequest{Groups: brokerGroups})
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, response.Groups...)
+	}
+	return result, nil
+}
+
+// ListConsumerGroups implements the sarama.ClusterAdmin interface
+func (ca *clusterAdmin) ListConsumerGroups() (map[string]string, error) {
+	allGroups := make(map[string]string)
+
+	// Query brokers in parallel, since we have to query *all* brokers
+	brokers := ca.client.Brokers()
+	groupMaps := make(chan map[string]string, len(brokers))
+	errs := make(chan error, len(brokers))
+	wg := sync.WaitGroup{}
+
+	for _, b := range brokers {
+		wg.Add(1)
+		go func(b *sarama.Broker) {
+			defer wg.Done()
+			_ = b.Open(ca.conf) // Ensure that broker is opened
+
+			response, err := b.ListGroups(&sarama.ListGroupsRequest{})
+			if err != nil {
+				errs <- err
+				return
+			}
+			groupMaps <- response.Groups
+		}(b)
+	}
+
+	wg.Wait()
+	close(groupMaps)
+	close(errs)
+
+	for groupMap := range groupMaps {
+		for group, protocolType := range groupMap {
+			allGroups[group] = protocolType
+		}
+	}
+
+	// Intentionally return only the first error for simplicity
+	return allGroups, <-errs
+}
+
+// ListConsumerGroupOffsets implements the sarama.ClusterAdmin interface
+func (ca *clusterAdmin) ListConsumerGroupOffsets(group string, topicPartitions map[string][]int32) (*sarama.OffsetFetchResponse, error) {
+	coordinator, err := ca.client.Coordinator(group)
+	if err != nil {
+		return nil, err
+	}
+
+	request := &sarama.OffsetFetchRequest{ConsumerGroup: group}
+	if ca.conf.Version.IsAtLeast(sarama.V0_8_2_2) {
+		request.Version = 1
+	}
+	for topic, partitions := range topicPartitions {
+		for _, partition := range partitions {
+			request.AddPartition(topic, partition)
+		}
+	}
+
+	return coordinator.FetchOffset(request)
+}
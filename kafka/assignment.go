@@ -0,0 +1,160 @@
+package kafka
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/Shopify/sarama"
+)
+
+// BrokerInfo is the broker metadata needed for planning replica assignments
+type BrokerInfo struct {
+	ID   int32
+	Rack string
+}
+
+// BrokersFromSarama returns the id and rack of the brokers, e.g. from Client.Brokers
+func BrokersFromSarama(brokers []*sarama.Broker) []BrokerInfo {
+	infos := make([]BrokerInfo, 0, len(brokers))
+	for _, b := range brokers {
+		infos = append(infos, BrokerInfo{ID: b.ID(), Rack: b.Rack()})
+	}
+	return infos
+}
+
+// AssignmentOptions controls where an assignment starts
+type AssignmentOptions struct {
+	// FirstPartition is the id of the first planned partition, e.g. the current count when adding partitions
+	FirstPartition int32
+	// StartIndex is the index into the sorted brokers that leads the first partition
+	StartIndex int
+}
+
+// PlanReplicaAssignment plans the replicas of count partitions the same way kafka does when creating
+// topics. Leaders are spread round robin over the brokers and the followers are shifted for every
+// round so they are balanced as well. If the brokers have racks, the broker list alternates between
+// racks and every partition gets replicas in as many racks as possible. The plan only depends on
+// its input, the order of brokers doesn't matter.
+func PlanReplicaAssignment(brokers []BrokerInfo, count int32, replicationFactor int, options AssignmentOptions) (map[int32][]int32, error) {
+	if count < 0 {
+		return nil, fmt.Errorf("Number of partitions must not be negative")
+	}
+	if replicationFactor <= 0 {
+		return nil, fmt.Errorf("Replication factor must be larger than 0")
+	}
+	if replicationFactor > len(brokers) {
+		return nil, fmt.Errorf("Replication factor %d is larger than the %d available brokers", replicationFactor, len(brokers))
+	}
+	sorted := append([]BrokerInfo{}, brokers...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].ID < sorted[j].ID })
+
+	withRack := 0
+	for _, b := range sorted {
+		if len(b.Rack) != 0 {
+			withRack++
+		}
+	}
+	switch withRack {
+	case 0:
+		ids := make([]int32, len(sorted))
+		for i, b := range sorted {
+			ids[i] = b.ID
+		}
+		return assignRackUnaware(ids, count, replicationFactor, options), nil
+	case len(sorted):
+		return assignRackAware(sorted, count, replicationFactor, options), nil
+	default:
+		return nil, fmt.Errorf("Only %d of %d brokers have a rack, either all or none must have one", withRack, len(sorted))
+	}
+}
+
+// assignRackUnaware is kafka's assignment for brokers without racks
+func assignRackUnaware(brokers []int32, count int32, replicationFactor int, options AssignmentOptions) map[int32][]int32 {
+	n := len(brokers)
+	assignment := make(map[int32][]int32, count)
+	partition := options.FirstPartition
+	shift := options.StartIndex
+	for i := int32(0); i < count; i++ {
+		if partition > 0 && int(partition)%n == 0 {
+			shift++
+		}
+		first := (int(partition) + options.StartIndex) % n
+		replicas := []int32{brokers[first]}
+		for j := 0; j < replicationFactor-1; j++ {
+			replicas = append(replicas, brokers[replicaIndex(first, shift, j, n)])
+		}
+		assignment[partition] = replicas
+		partition++
+	}
+	return assignment
+}
+
+// assignRackAware is kafka's assignment for brokers with racks, a broker or rack only gets
+// a second replica of a partition once every broker or rack has one
+func assignRackAware(brokers []BrokerInfo, count int32, replicationFactor int, options AssignmentOptions) map[int32][]int32 {
+	rackOf := make(map[int32]string, len(brokers))
+	for _, b := range brokers {
+		rackOf[b.ID] = b.Rack
+	}
+	arranged, racks := rackAlternatedBrokers(brokers)
+	n := len(arranged)
+	assignment := make(map[int32][]int32, count)
+	partition := options.FirstPartition
+	shift := options.StartIndex
+	for i := int32(0); i < count; i++ {
+		if partition > 0 && int(partition)%n == 0 {
+			shift++
+		}
+		first := (int(partition) + options.StartIndex) % n
+		leader := arranged[first]
+		replicas := []int32{leader}
+		usedRacks := map[string]bool{rackOf[leader]: true}
+		usedBrokers := map[int32]bool{leader: true}
+		k := 0
+		for j := 0; j < replicationFactor-1; j++ {
+			for {
+				broker := arranged[replicaIndex(first, shift*racks, k, n)]
+				rack := rackOf[broker]
+				k++
+				if (!usedRacks[rack] || len(usedRacks) == racks) && (!usedBrokers[broker] || len(usedBrokers) == n) {
+					replicas = append(replicas, broker)
+					usedRacks[rack] = true
+					usedBrokers[broker] = true
+					break
+				}
+			}
+		}
+		assignment[partition] = replicas
+		partition++
+	}
+	return assignment
+}
+
+// replicaIndex returns the index of a follower, shifted by at least 1 from the leader
+func replicaIndex(first, shift, replica, n int) int {
+	s := 1 + (shift+replica)%(n-1)
+	return (first + s) % n
+}
+
+// rackAlternatedBrokers orders the brokers by taking one of each rack in turn, e.g.
+// rack1: 0, 5, rack2: 3, 4, rack3: 1, 2 becomes 0, 3, 1, 5, 4, 2. It also returns the number of racks.
+func rackAlternatedBrokers(brokers []BrokerInfo) ([]int32, int) {
+	byRack := map[string][]int32{}
+	var racks []string
+	for _, b := range brokers {
+		if _, ok := byRack[b.Rack]; !ok {
+			racks = append(racks, b.Rack)
+		}
+		byRack[b.Rack] = append(byRack[b.Rack], b.ID)
+	}
+	sort.Strings(racks)
+	arranged := make([]int32, 0, len(brokers))
+	for i := 0; len(arranged) < len(brokers); i++ {
+		for _, rack := range racks {
+			if i < len(byRack[rack]) {
+				arranged = append(arranged, byRack[rack][i])
+			}
+		}
+	}
+	return arranged, len(racks)
+}
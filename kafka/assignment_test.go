@@ -0,0 +1,95 @@
+package kafka_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/izolight/kafkalib/kafka"
+)
+
+func TestPlanReplicaAssignment_RackUnaware(t *testing.T) {
+	brokers := []kafka.BrokerInfo{{ID: 4}, {ID: 3}, {ID: 2}, {ID: 1}, {ID: 0}}
+	// same as kafka's AdminUtils.assignReplicasToBrokers with a fixed start index of 0
+	expected := map[int32][]int32{
+		0: {0, 1, 2},
+		1: {1, 2, 3},
+		2: {2, 3, 4},
+		3: {3, 4, 0},
+		4: {4, 0, 1},
+		5: {0, 2, 3},
+		6: {1, 3, 4},
+		7: {2, 4, 0},
+		8: {3, 0, 1},
+		9: {4, 1, 2},
+	}
+	got, err := kafka.PlanReplicaAssignment(brokers, 10, 3, kafka.AssignmentOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected %v but got %v", expected, got)
+	}
+}
+
+func TestPlanReplicaAssignment_RackAware(t *testing.T) {
+	brokers := []kafka.BrokerInfo{
+		{ID: 0, Rack: "rack1"}, {ID: 1, Rack: "rack3"}, {ID: 2, Rack: "rack3"},
+		{ID: 3, Rack: "rack2"}, {ID: 4, Rack: "rack2"}, {ID: 5, Rack: "rack1"},
+	}
+	rackOf := map[int32]string{}
+	for _, b := range brokers {
+		rackOf[b.ID] = b.Rack
+	}
+	got, err := kafka.PlanReplicaAssignment(brokers, 12, 3, kafka.AssignmentOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the racks alternate, so the leaders follow 0, 3, 1, 5, 4, 2
+	if got[0][0] != 0 || got[1][0] != 3 || got[2][0] != 1 || got[3][0] != 5 {
+		t.Errorf("Expected leaders to alternate racks but got %v", got)
+	}
+	leaders := map[int32]int{}
+	replicas := map[int32]int{}
+	for p, r := range got {
+		racks := map[string]bool{}
+		for _, id := range r {
+			racks[rackOf[id]] = true
+			replicas[id]++
+		}
+		if len(racks) != 3 {
+			t.Errorf("Expected partition %d to span 3 racks but got %v", p, r)
+		}
+		leaders[r[0]]++
+	}
+	for _, b := range brokers {
+		if leaders[b.ID] != 2 || replicas[b.ID] != 6 {
+			t.Errorf("Expected broker %d to lead 2 and host 6 replicas but got %d and %d", b.ID, leaders[b.ID], replicas[b.ID])
+		}
+	}
+
+	again, err := kafka.PlanReplicaAssignment([]kafka.BrokerInfo{brokers[5], brokers[3], brokers[1], brokers[0], brokers[4], brokers[2]}, 12, 3, kafka.AssignmentOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, again) {
+		t.Errorf("Expected the plan not to depend on the broker order")
+	}
+}
+
+func TestPlanReplicaAssignment_Invalid(t *testing.T) {
+	testCases := []struct {
+		name              string
+		brokers           []kafka.BrokerInfo
+		replicationFactor int
+	}{
+		{"not enough brokers", []kafka.BrokerInfo{{ID: 1}, {ID: 2}}, 3},
+		{"no replicas", []kafka.BrokerInfo{{ID: 1}}, 0},
+		{"partial racks", []kafka.BrokerInfo{{ID: 1, Rack: "a"}, {ID: 2}}, 1},
+	}
+	for _, tc := range testCases {
+		_, err := kafka.PlanReplicaAssignment(tc.brokers, 1, tc.replicationFactor, kafka.AssignmentOptions{})
+		if err == nil {
+			t.Errorf("%s: expected an error", tc.name)
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package kafka
 
 import (
+	"context"
 	"github.com/Shopify/sarama"
 	"github.com/izolight/kafkalib/format"
 )
@@ -13,6 +14,14 @@ type BrokerClient struct {
 
 // GetBrokers implements the AdminClient interface for BrokerClient
 func (c Conn) GetBrokers() ([]*sarama.Broker, error) {
+	return c.GetBrokersContext(context.Background())
+}
+
+// GetBrokersContext is GetBrokers with a context
+func (c Conn) GetBrokersContext(ctx context.Context) ([]*sarama.Broker, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, newError("getting brokers", "", err)
+	}
 	brokers := c.Client.Brokers()
 	return brokers, nil
 }
@@ -0,0 +1,186 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/Shopify/sarama"
+	"github.com/izolight/kafkalib/format"
+)
+
+// defaultBulkConcurrency is the number of requests in flight if BulkOptions.Concurrency is not set
+const defaultBulkConcurrency = 8
+
+// Selector selects existing topics for bulk operations
+type Selector interface {
+	Match(name string, detail sarama.TopicDetail) bool
+}
+
+// SelectorFunc adapts a function to the Selector interface
+type SelectorFunc func(name string, detail sarama.TopicDetail) bool
+
+// Match implements the Selector interface
+func (f SelectorFunc) Match(name string, detail sarama.TopicDetail) bool {
+	return f(name, detail)
+}
+
+// BulkOptions controls bulk operations
+type BulkOptions struct {
+	// Concurrency limits the number of requests in flight
+	Concurrency int
+	// ValidateOnly checks the operation without changing the cluster. Creation is validated by
+	// the controller, deletion only checks that the topics exist.
+	ValidateOnly bool
+}
+
+// CreateTopics creates the topics concurrently. A failing topic doesn't stop the others,
+// the outcome of each topic is in the result. Nothing is created if a name is listed twice.
+func (c Conn) CreateTopics(topics []NewTopic, options BulkOptions) (*format.BulkResult, error) {
+	return c.CreateTopicsContext(context.Background(), topics, options)
+}
+
+// CreateTopicsContext is CreateTopics with a context
+func (c Conn) CreateTopicsContext(ctx context.Context, topics []NewTopic, options BulkOptions) (*format.BulkResult, error) {
+	byName := make(map[string]NewTopic, len(topics))
+	names := make([]string, 0, len(topics))
+	for _, t := range topics {
+		if _, ok := byName[t.Name]; ok {
+			return nil, &Error{Op: "creating topics", Resource: t.Name, Kind: ErrInvalidConfig,
+				Err: fmt.Errorf("topic %s is listed more than once", t.Name)}
+		}
+		byName[t.Name] = t
+		names = append(names, t.Name)
+	}
+	// the names are checked together so the topics can't collide with each other
+	errs, err := c.checkTopicNames(ctx, names)
+	if err != nil {
+		return nil, err
+	}
+	rejected := map[string]error{}
+	for i, err := range errs {
+		if err != nil {
+			rejected[names[i]] = err
+		}
+	}
+	unchecked := c
+	unchecked.NamingPolicy = nil
+	results := runBulk(ctx, names, options.Concurrency, func(ctx context.Context, name string) (string, error) {
+		if err := rejected[name]; err != nil {
+			return "", err
+		}
+		if err := unchecked.createTopic(ctx, byName[name], options.ValidateOnly); err != nil {
+			return "", err
+		}
+		if options.ValidateOnly {
+			return format.StatusValidated, nil
+		}
+		return format.StatusCreated, nil
+	})
+	return &format.BulkResult{Operation: "create", Results: results}, nil
+}
+
+// DeleteTopics deletes the topics concurrently. A failing topic doesn't stop the others,
+// the outcome of each topic is in the result.
+func (c Conn) DeleteTopics(names []string, options BulkOptions) (*format.BulkResult, error) {
+	return c.DeleteTopicsContext(context.Background(), names, options)
+}
+
+// DeleteTopicsContext is DeleteTopics with a context
+func (c Conn) DeleteTopicsContext(ctx context.Context, names []string, options BulkOptions) (*format.BulkResult, error) {
+	var existing format.Topics
+	if options.ValidateOnly {
+		var err error
+		existing, err = c.GetAllTopicsContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+	}
+	results := runBulk(ctx, names, options.Concurrency, func(ctx context.Context, name string) (string, error) {
+		if options.ValidateOnly {
+			if _, ok := existing[name]; !ok {
+				return "", newKindError("deleting topic", name, ErrTopicNotFound)
+			}
+			return format.StatusValidated, nil
+		}
+		if err := c.DeleteTopicContext(ctx, name); err != nil {
+			return "", err
+		}
+		return format.StatusDeleted, nil
+	})
+	return &format.BulkResult{Operation: "delete", Results: results}, nil
+}
+
+// DeleteSelectedTopics deletes all topics matched by selector like DeleteTopics
+func (c Conn) DeleteSelectedTopics(selector Selector, options BulkOptions) (*format.BulkResult, error) {
+	return c.DeleteSelectedTopicsContext(context.Background(), selector, options)
+}
+
+// DeleteSelectedTopicsContext is DeleteSelectedTopics with a context
+func (c Conn) DeleteSelectedTopicsContext(ctx context.Context, selector Selector, options BulkOptions) (*format.BulkResult, error) {
+	names, err := c.SelectTopicsContext(ctx, selector)
+	if err != nil {
+		return nil, err
+	}
+	return c.DeleteTopicsContext(ctx, names, options)
+}
+
+// SelectTopics returns the sorted names of the topics matched by selector
+func (c Conn) SelectTopics(selector Selector) ([]string, error) {
+	return c.SelectTopicsContext(context.Background(), selector)
+}
+
+// SelectTopicsContext is SelectTopics with a context
+func (c Conn) SelectTopicsContext(ctx context.Context, selector Selector) ([]string, error) {
+	topics, err := c.GetSelectedTopicsContext(ctx, selector)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(topics))
+	for name := range topics {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// runBulk calls f for each name with at most concurrency calls at the same time and returns
+// the results in the order of names. Names not started before ctx is done are canceled.
+func runBulk(ctx context.Context, names []string, concurrency int, f func(ctx context.Context, name string) (string, error)) []format.TopicResult {
+	if concurrency <= 0 {
+		concurrency = defaultBulkConcurrency
+	}
+	results := make([]format.TopicResult, len(names))
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i, name := range names {
+		results[i].Topic = name
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			results[i].Status = format.StatusCanceled
+			results[i].Error = ctx.Err().Error()
+			continue
+		}
+		wg.Add(1)
+		go func(result *format.TopicResult) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			status, err := f(ctx, result.Topic)
+			switch {
+			case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+				result.Status = format.StatusCanceled
+				result.Error = err.Error()
+			case err != nil:
+				result.Status = format.StatusFailed
+				result.Error = err.Error()
+			default:
+				result.Status = status
+			}
+		}(&results[i])
+	}
+	wg.Wait()
+	return results
+}
@@ -0,0 +1,124 @@
+package kafka_test
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/Shopify/sarama"
+	"github.com/izolight/kafkalib/format"
+	"github.com/izolight/kafkalib/kafka"
+)
+
+func bulkStatuses(result *format.BulkResult) []string {
+	var statuses []string
+	for _, r := range result.Results {
+		statuses = append(statuses, r.Topic+"="+r.Status)
+	}
+	return statuses
+}
+
+func TestCreateTopics(t *testing.T) {
+	detail := sarama.TopicDetail{NumPartitions: 1, ReplicationFactor: 1}
+	topics := []kafka.NewTopic{
+		{Name: "first", TopicDetail: detail},
+		{Name: "simpleTopic", TopicDetail: detail},
+		{Name: "second", TopicDetail: detail},
+	}
+	testCases := []struct {
+		name     string
+		options  kafka.BulkOptions
+		expected []string
+		created  bool
+	}{
+		{"create", kafka.BulkOptions{Concurrency: 2}, []string{"first=created", "simpleTopic=failed", "second=created"}, true},
+		{"validate only", kafka.BulkOptions{ValidateOnly: true}, []string{"first=validated", "simpleTopic=failed", "second=validated"}, false},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			c := kafka.Conn{AdminClient: NewTestClient()}
+			result, err := c.CreateTopics(topics, tc.options)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := bulkStatuses(result); !reflect.DeepEqual(got, tc.expected) {
+				t.Errorf("Expected %v but got %v", tc.expected, got)
+			}
+			if result.Failed() != 1 {
+				t.Errorf("Expected 1 failed topic but got %d", result.Failed())
+			}
+			all, _ := c.GetAllTopics()
+			if _, ok := all["first"]; ok != tc.created {
+				t.Errorf("Expected topic created to be %t", tc.created)
+			}
+		})
+	}
+}
+
+func TestDeleteTopics(t *testing.T) {
+	testCases := []struct {
+		name     string
+		options  kafka.BulkOptions
+		expected []string
+		left     int
+	}{
+		{"delete", kafka.BulkOptions{}, []string{"simpleTopic=deleted", "unknown=failed", "topicWithPartitions=deleted"}, 2},
+		{"validate only", kafka.BulkOptions{ValidateOnly: true}, []string{"simpleTopic=validated", "unknown=failed", "topicWithPartitions=validated"}, 4},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			c := kafka.Conn{AdminClient: NewTestClient()}
+			result, err := c.DeleteTopics([]string{"simpleTopic", "unknown", "topicWithPartitions"}, tc.options)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := bulkStatuses(result); !reflect.DeepEqual(got, tc.expected) {
+				t.Errorf("Expected %v but got %v", tc.expected, got)
+			}
+			all, _ := c.GetAllTopics()
+			if len(all) != tc.left {
+				t.Errorf("Expected %d topics left but got %d", tc.left, len(all))
+			}
+		})
+	}
+}
+
+func TestDeleteSelectedTopics(t *testing.T) {
+	c := kafka.Conn{AdminClient: NewTestClient()}
+	selector := kafka.SelectorFunc(func(name string, detail sarama.TopicDetail) bool {
+		return strings.HasPrefix(name, "topicWith")
+	})
+	names, err := c.SelectTopics(selector)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := c.DeleteSelectedTopics(selector, kafka.BulkOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Results) != len(names) || result.Failed() != 0 {
+		t.Errorf("Expected %v deleted but got %v", names, bulkStatuses(result))
+	}
+	all, _ := c.GetAllTopics()
+	for name := range all {
+		if selector.Match(name, all[name]) {
+			t.Errorf("Expected %s to be deleted", name)
+		}
+	}
+}
+
+func TestCreateTopicsContext_Canceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	c := kafka.Conn{AdminClient: NewTestClient()}
+	result, err := c.CreateTopicsContext(ctx, []kafka.NewTopic{{Name: "first"}, {Name: "second"}}, kafka.BulkOptions{Concurrency: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, r := range result.Results {
+		if r.Status != format.StatusCanceled {
+			t.Errorf("Expected %s to be canceled but got %s", r.Topic, r.Status)
+		}
+	}
+}
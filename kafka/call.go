@@ -0,0 +1,24 @@
+package kafka
+
+import (
+	"context"
+)
+
+// call runs f and returns its error, or an error wrapping ctx.Err() if ctx is done first.
+// sarama has no support for contexts, so an abandoned call keeps running in the background
+// until its own network timeouts hit, but the caller is released immediately.
+func call(ctx context.Context, op, resource string, f func() error) error {
+	if err := ctx.Err(); err != nil {
+		return newError(op, resource, err)
+	}
+	done := make(chan error, 1)
+	go func() {
+		done <- f()
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return newError(op, resource, ctx.Err())
+	}
+}
@@ -0,0 +1,65 @@
+package kafka_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/izolight/kafkalib/kafka"
+)
+
+// blockingClient blocks ListTopics until release is closed
+type blockingClient struct {
+	sarama.ClusterAdmin
+	release chan struct{}
+}
+
+func (b *blockingClient) ListTopics() (map[string]sarama.TopicDetail, error) {
+	<-b.release
+	return b.ClusterAdmin.ListTopics()
+}
+
+func TestContext_Deadline(t *testing.T) {
+	client := &blockingClient{NewTestClient(), make(chan struct{})}
+	defer close(client.release)
+	c := kafka.Conn{
+		AdminClient: client,
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := c.GetAllTopicsContext(ctx)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("%v should be %v", err, context.DeadlineExceeded)
+	}
+	if errors.Is(err, kafka.ErrBrokerUnavailable) {
+		t.Errorf("%v should not be classified as %v", err, kafka.ErrBrokerUnavailable)
+	}
+	if time.Since(start) > time.Second {
+		t.Error("Call did not return at the deadline")
+	}
+}
+
+func TestContext_Canceled(t *testing.T) {
+	client := NewTestClient()
+	c := kafka.Conn{
+		AdminClient: client,
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := c.CreateTopicContext(ctx, kafka.NewTopic{Name: "canceledTopic", TopicDetail: sarama.TopicDetail{NumPartitions: 1, ReplicationFactor: 1}})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("%v should be %v", err, context.Canceled)
+	}
+	topics, err := c.GetAllTopics()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := topics["canceledTopic"]; ok {
+		t.Error("Topic was created even though the context was canceled")
+	}
+}
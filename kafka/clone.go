@@ -0,0 +1,167 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/Shopify/sarama"
+	"github.com/izolight/kafkalib/format"
+)
+
+// CloneOptions controls how CloneTopic creates the clone
+type CloneOptions struct {
+	// Target is the connection the clone is created with, the source connection is used if it is nil
+	Target *Conn
+	// Partitions overrides the partition count of the source if it is set
+	Partitions int32
+	// ReplicationFactor overrides the replication factor of the source if it is set
+	ReplicationFactor int16
+	// Config is merged into the config overrides of the source, an empty value drops the override
+	Config map[string]string
+	// ACLs copies the literal acls of the source topic to the clone
+	ACLs bool
+	// DryRun only validates the creation and returns what would be created
+	DryRun bool
+}
+
+// CloneTopic creates the topic target with the partitions, replication factor and config overrides of
+// source. The replica assignment is not copied, the target cluster places the replicas itself.
+// The acls are copied after the topic was created. If that fails the topic is kept and the clone is
+// returned with Incomplete set together with the error, its ACLs are the ones that were copied.
+func (c Conn) CloneTopic(source, target string, options CloneOptions) (*format.TopicClone, error) {
+	return c.CloneTopicContext(context.Background(), source, target, options)
+}
+
+// CloneTopicContext is CloneTopic with a context
+func (c Conn) CloneTopicContext(ctx context.Context, source, target string, options CloneOptions) (*format.TopicClone, error) {
+	const op = "cloning topic"
+	dst := c
+	if options.Target != nil {
+		dst = *options.Target
+	} else if source == target {
+		return nil, &Error{Op: op, Resource: source, Kind: ErrInvalidConfig, Err: fmt.Errorf("target must differ from the source on the same connection")}
+	}
+
+	clone, acls, err := c.planClone(ctx, source, target, options)
+	if err != nil {
+		return nil, err
+	}
+	topic := NewTopic{Name: target, TopicDetail: sarama.TopicDetail{
+		NumPartitions:     clone.Partitions,
+		ReplicationFactor: clone.ReplicationFactor,
+		ConfigEntries:     map[string]*string{},
+	}}
+	for name, value := range clone.Config {
+		v := value
+		topic.ConfigEntries[name] = &v
+	}
+	if err := dst.createTopic(ctx, topic, options.DryRun); err != nil {
+		return nil, newError(op, target, err)
+	}
+	if options.DryRun {
+		return clone, nil
+	}
+	for i, acl := range acls {
+		err := dst.CreateACLContext(ctx, acl)
+		if err != nil && !errors.Is(err, ErrACLExists) {
+			clone.ACLs = clone.ACLs[:i]
+			clone.Incomplete = true
+			return clone, newError(op, target, err)
+		}
+	}
+	return clone, nil
+}
+
+// planClone reads the source topic and applies the overrides of options
+func (c Conn) planClone(ctx context.Context, source, target string, options CloneOptions) (*format.TopicClone, []*sarama.AclCreation, error) {
+	const op = "cloning topic"
+	topics, err := c.GetAllTopicsContext(ctx)
+	if err != nil {
+		return nil, nil, newError(op, source, err)
+	}
+	detail, ok := topics[source]
+	if !ok {
+		return nil, nil, newKindError(op, source, ErrTopicNotFound)
+	}
+	config, err := c.GetTopicConfigContext(ctx, source)
+	if err != nil {
+		return nil, nil, newError(op, source, err)
+	}
+
+	clone := &format.TopicClone{
+		Source:            source,
+		Topic:             target,
+		Partitions:        detail.NumPartitions,
+		ReplicationFactor: detail.ReplicationFactor,
+		Config:            map[string]string{},
+		DryRun:            options.DryRun,
+	}
+	if options.Partitions > 0 {
+		clone.Partitions = options.Partitions
+	}
+	if options.ReplicationFactor > 0 {
+		clone.ReplicationFactor = options.ReplicationFactor
+	}
+	var sensitive []string
+	for _, e := range config.Overrides() {
+		if e.Sensitive {
+			sensitive = append(sensitive, e.Name)
+		}
+		clone.Config[e.Name] = e.Value
+	}
+	for name, value := range options.Config {
+		if value == "" {
+			delete(clone.Config, name)
+			continue
+		}
+		clone.Config[name] = value
+	}
+	for _, name := range sensitive {
+		// the broker doesn't return sensitive values, they can't be copied
+		if value, ok := clone.Config[name]; ok && value == "" {
+			return nil, nil, &Error{Op: op, Resource: source, Kind: ErrInvalidConfig,
+				Err: fmt.Errorf("sensitive config %s can't be copied, set or drop it explicitly", name)}
+		}
+	}
+
+	if !options.ACLs {
+		return clone, nil, nil
+	}
+	var resourceAcls []sarama.ResourceAcls
+	err = call(ctx, op, source, func() error {
+		var err error
+		name := source
+		resourceAcls, err = c.admin(ctx).ListAcls(sarama.AclFilter{
+			ResourceType:              sarama.AclResourceTopic,
+			ResourceName:              &name,
+			ResourcePatternTypeFilter: sarama.AclPatternLiteral,
+			Operation:                 sarama.AclOperationAny,
+			PermissionType:            sarama.AclPermissionAny,
+		})
+		return err
+	})
+	if err != nil {
+		return nil, nil, newError(op, source, err)
+	}
+	var acls []*sarama.AclCreation
+	for _, r := range resourceAcls {
+		// prefixed acls would also match other topics, only literal ones belong to the source
+		if r.ResourceType != sarama.AclResourceTopic || r.ResourceName != source || r.ResoucePatternType == sarama.AclPatternPrefixed {
+			continue
+		}
+		for _, a := range r.Acls {
+			acls = append(acls, &sarama.AclCreation{
+				Resource: sarama.Resource{ResourceType: sarama.AclResourceTopic, ResourceName: target, ResoucePatternType: r.ResoucePatternType},
+				Acl:      *a,
+			})
+			clone.ACLs = append(clone.ACLs, format.CloneACL{
+				Principal:  a.Principal,
+				Host:       a.Host,
+				Operation:  format.Operation(a.Operation),
+				Permission: format.Permission(a.PermissionType),
+			})
+		}
+	}
+	return clone, acls, nil
+}
@@ -0,0 +1,136 @@
+package kafka_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/Shopify/sarama"
+	"github.com/izolight/kafkalib/kafka"
+)
+
+func TestCloneTopic(t *testing.T) {
+	compact := "compact"
+	testCases := []struct {
+		name     string
+		target   string
+		options  kafka.CloneOptions
+		config   map[string]string
+		replicas int16
+		created  bool
+		success  bool
+	}{
+		{"same cluster", "topicWithReplicas-v2", kafka.CloneOptions{}, map[string]string{"cleanup.policy": "compact"}, 3, true, true},
+		{"overrides", "topicWithReplicas-v2", kafka.CloneOptions{ReplicationFactor: 2, Config: map[string]string{"cleanup.policy": "", "retention.ms": "1000"}},
+			map[string]string{"retention.ms": "1000"}, 2, true, true},
+		{"dry run", "topicWithReplicas-v2", kafka.CloneOptions{DryRun: true}, map[string]string{"cleanup.policy": "compact"}, 3, false, true},
+		{"exists on target", "topicWithReplicas", kafka.CloneOptions{Target: &kafka.Conn{AdminClient: NewTestClient()}}, nil, 0, false, false},
+		{"same name", "topicWithReplicas", kafka.CloneOptions{}, nil, 0, false, false},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			admin := NewTestClient()
+			detail := admin.(*testClient).topics["topicWithReplicas"]
+			detail.ConfigEntries = map[string]*string{"cleanup.policy": &compact}
+			admin.(*testClient).topics["topicWithReplicas"] = detail
+			c := kafka.Conn{AdminClient: admin}
+
+			clone, err := c.CloneTopic("topicWithReplicas", tc.target, tc.options)
+			if !tc.success {
+				if err == nil {
+					t.Fatal("Expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(clone.Config, tc.config) {
+				t.Errorf("Expected config %v but got %v", tc.config, clone.Config)
+			}
+			if clone.Partitions != 1 || clone.ReplicationFactor != tc.replicas {
+				t.Errorf("Expected 1 partition with %d replicas but got %d with %d", tc.replicas, clone.Partitions, clone.ReplicationFactor)
+			}
+			created, ok := admin.(*testClient).topics[tc.target]
+			if ok != tc.created {
+				t.Fatalf("Expected created to be %t", tc.created)
+			}
+			if ok && created.ReplicationFactor != tc.replicas {
+				t.Errorf("Expected created topic with %d replicas but got %d", tc.replicas, created.ReplicationFactor)
+			}
+		})
+	}
+}
+
+func TestCloneTopic_ACLs(t *testing.T) {
+	source := kafka.Conn{AdminClient: NewTestClient()}
+	err := source.CreateACL(&sarama.AclCreation{
+		Resource: sarama.Resource{ResourceName: "simpleTopic", ResourceType: sarama.AclResourceTopic},
+		Acl:      sarama.Acl{Principal: "User:app", Host: "*", Operation: sarama.AclOperationRead, PermissionType: sarama.AclPermissionAllow},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	target := kafka.Conn{AdminClient: NewTestClient()}
+	clone, err := source.CloneTopic("simpleTopic", "simpleTopic-staging", kafka.CloneOptions{Target: &target, ACLs: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(clone.ACLs) != 1 || clone.ACLs[0].Principal != "User:app" {
+		t.Errorf("Expected the acl of User:app but got %v", clone.ACLs)
+	}
+	acls, err := target.GetACLs(&sarama.AclFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(acls.ByPrincipal["User:app"]) != 1 {
+		t.Errorf("Expected the acl to be created on the target but got %v", acls.ByPrincipal)
+	}
+}
+
+func TestCloneTopic_Missing(t *testing.T) {
+	c := kafka.Conn{AdminClient: NewTestClient()}
+	_, err := c.CloneTopic("missing", "missing-v2", kafka.CloneOptions{})
+	if !errors.Is(err, kafka.ErrTopicNotFound) {
+		t.Errorf("Expected ErrTopicNotFound but got %v", err)
+	}
+}
+
+// aclLimitClient denies the creation of acls once limit acls were created
+type aclLimitClient struct {
+	sarama.ClusterAdmin
+	limit int
+}
+
+func (a *aclLimitClient) CreateACL(resource sarama.Resource, acl sarama.Acl) error {
+	if a.limit == 0 {
+		return sarama.ErrClusterAuthorizationFailed
+	}
+	a.limit--
+	return a.ClusterAdmin.CreateACL(resource, acl)
+}
+
+func TestCloneTopic_ACLsFailed(t *testing.T) {
+	source := kafka.Conn{AdminClient: NewTestClient()}
+	for _, principal := range []string{"User:app", "User:audit"} {
+		err := source.CreateACL(&sarama.AclCreation{
+			Resource: sarama.Resource{ResourceName: "simpleTopic", ResourceType: sarama.AclResourceTopic},
+			Acl:      sarama.Acl{Principal: principal, Host: "*", Operation: sarama.AclOperationRead, PermissionType: sarama.AclPermissionAllow},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	targetAdmin := NewTestClient()
+	target := kafka.Conn{AdminClient: &aclLimitClient{ClusterAdmin: targetAdmin, limit: 1}}
+	clone, err := source.CloneTopic("simpleTopic", "simpleTopic-staging", kafka.CloneOptions{Target: &target, ACLs: true})
+	if !errors.Is(err, kafka.ErrAuthorizationDenied) {
+		t.Fatalf("Expected ErrAuthorizationDenied but got %v", err)
+	}
+	if clone == nil || !clone.Incomplete || len(clone.ACLs) != 1 {
+		t.Fatalf("Expected an incomplete clone with one acl but got %+v", clone)
+	}
+	if _, ok := targetAdmin.(*testClient).topics["simpleTopic-staging"]; !ok {
+		t.Errorf("Expected the topic to be kept")
+	}
+}
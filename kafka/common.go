@@ -1,7 +1,9 @@
 package kafka
 
 import (
-	"crypto/tls"
+	"strings"
+	"time"
+
 	"github.com/Shopify/sarama"
 )
 
@@ -10,77 +12,242 @@ type Conn struct {
 	AdminClient sarama.ClusterAdmin
 	Client      sarama.Client
 	Consumer    sarama.Consumer
+	// Version is the kafka protocol version used by the connection
+	Version sarama.KafkaVersion
+	// NamingPolicy is checked before creating topics, nil allows all names the brokers accept
+	NamingPolicy NamingPolicy
 }
 
-// Config holds the config values for connecting to kafka
+// Config holds the config values for connecting to kafka.
+// Password, TLSKeyPassword, TLSKeyPEM and OAuthClientSecret may reference a secret as
+// described in ResolveSecret, they are resolved when building the sarama config.
 type Config struct {
-	BrokerList            []string
+	BrokerList []string
+	// Version is the kafka version of the brokers, e.g. 2.1.0. If it is empty or auto
+	// the version is negotiated with the cluster when connecting.
+	Version string
+	// ClientID defaults to kafkactl
+	ClientID string
+	// DialTimeout, ReadTimeout, WriteTimeout, AdminTimeout and MetadataRefresh
+	// override the sarama defaults when they are set
+	DialTimeout           time.Duration
+	ReadTimeout           time.Duration
+	WriteTimeout          time.Duration
+	AdminTimeout          time.Duration
+	MetadataRefresh       time.Duration
 	TLSEnabled            bool
 	TLSInsecureSkipVerify bool
-	User                  string
-	Password              string
+	// TLSCAFile and TLSCAPEM add CA certificates used for verifying the brokers
+	TLSCAFile string
+	TLSCAPEM  string
+	// TLSCertFile/TLSKeyFile or TLSCertPEM/TLSKeyPEM hold the client certificate for mutual TLS,
+	// TLSKeyPassword is used if the key is an encrypted PKCS#8 or legacy PEM encrypted key
+	TLSCertFile    string
+	TLSKeyFile     string
+	TLSCertPEM     string
+	TLSKeyPEM      string
+	TLSKeyPassword string
+	TLSServerName  string
+	// TLSMinVersion is one of 1.0, 1.1, 1.2 or 1.3
+	TLSMinVersion string
+	// SASLMechanism is one of PLAIN (default), SCRAM-SHA-256, SCRAM-SHA-512 or OAUTHBEARER
+	SASLMechanism string
+	User          string
+	Password      string
+	// TokenProvider is used for OAUTHBEARER, if it is nil a ClientCredentialsTokenProvider
+	// is created from the OAuth fields
+	TokenProvider     sarama.AccessTokenProvider `json:"-"`
+	OAuthTokenURL     string
+	OAuthClientID     string
+	OAuthClientSecret string
+	OAuthScopes       []string
+	// Retry enables retrying admin calls that fail with transient errors, see RetryPolicy
+	Retry *RetryPolicy
+	// ConsumerErrors sets Consumer.Return.Errors, so Consume reports why a partition failed instead of
+	// sarama only logging it. Code reading the Consumer of the Conn directly then has to drain the Errors
+	// of its partition consumers, or they stop fetching once the error buffer is full.
+	ConsumerErrors bool
+	// NamingPolicy is set on the Conn created by NewConn, nil uses DefaultNamingPolicy and an empty
+	// NamingPolicies allows all names the brokers accept. Policies that compare with the existing topics,
+	// like the default one, cost a metadata request for all topics before each creation.
+	NamingPolicy NamingPolicy `json:"-"`
 }
 
-// NewAdminClient wraps the Admin creation of sarama
-func NewAdminClient(config *Config) (sarama.ClusterAdmin, error) {
+// defaultClientID is used when Config.ClientID is empty
+const defaultClientID = "kafkactl"
+
+// NewSaramaConfig builds the sarama config shared by all constructors. The version is only
+// set if Config.Version is explicit, the constructors negotiate it otherwise.
+func NewSaramaConfig(config *Config) (*sarama.Config, error) {
+	cfg, err := buildSaramaConfig(config)
+	if err != nil {
+		return nil, &Error{Op: "building config", Kind: ErrInvalidConfig, Err: err}
+	}
+	return cfg, nil
+}
+
+func buildSaramaConfig(config *Config) (*sarama.Config, error) {
+	config, err := config.resolveSecrets()
+	if err != nil {
+		return nil, err
+	}
 	cfg := sarama.NewConfig()
-	cfg.ClientID = "kafkactl"
-	cfg.Version = sarama.V2_0_0_0
+	cfg.ClientID = defaultClientID
+	if len(config.ClientID) != 0 {
+		cfg.ClientID = config.ClientID
+	}
+	version, ok, err := parseVersion(config.Version)
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		cfg.Version = version
+	}
+	if config.DialTimeout > 0 {
+		cfg.Net.DialTimeout = config.DialTimeout
+	}
+	if config.ReadTimeout > 0 {
+		cfg.Net.ReadTimeout = config.ReadTimeout
+	}
+	if config.WriteTimeout > 0 {
+		cfg.Net.WriteTimeout = config.WriteTimeout
+	}
+	if config.AdminTimeout > 0 {
+		cfg.Admin.Timeout = config.AdminTimeout
+	}
+	if config.MetadataRefresh > 0 {
+		cfg.Metadata.RefreshFrequency = config.MetadataRefresh
+	}
+	cfg.Consumer.Return.Errors = config.ConsumerErrors
 	cfg.Net.TLS.Enable = config.TLSEnabled
-	cfg.Net.TLS.Config = &tls.Config{
-		InsecureSkipVerify: config.TLSInsecureSkipVerify,
+	if config.TLSEnabled {
+		tlsConfig, err := newTLSConfig(config)
+		if err != nil {
+			return nil, err
+		}
+		cfg.Net.TLS.Config = tlsConfig
+	}
+	if err := configureSASL(cfg, config); err != nil {
+		return nil, err
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// newConnectedConfig builds the sarama config and negotiates the version with the cluster if needed
+func newConnectedConfig(config *Config) (*sarama.Config, error) {
+	cfg, err := NewSaramaConfig(config)
+	if err != nil {
+		return nil, err
 	}
-	if len(config.User) != 0 && len(config.Password) != 0 {
-		cfg.Net.SASL.Enable = true
-		cfg.Net.SASL.User = config.User
-		cfg.Net.SASL.Password = config.Password
+	if _, ok, _ := parseVersion(config.Version); !ok {
+		version, err := NegotiateVersion(config.BrokerList, cfg)
+		if err != nil {
+			return nil, newError("connecting to", strings.Join(config.BrokerList, ","), err)
+		}
+		cfg.Version = version
 	}
+	return cfg, nil
+}
 
-	admin, err := sarama.NewClusterAdmin(config.BrokerList, cfg)
+// NewConn creates a Conn whose AdminClient, Client and Consumer share a single sarama.Client
+func NewConn(config *Config) (*Conn, error) {
+	cfg, err := newConnectedConfig(config)
 	if err != nil {
 		return nil, err
 	}
+	client, err := sarama.NewClient(config.BrokerList, cfg)
+	if err != nil {
+		return nil, newError("connecting to", strings.Join(config.BrokerList, ","), err)
+	}
+	admin, err := newClusterAdminFromClient(client)
+	if err != nil {
+		client.Close()
+		return nil, newError("connecting to", strings.Join(config.BrokerList, ","), err)
+	}
+	if config.Retry != nil {
+		admin = NewRetryingAdmin(admin, client, *config.Retry)
+	}
+	consumer, err := sarama.NewConsumerFromClient(client)
+	if err != nil {
+		admin.Close()
+		client.Close()
+		return nil, newError("connecting to", strings.Join(config.BrokerList, ","), err)
+	}
+	naming := config.NamingPolicy
+	if naming == nil {
+		naming = DefaultNamingPolicy
+	}
+	return &Conn{
+		AdminClient:  admin,
+		Client:       client,
+		Consumer:     consumer,
+		Version:      cfg.Version,
+		NamingPolicy: naming,
+	}, nil
+}
+
+// Close tears down the Consumer, AdminClient and Client in that order and returns the first error
+func (c Conn) Close() error {
+	var firstErr error
+	if c.Consumer != nil {
+		if err := c.Consumer.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	if c.AdminClient != nil {
+		if err := c.AdminClient.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	if c.Client != nil && !c.Client.Closed() {
+		if err := c.Client.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// NewAdminClient creates a ClusterAdmin with its own client, closing the admin closes the client
+func NewAdminClient(config *Config) (sarama.ClusterAdmin, error) {
+	cfg, err := newConnectedConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	admin, client, err := newClusterAdmin(config.BrokerList, cfg)
+	if err != nil {
+		return nil, newError("connecting to", strings.Join(config.BrokerList, ","), err)
+	}
+	if config.Retry != nil {
+		admin = NewRetryingAdmin(admin, client, *config.Retry)
+	}
 	return admin, err
 }
 
 // NewConsumer wraps the Consumer creation of sarama
 func NewConsumer(config *Config) (sarama.Consumer, error) {
-	cfg := sarama.NewConfig()
-	cfg.ClientID = "kafkactl"
-	cfg.Version = sarama.V2_0_0_0
-	cfg.Net.TLS.Enable = config.TLSEnabled
-	cfg.Net.TLS.Config = &tls.Config{
-		InsecureSkipVerify: config.TLSInsecureSkipVerify,
-	}
-	if len(config.User) != 0 && len(config.Password) != 0 {
-		cfg.Net.SASL.Enable = true
-		cfg.Net.SASL.User = config.User
-		cfg.Net.SASL.Password = config.Password
+	cfg, err := newConnectedConfig(config)
+	if err != nil {
+		return nil, err
 	}
 	consumer, err := sarama.NewConsumer(config.BrokerList, cfg)
 	if err != nil {
-		return nil, err
+		return nil, newError("connecting to", strings.Join(config.BrokerList, ","), err)
 	}
 	return consumer, err
 }
 
 // NewClient wraps the Client creation of sarama
 func NewClient(config *Config) (sarama.Client, error) {
-	cfg := sarama.NewConfig()
-	cfg.ClientID = "kafkactl"
-	cfg.Version = sarama.V2_0_0_0
-	cfg.Net.TLS.Enable = config.TLSEnabled
-	cfg.Net.TLS.Config = &tls.Config{
-		InsecureSkipVerify: config.TLSInsecureSkipVerify,
-	}
-	if len(config.User) != 0 && len(config.Password) != 0 {
-		cfg.Net.SASL.Enable = true
-		cfg.Net.SASL.User = config.User
-		cfg.Net.SASL.Password = config.Password
+	cfg, err := newConnectedConfig(config)
+	if err != nil {
+		return nil, err
 	}
 	client, err := sarama.NewClient(config.BrokerList, cfg)
 	if err != nil {
-		return nil, err
+		return nil, newError("connecting to", strings.Join(config.BrokerList, ","), err)
 	}
 	return client, err
 }
@@ -3,11 +3,22 @@ package kafka_test
 import (
 	"fmt"
 	"github.com/Shopify/sarama"
+	"github.com/izolight/kafkalib/kafka"
+	"sort"
+	"sync"
+	"testing"
+	"time"
 )
 
 type testClient struct {
+	// mu guards topics against the concurrent calls of bulk operations
+	mu     sync.Mutex
 	topics map[string]sarama.TopicDetail
 	acls   []sarama.ResourceAcls
+	// deletedRecords holds the offsets of the DeleteRecords calls per topic
+	deletedRecords map[string]map[int32]int64
+	// brokerAddrs replaces the address of brokers in DescribeCluster, e.g. with a testRawBroker
+	brokerAddrs map[int32]string
 }
 
 // NewTestClient creates a client that is used in tests
@@ -43,8 +54,14 @@ func NewTestClient() sarama.ClusterAdmin {
 }
 
 func (t *testClient) CreateTopic(topic string, detail *sarama.TopicDetail, validateOnly bool) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
 	if _, ok := t.topics[topic]; ok {
-		return fmt.Errorf("Topic %s already exists", topic)
+		msg := fmt.Sprintf("Topic '%s' already exists.", topic)
+		return &sarama.TopicError{Err: sarama.ErrTopicAlreadyExists, ErrMsg: &msg}
+	}
+	if validateOnly {
+		return nil
 	}
 	t.topics[topic] = *detail
 	return nil
@@ -58,31 +75,127 @@ func (t *testClient) ListTopics() (map[string]sarama.TopicDetail, error) {
 }
 
 func (t *testClient) DescribeTopics(topics []string) (metadata []*sarama.TopicMetadata, err error) {
-	panic("not implemented")
+	if t.topics == nil {
+		return nil, fmt.Errorf("Error describing topics")
+	}
+	if topics == nil {
+		// like the broker, no topics describes all of them
+		for name := range t.topics {
+			topics = append(topics, name)
+		}
+		sort.Strings(topics)
+	}
+	for _, name := range topics {
+		detail, ok := t.topics[name]
+		if !ok {
+			metadata = append(metadata, &sarama.TopicMetadata{Name: name, Err: sarama.ErrUnknownTopicOrPartition})
+			continue
+		}
+		m := &sarama.TopicMetadata{Name: name}
+		for p := int32(0); p < detail.NumPartitions; p++ {
+			replicas := detail.ReplicaAssignment[p]
+			if replicas == nil {
+				// spread the replicas over brokers 1 to 3 like the default assignment
+				for r := int32(0); r < int32(detail.ReplicationFactor); r++ {
+					replicas = append(replicas, (p+r)%3+1)
+				}
+			}
+			m.Partitions = append(m.Partitions, &sarama.PartitionMetadata{
+				ID:       p,
+				Leader:   replicas[0],
+				Replicas: replicas,
+				Isr:      replicas,
+			})
+		}
+		metadata = append(metadata, m)
+	}
+	return metadata, nil
 }
 
 func (t *testClient) DeleteTopic(topic string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
 	if _, ok := t.topics[topic]; ok {
 		delete(t.topics, topic)
 		return nil
 	}
-	return fmt.Errorf("Topic %s not found", topic)
+	return sarama.ErrUnknownTopicOrPartition
 }
 
 func (t *testClient) CreatePartitions(topic string, count int32, assignment [][]int32, validateOnly bool) error {
-	panic("not implemented")
+	detail, ok := t.topics[topic]
+	if !ok {
+		return &sarama.TopicPartitionError{Err: sarama.ErrUnknownTopicOrPartition}
+	}
+	if count <= detail.NumPartitions {
+		return &sarama.TopicPartitionError{Err: sarama.ErrInvalidPartitions}
+	}
+	if validateOnly {
+		return nil
+	}
+	if assignment != nil {
+		replicaAssignment := map[int32][]int32{}
+		for p, replicas := range detail.ReplicaAssignment {
+			replicaAssignment[p] = replicas
+		}
+		for i, replicas := range assignment {
+			replicaAssignment[detail.NumPartitions+int32(i)] = replicas
+		}
+		detail.ReplicaAssignment = replicaAssignment
+	}
+	detail.NumPartitions = count
+	t.topics[topic] = detail
+	return nil
 }
 
 func (t *testClient) DeleteRecords(topic string, partitionOffsets map[int32]int64) error {
-	panic("not implemented")
+	if _, ok := t.topics[topic]; !ok {
+		return &sarama.TopicPartitionError{Err: sarama.ErrUnknownTopicOrPartition}
+	}
+	if t.deletedRecords == nil {
+		t.deletedRecords = map[string]map[int32]int64{}
+	}
+	t.deletedRecords[topic] = partitionOffsets
+	return nil
+}
+
+// testConfigDefaults are the broker defaults reported for every topic
+var testConfigDefaults = map[string]string{
+	"cleanup.policy":      "delete",
+	"min.insync.replicas": "1",
+	"retention.ms":        "604800000",
 }
 
 func (t *testClient) DescribeConfig(resource sarama.ConfigResource) ([]sarama.ConfigEntry, error) {
-	panic("not implemented")
+	if t.topics == nil {
+		return nil, fmt.Errorf("Error describing config")
+	}
+	detail, ok := t.topics[resource.Name]
+	if !ok {
+		return nil, &sarama.TopicError{Err: sarama.ErrUnknownTopicOrPartition}
+	}
+	var entries []sarama.ConfigEntry
+	for name, value := range testConfigDefaults {
+		if _, ok := detail.ConfigEntries[name]; !ok {
+			entries = append(entries, sarama.ConfigEntry{Name: name, Value: value, Default: true, Source: sarama.SourceDefault})
+		}
+	}
+	for name, value := range detail.ConfigEntries {
+		entries = append(entries, sarama.ConfigEntry{Name: name, Value: *value, Source: sarama.SourceTopic})
+	}
+	return entries, nil
 }
 
 func (t *testClient) AlterConfig(resourceType sarama.ConfigResourceType, name string, entries map[string]*string, validateOnly bool) error {
-	panic("not implemented")
+	detail, ok := t.topics[name]
+	if !ok {
+		return &sarama.TopicError{Err: sarama.ErrUnknownTopicOrPartition}
+	}
+	if !validateOnly {
+		detail.ConfigEntries = entries
+		t.topics[name] = detail
+	}
+	return nil
 }
 
 func (t *testClient) CreateACL(resource sarama.Resource, acl sarama.Acl) error {
@@ -102,6 +215,7 @@ func (t *testClient) CreateACL(resource sarama.Resource, acl sarama.Acl) error {
 				return nil
 			}
 		}
+		t.acls = append(t.acls, sarama.ResourceAcls{Resource: resource, Acls: []*sarama.Acl{&acl}})
 	}
 	return nil
 }
@@ -130,7 +244,15 @@ func (t *testClient) ListConsumerGroupOffsets(group string, topicPartitions map[
 }
 
 func (t *testClient) DescribeCluster() (brokers []*sarama.Broker, controllerID int32, err error) {
-	panic("not implemented")
+	metadata := &sarama.MetadataResponse{}
+	for id := int32(1); id <= 3; id++ {
+		addr, ok := t.brokerAddrs[id]
+		if !ok {
+			addr = fmt.Sprintf("broker%d:9092", id)
+		}
+		metadata.AddBroker(addr, id)
+	}
+	return metadata.Brokers, 1, nil
 }
 
 func (t *testClient) Close() error {
@@ -138,3 +260,160 @@ func (t *testClient) Close() error {
 	t.acls = nil
 	return nil
 }
+
+// testEpoch is the timestamp of offset 0 in a testSaramaClient, every following offset is a second later
+var testEpoch = time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// testPartition holds the watermarks of a partition of a testSaramaClient
+type testPartition struct {
+	low, high int64
+}
+
+// testSaramaClient is a sarama.Client with offsets for the topics, other methods panic
+type testSaramaClient struct {
+	sarama.Client
+	topics map[string]map[int32]testPartition
+}
+
+// newTestSaramaClient creates a client with simpleTopic holding offsets 10 to 100 and
+// topicWithPartitions holding offsets 0 to 10, 5 to 5, 0 to 50 and 20 to 30
+func newTestSaramaClient() *testSaramaClient {
+	return &testSaramaClient{topics: map[string]map[int32]testPartition{
+		"simpleTopic":         {0: {10, 100}},
+		"topicWithPartitions": {0: {0, 10}, 1: {5, 5}, 2: {0, 50}, 3: {20, 30}},
+	}}
+}
+
+func (t *testSaramaClient) Config() *sarama.Config {
+	return sarama.NewConfig()
+}
+
+func (t *testSaramaClient) Partitions(topic string) ([]int32, error) {
+	partitions, ok := t.topics[topic]
+	if !ok {
+		return nil, sarama.ErrUnknownTopicOrPartition
+	}
+	ids := make([]int32, 0, len(partitions))
+	for id := range partitions {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids, nil
+}
+
+func (t *testSaramaClient) GetOffset(topic string, partitionID int32, timestamp int64) (int64, error) {
+	p, ok := t.topics[topic][partitionID]
+	if !ok {
+		return -1, sarama.ErrUnknownTopicOrPartition
+	}
+	switch timestamp {
+	case sarama.OffsetOldest:
+		return p.low, nil
+	case sarama.OffsetNewest:
+		return p.high, nil
+	}
+	for offset := p.low; offset < p.high; offset++ {
+		if testEpoch.Add(time.Duration(offset)*time.Second).UnixNano()/int64(time.Millisecond) >= timestamp {
+			return offset, nil
+		}
+	}
+	return -1, nil
+}
+
+// newAPIVersionsResponse returns a mocked ApiVersions response with the given max versions
+func newAPIVersionsResponse(versions map[int16]int16) sarama.MockResponse {
+	resp := &sarama.ApiVersionsResponse{}
+	for key, max := range versions {
+		resp.ApiVersions = append(resp.ApiVersions, &sarama.ApiVersionsResponseBlock{ApiKey: key, MaxVersion: max})
+	}
+	return sarama.NewMockWrapper(resp)
+}
+
+// newMockCluster starts a single mock broker which is also the controller
+func newMockCluster(t *testing.T) *sarama.MockBroker {
+	broker := sarama.NewMockBroker(t, 1)
+	broker.SetHandlerByMap(map[string]sarama.MockResponse{
+		"ApiVersionsRequest": newAPIVersionsResponse(map[int16]int16{1: 8, 18: 1, 19: 2, 32: 1, 37: 0}),
+		"MetadataRequest": sarama.NewMockMetadataResponse(t).
+			SetBroker(broker.Addr(), broker.BrokerID()).
+			SetController(broker.BrokerID()).
+			SetLeader("simpleTopic", 0, broker.BrokerID()),
+	})
+	return broker
+}
+
+func TestNewSaramaConfig(t *testing.T) {
+	testCases := []struct {
+		config   kafka.Config
+		clientID string
+		success  bool
+	}{
+		{kafka.Config{}, "kafkactl", true},
+		{kafka.Config{ClientID: "tool", DialTimeout: time.Second, MetadataRefresh: time.Minute, ConsumerErrors: true}, "tool", true},
+		{kafka.Config{SASLMechanism: "SCRAM-SHA-512"}, "", false},
+		{kafka.Config{TLSEnabled: true, TLSMinVersion: "0.9"}, "", false},
+	}
+	for _, tc := range testCases {
+		cfg, err := kafka.NewSaramaConfig(&tc.config)
+		if err != nil && tc.success {
+			t.Fatal(err)
+		}
+		if err == nil && !tc.success {
+			t.Fatal("Should return an error")
+		}
+		if !tc.success {
+			continue
+		}
+		if cfg.ClientID != tc.clientID {
+			t.Errorf("ClientID is %s, expected %s", cfg.ClientID, tc.clientID)
+		}
+		if tc.config.DialTimeout != 0 && cfg.Net.DialTimeout != tc.config.DialTimeout {
+			t.Errorf("DialTimeout is %s, expected %s", cfg.Net.DialTimeout, tc.config.DialTimeout)
+		}
+		if tc.config.MetadataRefresh != 0 && cfg.Metadata.RefreshFrequency != tc.config.MetadataRefresh {
+			t.Errorf("Metadata refresh is %s, expected %s", cfg.Metadata.RefreshFrequency, tc.config.MetadataRefresh)
+		}
+		if cfg.Consumer.Return.Errors != tc.config.ConsumerErrors {
+			t.Errorf("Consumer errors returned is %t, expected %t", cfg.Consumer.Return.Errors, tc.config.ConsumerErrors)
+		}
+	}
+}
+
+func TestNewConn(t *testing.T) {
+	broker := newMockCluster(t)
+	defer broker.Close()
+
+	c, err := kafka.NewConn(&kafka.Config{BrokerList: []string{broker.Addr()}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	brokers, err := c.GetBrokers()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(brokers) != 1 {
+		t.Errorf("Got %d brokers, expected 1", len(brokers))
+	}
+	partitions, err := c.Consumer.Partitions("simpleTopic")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(partitions) != 1 {
+		t.Errorf("Got %d partitions, expected 1", len(partitions))
+	}
+	if c.Version != sarama.V2_0_0_0 {
+		t.Errorf("Negotiated version %s, expected %s", c.Version, sarama.V2_0_0_0)
+	}
+	if c.NamingPolicy == nil || c.NamingPolicy.Check("invalid topic", nil) == nil {
+		t.Errorf("Expected the default naming policy to reject invalid names")
+	}
+	if err := c.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if !c.Client.Closed() {
+		t.Fatal("Client should be closed")
+	}
+	if err := c.Close(); err != nil {
+		t.Fatalf("Closing twice should not fail: %s", err)
+	}
+}
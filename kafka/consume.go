@@ -0,0 +1,375 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/izolight/kafkalib/format"
+	log "github.com/sirupsen/logrus"
+)
+
+// kinds of start positions
+const (
+	startNewest = iota
+	startOldest
+	startOffset
+	startFromEnd
+	startTime
+)
+
+// trailingMarkers is how many offsets before the high watermark UntilEnd expects to be transaction markers
+const trailingMarkers = 4
+
+// StartPosition is where consumption of each partition begins, the zero value starts at the newest offset
+type StartPosition struct {
+	kind   int
+	offset int64
+	time   time.Time
+}
+
+// StartAtOldest starts at the oldest offset still available
+func StartAtOldest() StartPosition {
+	return StartPosition{kind: startOldest}
+}
+
+// StartAtNewest only reads messages produced after consumption started
+func StartAtNewest() StartPosition {
+	return StartPosition{kind: startNewest}
+}
+
+// StartAtOffset starts at offset, it has to be between the watermarks of each consumed partition
+func StartAtOffset(offset int64) StartPosition {
+	return StartPosition{kind: startOffset, offset: offset}
+}
+
+// StartFromEnd starts n messages before the newest offset or at the oldest one if there are fewer,
+// n must not be negative
+func StartFromEnd(n int64) StartPosition {
+	return StartPosition{kind: startFromEnd, offset: n}
+}
+
+// StartAtTime starts at the first message with a timestamp at or after t
+func StartAtTime(t time.Time) StartPosition {
+	return StartPosition{kind: startTime, time: t}
+}
+
+// ConsumeOptions controls what Consume reads and when it stops
+type ConsumeOptions struct {
+	// Partitions limits consumption to these partitions, all partitions are read if it is empty
+	Partitions []int32
+	Start      StartPosition
+	// Limit stops after this many messages of all partitions, 0 means no limit
+	Limit int
+	// UntilEnd stops each partition at the newest offset it had when consumption started. Transaction
+	// markers aren't delivered, so a partition whose last messages are within a few offsets of that
+	// stops once no messages arrived for twice the fetch wait time of the client, any other partition
+	// after the read timeout.
+	UntilEnd bool
+	// Until stops each partition at the first message with a timestamp after it
+	Until time.Time
+}
+
+// MessageStream delivers consumed messages until all partitions are done or it is closed
+type MessageStream struct {
+	// Messages is closed when consumption ended, Err tells why
+	Messages <-chan format.Message
+	cancel   context.CancelFunc
+	done     chan struct{}
+	mu       sync.Mutex
+	err      error
+}
+
+// Close stops consumption and waits until the partitions are closed, it returns the same as Err
+func (s *MessageStream) Close() error {
+	s.cancel()
+	<-s.done
+	return s.Err()
+}
+
+// Err returns the error that ended consumption, it is nil if it ended because of the options or Close
+func (s *MessageStream) Err() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.err
+}
+
+func (s *MessageStream) setErr(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err == nil {
+		s.err = err
+	}
+}
+
+// Consume reads messages of a topic with the Consumer of the connection and streams them in the order
+// of each partition. Without Limit, UntilEnd or Until it runs until the stream is closed.
+func (c Conn) Consume(topic string, options ConsumeOptions) (*MessageStream, error) {
+	return c.ConsumeContext(context.Background(), topic, options)
+}
+
+// ConsumeContext is Consume with a context, consumption also ends when ctx is done
+func (c Conn) ConsumeContext(ctx context.Context, topic string, options ConsumeOptions) (*MessageStream, error) {
+	const op = "consuming topic"
+	if options.Limit < 0 {
+		return nil, &Error{Op: op, Resource: topic, Kind: ErrInvalidConfig, Err: fmt.Errorf("limit must not be negative")}
+	}
+	if options.Start.kind == startFromEnd && options.Start.offset < 0 {
+		return nil, &Error{Op: op, Resource: topic, Kind: ErrInvalidConfig,
+			Err: fmt.Errorf("number of messages from the end must not be negative")}
+	}
+	type partitionStart struct {
+		partition int32
+		offset    int64
+		high      int64
+	}
+	var starts []partitionStart
+	err := call(ctx, op, topic, func() error {
+		partitions, err := c.consumePartitions(topic, options.Partitions)
+		if err != nil {
+			return err
+		}
+		for _, p := range partitions {
+			offset, high, err := c.startOffset(topic, p, options.Start)
+			if err != nil {
+				return err
+			}
+			starts = append(starts, partitionStart{p, offset, high})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, newError(op, topic, err)
+	}
+
+	consumers := make([]sarama.PartitionConsumer, 0, len(starts))
+	// consumed holds the start of each consumer
+	consumed := make([]partitionStart, 0, len(starts))
+	for _, s := range starts {
+		if options.UntilEnd && s.offset >= s.high {
+			continue
+		}
+		pc, err := c.Consumer.ConsumePartition(topic, s.partition, s.offset)
+		if err != nil {
+			for _, pc := range consumers {
+				pc.Close()
+			}
+			return nil, newError(op, fmt.Sprintf("%s-%d", topic, s.partition), err)
+		}
+		consumers = append(consumers, pc)
+		consumed = append(consumed, s)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	messages := make(chan format.Message)
+	stream := &MessageStream{Messages: messages, cancel: cancel, done: make(chan struct{})}
+	var sent sync.Mutex
+	count := 0
+	// send delivers a message unless the limit is reached or ctx is done and reports if consumption goes on
+	send := func(m *sarama.ConsumerMessage) bool {
+		sent.Lock()
+		defer sent.Unlock()
+		if options.Limit > 0 && count >= options.Limit {
+			return false
+		}
+		select {
+		case messages <- format.FromConsumerMessage(m):
+		case <-ctx.Done():
+			return false
+		}
+		count++
+		if options.Limit > 0 && count >= options.Limit {
+			cancel()
+			return false
+		}
+		return true
+	}
+
+	var wg sync.WaitGroup
+	cfg := c.saramaConfig()
+	for i, pc := range consumers {
+		wg.Add(1)
+		go func(pc sarama.PartitionConsumer, s partitionStart) {
+			defer wg.Done()
+			resource := fmt.Sprintf("%s-%d", topic, s.partition)
+			// next is the offset after the last delivered message. The offsets of transaction markers are
+			// skipped without a message, so the partition is done once nothing arrived for a while: for two
+			// fetch wait times when only markers can be left, for the read timeout before that so a slow
+			// fetch doesn't end it early.
+			next := s.offset
+			window := func() time.Duration {
+				if s.high-next <= trailingMarkers {
+					return 2 * cfg.Consumer.MaxWaitTime
+				}
+				return cfg.Net.ReadTimeout
+			}
+			var idle *time.Timer
+			var idleC <-chan time.Time
+			if options.UntilEnd {
+				idle = time.NewTimer(window())
+				defer idle.Stop()
+				idleC = idle.C
+			}
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case <-idleC:
+					if pc.HighWaterMarkOffset() >= s.high {
+						return
+					}
+					idle.Reset(window())
+				case m, ok := <-pc.Messages():
+					if !ok {
+						// sarama stopped the partition on its own
+						if ctx.Err() == nil {
+							stream.setErr(newError(op, resource, stopError(pc)))
+							cancel()
+						}
+						return
+					}
+					if options.UntilEnd && m.Offset >= s.high {
+						return
+					}
+					if !options.Until.IsZero() && m.Timestamp.After(options.Until) {
+						return
+					}
+					if !send(m) {
+						return
+					}
+					if options.UntilEnd && m.Offset >= s.high-1 {
+						return
+					}
+					next = m.Offset + 1
+					if idle != nil {
+						if !idle.Stop() {
+							<-idle.C
+						}
+						idle.Reset(window())
+					}
+				case err, ok := <-pc.Errors():
+					if !ok {
+						return
+					}
+					if !terminal(err) {
+						continue
+					}
+					stream.setErr(newError(op, resource, err.Err))
+					cancel()
+					return
+				}
+			}
+		}(pc, consumed[i])
+	}
+	go func() {
+		wg.Wait()
+		for _, pc := range consumers {
+			if err := closeError(pc); err != nil {
+				stream.setErr(newError(op, topic, err))
+			}
+		}
+		cancel()
+		close(messages)
+		close(stream.done)
+	}()
+	return stream, nil
+}
+
+// terminal reports if sarama stopped the partition because of err and logs it otherwise, sarama
+// retries on all errors but ErrOffsetOutOfRange by itself
+func terminal(err *sarama.ConsumerError) bool {
+	if err.Err == sarama.ErrOffsetOutOfRange {
+		return true
+	}
+	log.Warnf("Error consuming %s-%d, retrying: %s", err.Topic, err.Partition, err.Err)
+	return false
+}
+
+// closeError closes pc and returns the errors it still held without those sarama retried on
+func closeError(pc sarama.PartitionConsumer) error {
+	err := pc.Close()
+	errs, ok := err.(sarama.ConsumerErrors)
+	if !ok {
+		return err
+	}
+	for _, cErr := range errs {
+		if terminal(cErr) {
+			return cErr.Err
+		}
+	}
+	return nil
+}
+
+// stopError returns why sarama stopped the partition consumer pc, the cause is only known with
+// Config.ConsumerErrors
+func stopError(pc sarama.PartitionConsumer) error {
+	err := errors.New("partition consumer stopped")
+	for cErr := range pc.Errors() {
+		err = cErr.Err
+	}
+	return err
+}
+
+// consumePartitions returns the sorted partitions to consume and checks that the selected ones exist
+func (c Conn) consumePartitions(topic string, selected []int32) ([]int32, error) {
+	partitions, err := c.Client.Partitions(topic)
+	if err != nil {
+		return nil, err
+	}
+	if len(selected) != 0 {
+	next:
+		for _, p := range selected {
+			for _, existing := range partitions {
+				if existing == p {
+					continue next
+				}
+			}
+			return nil, &Error{Op: "consuming topic", Resource: topic, Kind: ErrInvalidConfig, Err: fmt.Errorf("partition %d doesn't exist", p)}
+		}
+		partitions = selected
+	}
+	// the client returns its cached slice, sort a copy
+	partitions = append([]int32{}, partitions...)
+	sort.Slice(partitions, func(i, j int) bool { return partitions[i] < partitions[j] })
+	return partitions, nil
+}
+
+// startOffset resolves the start position to an absolute offset of the partition and returns it
+// with the high watermark
+func (c Conn) startOffset(topic string, partition int32, start StartPosition) (offset, high int64, err error) {
+	low, high, err := c.watermarks(topic, partition)
+	if err != nil {
+		return 0, 0, err
+	}
+	switch start.kind {
+	case startOldest:
+		return low, high, nil
+	case startOffset:
+		if start.offset < low || start.offset > high {
+			return 0, 0, &Error{Op: "consuming topic", Resource: topic, Kind: ErrInvalidConfig,
+				Err: fmt.Errorf("offset %d is outside of %d to %d of partition %d", start.offset, low, high, partition)}
+		}
+		return start.offset, high, nil
+	case startFromEnd:
+		offset = high - start.offset
+		if offset < low {
+			offset = low
+		}
+		return offset, high, nil
+	case startTime:
+		offset, err := c.Client.GetOffset(topic, partition, start.time.UnixNano()/int64(time.Millisecond))
+		if err != nil {
+			return 0, 0, err
+		}
+		if offset < 0 {
+			// all messages are older
+			offset = high
+		}
+		return offset, high, nil
+	}
+	return high, high, nil
+}
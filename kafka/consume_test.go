@@ -0,0 +1,227 @@
+package kafka_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/Shopify/sarama/mocks"
+	"github.com/izolight/kafkalib/format"
+	"github.com/izolight/kafkalib/kafka"
+)
+
+func collect(t *testing.T, stream *kafka.MessageStream) []format.Message {
+	var messages []format.Message
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case m, ok := <-stream.Messages:
+			if !ok {
+				return messages
+			}
+			messages = append(messages, m)
+		case <-timeout:
+			t.Fatal("Timed out waiting for the stream to end")
+		}
+	}
+}
+
+func TestConsume_StartPosition(t *testing.T) {
+	testCases := []struct {
+		name    string
+		start   kafka.StartPosition
+		offset  int64
+		success bool
+	}{
+		{"oldest", kafka.StartAtOldest(), 10, true},
+		{"newest", kafka.StartAtNewest(), 100, true},
+		{"zero value", kafka.StartPosition{}, 100, true},
+		{"offset", kafka.StartAtOffset(42), 42, true},
+		{"offset before low watermark", kafka.StartAtOffset(5), 0, false},
+		{"offset after high watermark", kafka.StartAtOffset(101), 0, false},
+		{"from end", kafka.StartFromEnd(5), 95, true},
+		{"from end before low watermark", kafka.StartFromEnd(500), 10, true},
+		{"negative from end", kafka.StartFromEnd(-1), 0, false},
+		{"time", kafka.StartAtTime(testEpoch.Add(42 * time.Second)), 42, true},
+		{"time after all messages", kafka.StartAtTime(testEpoch.Add(time.Hour)), 100, true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			consumer := mocks.NewConsumer(t, nil)
+			if tc.success {
+				consumer.ExpectConsumePartition("simpleTopic", 0, tc.offset)
+			}
+			c := kafka.Conn{Client: newTestSaramaClient(), Consumer: consumer}
+			stream, err := c.Consume("simpleTopic", kafka.ConsumeOptions{Start: tc.start})
+			if !tc.success {
+				if !errors.Is(err, kafka.ErrInvalidConfig) {
+					t.Errorf("Expected ErrInvalidConfig but got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := stream.Close(); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestConsume_Stop(t *testing.T) {
+	until := testEpoch.Add(time.Minute)
+	testCases := []struct {
+		name     string
+		options  kafka.ConsumeOptions
+		messages int
+	}{
+		{"limit", kafka.ConsumeOptions{Start: kafka.StartAtOldest(), Limit: 3}, 3},
+		// the mock numbers the offsets from 1, so offset 9 is the last one before the high watermark 10
+		{"until end", kafka.ConsumeOptions{Start: kafka.StartAtOldest(), UntilEnd: true}, 9},
+		{"until time", kafka.ConsumeOptions{Start: kafka.StartAtOldest(), Until: until}, 4},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			consumer := mocks.NewConsumer(t, nil)
+			pc := consumer.ExpectConsumePartition("topicWithPartitions", 0, 0)
+			for i := 0; i < 12; i++ {
+				timestamp := testEpoch.Add(time.Duration(i) * 20 * time.Second)
+				pc.YieldMessage(&sarama.ConsumerMessage{Key: []byte("key"), Value: []byte("value"), Timestamp: timestamp})
+			}
+			c := kafka.Conn{Client: newTestSaramaClient(), Consumer: consumer}
+			// partition 1 is empty, so it isn't consumed until the end
+			tc.options.Partitions = []int32{0, 1}
+			if !tc.options.UntilEnd {
+				tc.options.Partitions = []int32{0}
+			}
+			stream, err := c.Consume("topicWithPartitions", tc.options)
+			if err != nil {
+				t.Fatal(err)
+			}
+			messages := collect(t, stream)
+			if len(messages) != tc.messages {
+				t.Errorf("Expected %d messages but got %d", tc.messages, len(messages))
+			}
+			for i, m := range messages {
+				if m.Offset != int64(i+1) || m.Key != "key" || m.Value != "value" {
+					t.Errorf("Unexpected message %v", m)
+				}
+			}
+			if err := stream.Err(); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+// markerConsumer reports the high watermark high for its partition consumers, like a partition whose
+// last offsets hold transaction markers that are never delivered
+type markerConsumer struct {
+	sarama.Consumer
+	high int64
+}
+
+func (m markerConsumer) ConsumePartition(topic string, partition int32, offset int64) (sarama.PartitionConsumer, error) {
+	pc, err := m.Consumer.ConsumePartition(topic, partition, offset)
+	return markerPartitionConsumer{pc, m.high}, err
+}
+
+type markerPartitionConsumer struct {
+	sarama.PartitionConsumer
+	high int64
+}
+
+func (m markerPartitionConsumer) HighWaterMarkOffset() int64 {
+	return m.high
+}
+
+func TestConsume_UntilEndTransactional(t *testing.T) {
+	consumer := mocks.NewConsumer(t, nil)
+	pc := consumer.ExpectConsumePartition("topicWithPartitions", 0, 0)
+	// offset 9 before the high watermark 10 is a commit marker
+	for i := 0; i < 8; i++ {
+		pc.YieldMessage(&sarama.ConsumerMessage{Key: []byte("key"), Value: []byte("value"), Timestamp: testEpoch})
+	}
+	c := kafka.Conn{Client: newTestSaramaClient(), Consumer: markerConsumer{consumer, 10}}
+	stream, err := c.Consume("topicWithPartitions", kafka.ConsumeOptions{Partitions: []int32{0}, Start: kafka.StartAtOldest(), UntilEnd: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if messages := collect(t, stream); len(messages) != 8 {
+		t.Errorf("Expected 8 messages but got %d", len(messages))
+	}
+	if err := stream.Err(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestConsume_UntilEndSlowFetch(t *testing.T) {
+	consumer := mocks.NewConsumer(t, nil)
+	pc := consumer.ExpectConsumePartition("topicWithPartitions", 0, 0)
+	for i := 0; i < 3; i++ {
+		pc.YieldMessage(&sarama.ConsumerMessage{Key: []byte("key"), Value: []byte("value"), Timestamp: testEpoch})
+	}
+	c := kafka.Conn{Client: newTestSaramaClient(), Consumer: markerConsumer{consumer, 10}}
+	stream, err := c.Consume("topicWithPartitions", kafka.ConsumeOptions{Partitions: []int32{0}, Start: kafka.StartAtOldest(), UntilEnd: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		// the next fetch takes longer than two fetch wait times
+		time.Sleep(3 * sarama.NewConfig().Consumer.MaxWaitTime)
+		for i := 0; i < 6; i++ {
+			pc.YieldMessage(&sarama.ConsumerMessage{Key: []byte("key"), Value: []byte("value"), Timestamp: testEpoch})
+		}
+	}()
+	if messages := collect(t, stream); len(messages) != 9 {
+		t.Errorf("Expected 9 messages but got %d", len(messages))
+	}
+	if err := stream.Err(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestConsume_Error(t *testing.T) {
+	testCases := []struct {
+		name     string
+		err      error
+		messages int
+	}{
+		{"terminal", sarama.ErrOffsetOutOfRange, 0},
+		{"retried by sarama", sarama.ErrNotLeaderForPartition, 1},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			consumer := mocks.NewConsumer(t, nil)
+			pc := consumer.ExpectConsumePartition("simpleTopic", 0, 100)
+			pc.YieldError(tc.err)
+			if tc.messages > 0 {
+				pc.YieldMessage(&sarama.ConsumerMessage{Key: []byte("key"), Value: []byte("value"), Timestamp: testEpoch})
+			}
+			c := kafka.Conn{Client: newTestSaramaClient(), Consumer: consumer}
+			stream, err := c.Consume("simpleTopic", kafka.ConsumeOptions{Limit: 1})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if messages := collect(t, stream); len(messages) != tc.messages {
+				t.Errorf("Expected %d messages but got %d", tc.messages, len(messages))
+			}
+			if tc.messages == 0 && !errors.Is(stream.Err(), tc.err) {
+				t.Errorf("Expected %v but got %v", tc.err, stream.Err())
+			}
+			if tc.messages > 0 && stream.Err() != nil {
+				t.Errorf("Expected no error but got %v", stream.Err())
+			}
+		})
+	}
+}
+
+func TestConsume_UnknownPartition(t *testing.T) {
+	c := kafka.Conn{Client: newTestSaramaClient(), Consumer: mocks.NewConsumer(t, nil)}
+	_, err := c.Consume("simpleTopic", kafka.ConsumeOptions{Partitions: []int32{3}})
+	if !errors.Is(err, kafka.ErrInvalidConfig) {
+		t.Errorf("Expected ErrInvalidConfig but got %v", err)
+	}
+}
@@ -1,7 +1,7 @@
 package kafka
 
 import (
-	"fmt"
+	"context"
 	"github.com/Shopify/sarama"
 	"github.com/izolight/kafkalib/format"
 )
@@ -19,9 +19,19 @@ func (c Conn) GetConsumerGroup() (format.Topics, error) {
 
 // GetAllConsumerGroups returns all Consumer Groups
 func (c Conn) GetAllConsumerGroups() (format.ConsumerGroups, error) {
-	groups, err := c.AdminClient.ListConsumerGroups()
+	return c.GetAllConsumerGroupsContext(context.Background())
+}
+
+// GetAllConsumerGroupsContext is GetAllConsumerGroups with a context
+func (c Conn) GetAllConsumerGroupsContext(ctx context.Context) (format.ConsumerGroups, error) {
+	var groups map[string]string
+	err := call(ctx, "getting consumer groups", "", func() error {
+		var err error
+		groups, err = c.admin(ctx).ListConsumerGroups()
+		return err
+	})
 	if err != nil {
-		return nil, fmt.Errorf("Error getting all Consumergroups: %s", err)
+		return nil, newError("getting consumer groups", "", err)
 	}
 	return groups, nil
 }
@@ -0,0 +1,318 @@
+package kafka
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ghodss/yaml"
+)
+
+// environment variables used by the contexts file
+const (
+	// ContextsFileEnv overrides the default location of the contexts file
+	ContextsFileEnv = "KAFKA_CONTEXTS"
+	// ContextEnv selects the context instead of current-context
+	ContextEnv = "KAFKA_CONTEXT"
+)
+
+// Contexts is a kubeconfig-style file holding named clusters, credentials and the contexts combining them
+type Contexts struct {
+	CurrentContext string             `json:"current-context"`
+	Clusters       []NamedCluster     `json:"clusters"`
+	Credentials    []NamedCredentials `json:"credentials"`
+	Contexts       []NamedContext     `json:"contexts"`
+
+	// path is the file the contexts were loaded from
+	path string
+}
+
+// NamedCluster holds the connection settings of a cluster
+type NamedCluster struct {
+	Name        string     `json:"name"`
+	Brokers     []string   `json:"brokers"`
+	Version     string     `json:"version,omitempty"`
+	TLS         ClusterTLS `json:"tls,omitempty"`
+	ClientID    string     `json:"client-id,omitempty"`
+	DialTimeout string     `json:"dial-timeout,omitempty"`
+}
+
+// ClusterTLS holds the tls settings of a cluster
+type ClusterTLS struct {
+	Enabled            bool   `json:"enabled,omitempty"`
+	InsecureSkipVerify bool   `json:"insecure-skip-verify,omitempty"`
+	CAFile             string `json:"ca-file,omitempty"`
+	ServerName         string `json:"server-name,omitempty"`
+	MinVersion         string `json:"min-version,omitempty"`
+}
+
+// NamedCredentials holds the authentication settings for a cluster
+type NamedCredentials struct {
+	Name              string   `json:"name"`
+	SASLMechanism     string   `json:"sasl-mechanism,omitempty"`
+	User              string   `json:"user,omitempty"`
+	Password          string   `json:"password,omitempty"`
+	CertFile          string   `json:"cert-file,omitempty"`
+	KeyFile           string   `json:"key-file,omitempty"`
+	KeyPassword       string   `json:"key-password,omitempty"`
+	OAuthTokenURL     string   `json:"oauth-token-url,omitempty"`
+	OAuthClientID     string   `json:"oauth-client-id,omitempty"`
+	OAuthClientSecret string   `json:"oauth-client-secret,omitempty"`
+	OAuthScopes       []string `json:"oauth-scopes,omitempty"`
+}
+
+// NamedContext combines a cluster with credentials
+type NamedContext struct {
+	Name        string `json:"name"`
+	Cluster     string `json:"cluster"`
+	Credentials string `json:"credentials,omitempty"`
+}
+
+// ConfigOrigin records where the values of a resolved Config came from
+type ConfigOrigin struct {
+	Context string
+	File    string
+	// Fields maps Config field names to their source, either the file or an environment variable
+	Fields map[string]string
+}
+
+// Source returns the origin of a Config field or an empty string if it was not set
+func (o ConfigOrigin) Source(field string) string {
+	return o.Fields[field]
+}
+
+func (o *ConfigOrigin) set(field, source string) {
+	o.Fields[field] = source
+}
+
+// DefaultContextsPath returns the path of the contexts file, $KAFKA_CONTEXTS or ~/.kafka/contexts.yml
+func DefaultContextsPath() string {
+	if path := os.Getenv(ContextsFileEnv); len(path) != 0 {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return filepath.Join(".kafka", "contexts.yml")
+	}
+	return filepath.Join(home, ".kafka", "contexts.yml")
+}
+
+// LoadContexts reads and validates a contexts file
+func LoadContexts(path string) (*Contexts, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("Error reading contexts file: %s", err)
+	}
+	contexts := &Contexts{}
+	if err := yaml.Unmarshal(b, contexts); err != nil {
+		return nil, fmt.Errorf("Error parsing contexts file %s: %s", path, err)
+	}
+	contexts.path = path
+	if err := contexts.Validate(); err != nil {
+		return nil, err
+	}
+	return contexts, nil
+}
+
+// Validate checks that names are unique and all references can be resolved
+func (c *Contexts) Validate() error {
+	clusters := make(map[string]bool)
+	for i, cl := range c.Clusters {
+		if len(cl.Name) == 0 {
+			return c.errorf("cluster %d has no name", i)
+		}
+		if clusters[cl.Name] {
+			return c.errorf("cluster %s is defined more than once", cl.Name)
+		}
+		if len(cl.Brokers) == 0 {
+			return c.errorf("cluster %s has no brokers", cl.Name)
+		}
+		if _, _, err := parseVersion(cl.Version); err != nil {
+			return c.errorf("cluster %s: %s", cl.Name, err)
+		}
+		if len(cl.DialTimeout) != 0 {
+			if _, err := time.ParseDuration(cl.DialTimeout); err != nil {
+				return c.errorf("cluster %s: %s", cl.Name, err)
+			}
+		}
+		clusters[cl.Name] = true
+	}
+	credentials := make(map[string]bool)
+	for i, cr := range c.Credentials {
+		if len(cr.Name) == 0 {
+			return c.errorf("credentials %d have no name", i)
+		}
+		if credentials[cr.Name] {
+			return c.errorf("credentials %s are defined more than once", cr.Name)
+		}
+		credentials[cr.Name] = true
+	}
+	contexts := make(map[string]bool)
+	for i, ctx := range c.Contexts {
+		if len(ctx.Name) == 0 {
+			return c.errorf("context %d has no name", i)
+		}
+		if contexts[ctx.Name] {
+			return c.errorf("context %s is defined more than once", ctx.Name)
+		}
+		if !clusters[ctx.Cluster] {
+			return c.errorf("context %s references unknown cluster %q", ctx.Name, ctx.Cluster)
+		}
+		if len(ctx.Credentials) != 0 && !credentials[ctx.Credentials] {
+			return c.errorf("context %s references unknown credentials %q", ctx.Name, ctx.Credentials)
+		}
+		contexts[ctx.Name] = true
+	}
+	if len(c.CurrentContext) != 0 && !contexts[c.CurrentContext] {
+		return c.errorf("current-context references unknown context %q", c.CurrentContext)
+	}
+	return nil
+}
+
+func (c *Contexts) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("Invalid contexts file %s: %s", c.path, fmt.Sprintf(format, args...))
+}
+
+// Resolve builds the Config for the named context. If name is empty $KAFKA_CONTEXT or
+// current-context is used. Environment variables override the values from the file.
+func (c *Contexts) Resolve(name string) (*Config, *ConfigOrigin, error) {
+	if len(name) == 0 {
+		name = os.Getenv(ContextEnv)
+	}
+	if len(name) == 0 {
+		name = c.CurrentContext
+	}
+	if len(name) == 0 {
+		return nil, nil, fmt.Errorf("No context selected and no current-context set in %s", c.path)
+	}
+
+	var ctx *NamedContext
+	for i := range c.Contexts {
+		if c.Contexts[i].Name == name {
+			ctx = &c.Contexts[i]
+		}
+	}
+	if ctx == nil {
+		return nil, nil, fmt.Errorf("Context %s not found in %s", name, c.path)
+	}
+
+	config := &Config{}
+	origin := &ConfigOrigin{Context: name, File: c.path, Fields: make(map[string]string)}
+	source := "file:" + c.path
+	setString := func(field string, dst *string, value string) {
+		if len(value) != 0 {
+			*dst = value
+			origin.set(field, source)
+		}
+	}
+
+	for _, cl := range c.Clusters {
+		if cl.Name != ctx.Cluster {
+			continue
+		}
+		config.BrokerList = cl.Brokers
+		origin.set("BrokerList", source)
+		setString("Version", &config.Version, cl.Version)
+		setString("ClientID", &config.ClientID, cl.ClientID)
+		if len(cl.DialTimeout) != 0 {
+			config.DialTimeout, _ = time.ParseDuration(cl.DialTimeout)
+			origin.set("DialTimeout", source)
+		}
+		if cl.TLS.Enabled {
+			config.TLSEnabled = true
+			origin.set("TLSEnabled", source)
+		}
+		if cl.TLS.InsecureSkipVerify {
+			config.TLSInsecureSkipVerify = true
+			origin.set("TLSInsecureSkipVerify", source)
+		}
+		setString("TLSCAFile", &config.TLSCAFile, cl.TLS.CAFile)
+		setString("TLSServerName", &config.TLSServerName, cl.TLS.ServerName)
+		setString("TLSMinVersion", &config.TLSMinVersion, cl.TLS.MinVersion)
+	}
+
+	for _, cr := range c.Credentials {
+		if cr.Name != ctx.Credentials {
+			continue
+		}
+		setString("SASLMechanism", &config.SASLMechanism, cr.SASLMechanism)
+		setString("User", &config.User, cr.User)
+		setString("Password", &config.Password, cr.Password)
+		setString("TLSCertFile", &config.TLSCertFile, cr.CertFile)
+		setString("TLSKeyFile", &config.TLSKeyFile, cr.KeyFile)
+		setString("TLSKeyPassword", &config.TLSKeyPassword, cr.KeyPassword)
+		setString("OAuthTokenURL", &config.OAuthTokenURL, cr.OAuthTokenURL)
+		setString("OAuthClientID", &config.OAuthClientID, cr.OAuthClientID)
+		setString("OAuthClientSecret", &config.OAuthClientSecret, cr.OAuthClientSecret)
+		if len(cr.OAuthScopes) != 0 {
+			config.OAuthScopes = cr.OAuthScopes
+			origin.set("OAuthScopes", source)
+		}
+	}
+
+	if err := applyEnvOverrides(config, origin); err != nil {
+		return nil, nil, err
+	}
+	return config, origin, nil
+}
+
+// envOverride maps an environment variable to a Config field
+type envOverride struct {
+	env   string
+	field string
+	apply func(config *Config, value string) error
+}
+
+var envOverrides = []envOverride{
+	{"KAFKA_BROKERS", "BrokerList", func(c *Config, v string) error {
+		c.BrokerList = strings.Split(v, ",")
+		return nil
+	}},
+	{"KAFKA_VERSION", "Version", func(c *Config, v string) error {
+		c.Version = v
+		return nil
+	}},
+	{"KAFKA_CLIENT_ID", "ClientID", func(c *Config, v string) error {
+		c.ClientID = v
+		return nil
+	}},
+	{"KAFKA_TLS_ENABLED", "TLSEnabled", func(c *Config, v string) (err error) {
+		c.TLSEnabled, err = strconv.ParseBool(v)
+		return err
+	}},
+	{"KAFKA_TLS_CA_FILE", "TLSCAFile", func(c *Config, v string) error {
+		c.TLSCAFile = v
+		return nil
+	}},
+	{"KAFKA_SASL_MECHANISM", "SASLMechanism", func(c *Config, v string) error {
+		c.SASLMechanism = v
+		return nil
+	}},
+	{"KAFKA_USER", "User", func(c *Config, v string) error {
+		c.User = v
+		return nil
+	}},
+	{"KAFKA_PASSWORD", "Password", func(c *Config, v string) error {
+		c.Password = v
+		return nil
+	}},
+}
+
+// applyEnvOverrides sets the Config fields for all set environment variables
+func applyEnvOverrides(config *Config, origin *ConfigOrigin) error {
+	for _, o := range envOverrides {
+		value, ok := os.LookupEnv(o.env)
+		if !ok {
+			continue
+		}
+		if err := o.apply(config, value); err != nil {
+			return fmt.Errorf("Invalid value for %s: %s", o.env, err)
+		}
+		origin.set(o.field, "env:"+o.env)
+	}
+	return nil
+}
@@ -0,0 +1,153 @@
+package kafka_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/izolight/kafkalib/kafka"
+)
+
+const testContexts = `
+current-context: dev
+clusters:
+- name: dev
+  brokers: [localhost:9092]
+- name: prod
+  brokers: [kafka-1:9093, kafka-2:9093]
+  version: 2.1.0
+  tls:
+    enabled: true
+    ca-file: /etc/kafka/ca.pem
+credentials:
+- name: prod-admin
+  sasl-mechanism: SCRAM-SHA-512
+  user: admin
+  password: secret
+contexts:
+- name: dev
+  cluster: dev
+- name: prod
+  cluster: prod
+  credentials: prod-admin
+`
+
+func writeTestFile(t *testing.T, name, content string) (string, func()) {
+	dir, err := ioutil.TempDir("", "kafkalib")
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, name)
+	if err := ioutil.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path, func() { os.RemoveAll(dir) }
+}
+
+func TestContexts_Resolve(t *testing.T) {
+	path, cleanup := writeTestFile(t, "contexts.yml", testContexts)
+	defer cleanup()
+	contexts, err := kafka.LoadContexts(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		name     string
+		env      map[string]string
+		expected kafka.Config
+		sources  map[string]string
+		success  bool
+	}{
+		{
+			"",
+			nil,
+			kafka.Config{BrokerList: []string{"localhost:9092"}},
+			map[string]string{"BrokerList": "file:" + path},
+			true,
+		},
+		{
+			"prod",
+			map[string]string{"KAFKA_PASSWORD": "fromenv"},
+			kafka.Config{
+				BrokerList:    []string{"kafka-1:9093", "kafka-2:9093"},
+				Version:       "2.1.0",
+				TLSEnabled:    true,
+				TLSCAFile:     "/etc/kafka/ca.pem",
+				SASLMechanism: "SCRAM-SHA-512",
+				User:          "admin",
+				Password:      "fromenv",
+			},
+			map[string]string{"User": "file:" + path, "Password": "env:KAFKA_PASSWORD"},
+			true,
+		},
+		{
+			"",
+			map[string]string{"KAFKA_CONTEXT": "prod", "KAFKA_BROKERS": "a:1,b:2"},
+			kafka.Config{
+				BrokerList:    []string{"a:1", "b:2"},
+				Version:       "2.1.0",
+				TLSEnabled:    true,
+				TLSCAFile:     "/etc/kafka/ca.pem",
+				SASLMechanism: "SCRAM-SHA-512",
+				User:          "admin",
+				Password:      "secret",
+			},
+			map[string]string{"BrokerList": "env:KAFKA_BROKERS"},
+			true,
+		},
+		{"staging", nil, kafka.Config{}, nil, false},
+		{"dev", map[string]string{"KAFKA_TLS_ENABLED": "maybe"}, kafka.Config{}, nil, false},
+	}
+	for _, tc := range testCases {
+		for k, v := range tc.env {
+			os.Setenv(k, v)
+		}
+		config, origin, err := contexts.Resolve(tc.name)
+		for k := range tc.env {
+			os.Unsetenv(k)
+		}
+		if err != nil && tc.success {
+			t.Fatal(err)
+		}
+		if err == nil && !tc.success {
+			t.Fatalf("Resolving context %s should return an error", tc.name)
+		}
+		if !tc.success {
+			continue
+		}
+		if !reflect.DeepEqual(*config, tc.expected) {
+			t.Errorf("contexts.Resolve(%s):\nGot:\t%+v\nWant:\t%+v", tc.name, *config, tc.expected)
+		}
+		if origin.File != path {
+			t.Errorf("Origin file is %s, expected %s", origin.File, path)
+		}
+		for field, source := range tc.sources {
+			if origin.Source(field) != source {
+				t.Errorf("Source of %s is %s, expected %s", field, origin.Source(field), source)
+			}
+		}
+	}
+}
+
+func TestLoadContexts_Invalid(t *testing.T) {
+	testCases := []string{
+		"clusters: [{name: dev}]",
+		"clusters: [{name: dev, brokers: [a:1]}, {name: dev, brokers: [b:1]}]",
+		"clusters: [{name: dev, brokers: [a:1], version: latest}]",
+		"clusters: [{name: dev, brokers: [a:1]}]\ncontexts: [{name: dev, cluster: prod}]",
+		"clusters: [{name: dev, brokers: [a:1]}]\ncontexts: [{name: dev, cluster: dev, credentials: admin}]",
+		"clusters: [{name: dev, brokers: [a:1]}]\ncurrent-context: dev",
+		"clusters: {name: dev}",
+	}
+	for _, tc := range testCases {
+		path, cleanup := writeTestFile(t, "contexts.yml", tc)
+		_, err := kafka.LoadContexts(path)
+		cleanup()
+		if err == nil {
+			t.Errorf("Loading %q should return an error", tc)
+		}
+	}
+}
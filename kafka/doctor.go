@@ -0,0 +1,272 @@
+package kafka
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/izolight/kafkalib/format"
+	log "github.com/sirupsen/logrus"
+)
+
+// names of the diagnostic checks
+const (
+	checkDNS      = "dns"
+	checkTCP      = "tcp"
+	checkTLS      = "tls"
+	checkSASL     = "sasl"
+	checkVersion  = "version"
+	checkMetadata = "metadata"
+)
+
+// certificateExpiryWarning is how long before expiry a broker certificate is reported as warning
+const certificateExpiryWarning = 30 * 24 * time.Hour
+
+// Diagnose checks the connection to each bootstrap broker of config step by step: dns lookup,
+// tcp connect, tls handshake and certificate chain, sasl authentication, api versions and metadata.
+// Afterwards the listeners advertised in the metadata are checked for reachability, as clients
+// connect to those after bootstrapping. A check is skipped if a previous one for the same broker failed.
+// An error is only returned if config is invalid, connection problems are part of the report.
+func Diagnose(ctx context.Context, config *Config) (*format.Diagnosis, error) {
+	cfg, err := NewSaramaConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	explicit, hasVersion, _ := parseVersion(config.Version)
+
+	diagnosis := &format.Diagnosis{}
+	advertised := map[string]int32{}
+	for _, addr := range config.BrokerList {
+		d, brokers := diagnoseBootstrap(ctx, addr, cfg, explicit, hasVersion)
+		diagnosis.Brokers = append(diagnosis.Brokers, d)
+		for _, b := range brokers {
+			advertised[b.Addr()] = b.ID()
+		}
+	}
+
+	addrs := make([]string, 0, len(advertised))
+	for addr := range advertised {
+		addrs = append(addrs, addr)
+	}
+	sort.Slice(addrs, func(i, j int) bool {
+		return advertised[addrs[i]] < advertised[addrs[j]]
+	})
+	for _, addr := range addrs {
+		r := newCheckRunner(ctx, addr)
+		host := r.dns(addr)
+		r.tcp(addr, cfg)
+		r.tls(addr, host, cfg)
+		diagnosis.Brokers = append(diagnosis.Brokers, format.BrokerDiagnosis{
+			Address:    addr,
+			ID:         advertised[addr],
+			Advertised: true,
+			Checks:     r.checks,
+		})
+	}
+	return diagnosis, nil
+}
+
+// diagnoseBootstrap runs all checks against a bootstrap broker and returns the advertised brokers
+func diagnoseBootstrap(ctx context.Context, addr string, cfg *sarama.Config, explicit sarama.KafkaVersion, hasVersion bool) (format.BrokerDiagnosis, []*sarama.Broker) {
+	r := newCheckRunner(ctx, addr)
+	host := r.dns(addr)
+	r.tcp(addr, cfg)
+	r.tls(addr, host, cfg)
+
+	probeCfg := *cfg
+	// ApiVersions exists since 0.10.0, use it for talking to the broker until we know better
+	probeCfg.Version = sarama.V0_10_0_0
+	if hasVersion {
+		probeCfg.Version = explicit
+	}
+	broker := sarama.NewBroker(addr)
+	defer broker.Close()
+
+	r.run(checkSASL, func() (format.CheckStatus, string, error) {
+		if err := broker.Open(&probeCfg); err != nil {
+			return format.CheckFailed, "", err
+		}
+		if _, err := broker.Connected(); err != nil {
+			return format.CheckFailed, "", err
+		}
+		if !probeCfg.Net.SASL.Enable {
+			return format.CheckSkipped, "sasl disabled", nil
+		}
+		return format.CheckOK, fmt.Sprintf("authenticated as %s with %s", probeCfg.Net.SASL.User, probeCfg.Net.SASL.Mechanism), nil
+	})
+
+	r.run(checkVersion, func() (format.CheckStatus, string, error) {
+		resp, err := broker.ApiVersions(&sarama.ApiVersionsRequest{})
+		if err != nil {
+			return format.CheckFailed, "", err
+		}
+		if resp.Err != sarama.ErrNoError {
+			return format.CheckFailed, "", resp.Err
+		}
+		versions := make(map[int16]int16, len(resp.ApiVersions))
+		for _, block := range resp.ApiVersions {
+			versions[block.ApiKey] = block.MaxVersion
+		}
+		version := versionFromAPIVersions(versions)
+		if hasVersion && !version.IsAtLeast(explicit) {
+			return format.CheckWarning, fmt.Sprintf("configured version %s is newer than the cluster version %s", explicit, version), nil
+		}
+		return format.CheckOK, fmt.Sprintf("kafka %s", version), nil
+	})
+
+	var metadata *sarama.MetadataResponse
+	var brokers []*sarama.Broker
+	ok := r.run(checkMetadata, func() (format.CheckStatus, string, error) {
+		resp, err := broker.GetMetadata(&sarama.MetadataRequest{Version: 1})
+		if err != nil {
+			return format.CheckFailed, "", err
+		}
+		metadata = resp
+		return format.CheckOK, fmt.Sprintf("%d brokers, controller %d, %d topics", len(resp.Brokers), resp.ControllerID, len(resp.Topics)), nil
+	})
+	if ok {
+		brokers = metadata.Brokers
+	}
+
+	return format.BrokerDiagnosis{Address: addr, ID: -1, Checks: r.checks}, brokers
+}
+
+// checkRunner collects the checks for one broker
+type checkRunner struct {
+	ctx    context.Context
+	addr   string
+	checks []format.Check
+	failed bool
+}
+
+func newCheckRunner(ctx context.Context, addr string) *checkRunner {
+	return &checkRunner{ctx: ctx, addr: addr}
+}
+
+// run executes f unless a previous check failed or ctx is done and records the result. It reports
+// whether f returned without an error, only then the variables written by f may be read, an
+// abandoned f keeps running in the background.
+func (r *checkRunner) run(name string, f func() (format.CheckStatus, string, error)) bool {
+	if r.failed {
+		r.checks = append(r.checks, format.Check{Name: name, Status: format.CheckSkipped, Detail: "previous check failed"})
+		return false
+	}
+	var status format.CheckStatus
+	var detail string
+	start := time.Now()
+	err := call(r.ctx, "diagnosing", r.addr, func() error {
+		var err error
+		status, detail, err = f()
+		return err
+	})
+	check := format.Check{Name: name, Duration: time.Since(start)}
+	if err != nil {
+		r.failed = true
+		check.Status = format.CheckFailed
+		check.Detail = err.Error()
+	} else {
+		check.Status = status
+		check.Detail = detail
+	}
+	log.Debugf("Check %s for %s: %s %s", name, r.addr, check.Status, check.Detail)
+	r.checks = append(r.checks, check)
+	return err == nil
+}
+
+// dns looks up the host of addr and returns it
+func (r *checkRunner) dns(addr string) string {
+	host, _, err := net.SplitHostPort(addr)
+	r.run(checkDNS, func() (format.CheckStatus, string, error) {
+		if err != nil {
+			return format.CheckFailed, "", err
+		}
+		if net.ParseIP(host) != nil {
+			return format.CheckOK, "ip address", nil
+		}
+		addrs, err := net.DefaultResolver.LookupHost(r.ctx, host)
+		if err != nil {
+			return format.CheckFailed, "", err
+		}
+		return format.CheckOK, strings.Join(addrs, ", "), nil
+	})
+	return host
+}
+
+// tcp opens and closes a plain connection to addr
+func (r *checkRunner) tcp(addr string, cfg *sarama.Config) {
+	r.run(checkTCP, func() (format.CheckStatus, string, error) {
+		dialer := net.Dialer{Timeout: cfg.Net.DialTimeout}
+		conn, err := dialer.DialContext(r.ctx, "tcp", addr)
+		if err != nil {
+			return format.CheckFailed, "", err
+		}
+		defer conn.Close()
+		return format.CheckOK, fmt.Sprintf("connected to %s", conn.RemoteAddr()), nil
+	})
+}
+
+// tls does the tls handshake with addr and checks the certificate chain of the broker
+func (r *checkRunner) tls(addr, host string, cfg *sarama.Config) {
+	r.run(checkTLS, func() (format.CheckStatus, string, error) {
+		if !cfg.Net.TLS.Enable {
+			return format.CheckSkipped, "tls disabled", nil
+		}
+		tlsConfig := &tls.Config{}
+		if cfg.Net.TLS.Config != nil {
+			tlsConfig = cfg.Net.TLS.Config.Clone()
+		}
+		if len(tlsConfig.ServerName) == 0 {
+			tlsConfig.ServerName = host
+		}
+		dialer := &net.Dialer{Timeout: cfg.Net.DialTimeout}
+		conn, err := tls.DialWithDialer(dialer, "tcp", addr, tlsConfig)
+		if err != nil {
+			return format.CheckFailed, "", err
+		}
+		defer conn.Close()
+
+		state := conn.ConnectionState()
+		if len(state.PeerCertificates) == 0 {
+			return format.CheckFailed, "", fmt.Errorf("Broker sent no certificate")
+		}
+		leaf := state.PeerCertificates[0]
+		detail := fmt.Sprintf("%s, certificate %s issued by %s, expires %s",
+			tlsVersionName(state.Version), leaf.Subject, leaf.Issuer, leaf.NotAfter.Format("2006-01-02"))
+
+		if tlsConfig.InsecureSkipVerify {
+			// the handshake succeeded without verification, report what verification would say
+			intermediates := x509.NewCertPool()
+			for _, cert := range state.PeerCertificates[1:] {
+				intermediates.AddCert(cert)
+			}
+			_, err := leaf.Verify(x509.VerifyOptions{
+				DNSName:       tlsConfig.ServerName,
+				Roots:         tlsConfig.RootCAs,
+				Intermediates: intermediates,
+			})
+			if err != nil {
+				return format.CheckWarning, fmt.Sprintf("%s, certificate not verified: %s", detail, err), nil
+			}
+		}
+		if time.Until(leaf.NotAfter) < certificateExpiryWarning {
+			return format.CheckWarning, detail, nil
+		}
+		return format.CheckOK, detail, nil
+	})
+}
+
+// tlsVersionName returns the name of a tls protocol version as used for TLSMinVersion
+func tlsVersionName(version uint16) string {
+	for name, v := range tlsVersions {
+		if v == version {
+			return "TLS " + name
+		}
+	}
+	return fmt.Sprintf("TLS 0x%04x", version)
+}
@@ -0,0 +1,174 @@
+package kafka_test
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/izolight/kafkalib/format"
+	"github.com/izolight/kafkalib/kafka"
+)
+
+// closedAddr returns an address nobody listens on
+func closedAddr(t *testing.T) string {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := l.Addr().String()
+	l.Close()
+	return addr
+}
+
+// newTLSListener accepts tls connections and closes them after the handshake
+func newTLSListener(t *testing.T) (string, []byte, func()) {
+	certPEM, keyPEM, _ := newTestCertificate(t)
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{Certificates: []tls.Certificate{cert}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			conn.(*tls.Conn).Handshake()
+			conn.Close()
+		}
+	}()
+	return l.Addr().String(), certPEM, func() { l.Close() }
+}
+
+// statuses returns the status of each check by name
+func statuses(b format.BrokerDiagnosis) map[string]format.CheckStatus {
+	s := map[string]format.CheckStatus{}
+	for _, c := range b.Checks {
+		s[c.Name] = c.Status
+	}
+	return s
+}
+
+func TestDiagnose(t *testing.T) {
+	down := closedAddr(t)
+	broker := sarama.NewMockBroker(t, 1)
+	defer broker.Close()
+	broker.SetHandlerByMap(map[string]sarama.MockResponse{
+		"ApiVersionsRequest": newAPIVersionsResponse(map[int16]int16{1: 8, 18: 1, 19: 2, 32: 1, 37: 0}),
+		"MetadataRequest": sarama.NewMockMetadataResponse(t).
+			SetBroker(broker.Addr(), broker.BrokerID()).
+			SetBroker(down, 2).
+			SetController(broker.BrokerID()),
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	diagnosis, err := kafka.Diagnose(ctx, &kafka.Config{BrokerList: []string{broker.Addr(), down}, DialTimeout: time.Second})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diagnosis.OK() {
+		t.Errorf("Expected the diagnosis to fail")
+	}
+
+	expected := []struct {
+		address    string
+		advertised bool
+		statuses   map[string]format.CheckStatus
+	}{
+		{broker.Addr(), false, map[string]format.CheckStatus{
+			"dns": format.CheckOK, "tcp": format.CheckOK, "tls": format.CheckSkipped,
+			"sasl": format.CheckSkipped, "version": format.CheckOK, "metadata": format.CheckOK,
+		}},
+		{down, false, map[string]format.CheckStatus{
+			"dns": format.CheckOK, "tcp": format.CheckFailed, "tls": format.CheckSkipped,
+			"sasl": format.CheckSkipped, "version": format.CheckSkipped, "metadata": format.CheckSkipped,
+		}},
+		{broker.Addr(), true, map[string]format.CheckStatus{
+			"dns": format.CheckOK, "tcp": format.CheckOK, "tls": format.CheckSkipped,
+		}},
+		{down, true, map[string]format.CheckStatus{
+			"dns": format.CheckOK, "tcp": format.CheckFailed, "tls": format.CheckSkipped,
+		}},
+	}
+	if len(diagnosis.Brokers) != len(expected) {
+		t.Fatalf("Expected %d brokers but got %+v", len(expected), diagnosis.Brokers)
+	}
+	for i, e := range expected {
+		b := diagnosis.Brokers[i]
+		if b.Address != e.address || b.Advertised != e.advertised {
+			t.Errorf("Expected broker %d to be %s (advertised %t) but got %s (advertised %t)", i, e.address, e.advertised, b.Address, b.Advertised)
+		}
+		got := statuses(b)
+		for name, status := range e.statuses {
+			if got[name] != status {
+				t.Errorf("Expected check %s of %s to be %s but got %s", name, b.Address, status, got[name])
+			}
+		}
+	}
+}
+
+func TestDiagnose_TLS(t *testing.T) {
+	addr, certPEM, stop := newTLSListener(t)
+	defer stop()
+	testCases := []struct {
+		name     string
+		config   kafka.Config
+		expected format.CheckStatus
+	}{
+		{"verified", kafka.Config{TLSCAPEM: string(certPEM)}, format.CheckOK},
+		{"unknown authority", kafka.Config{}, format.CheckFailed},
+		{"insecure", kafka.Config{TLSInsecureSkipVerify: true}, format.CheckWarning},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.config.BrokerList = []string{addr}
+			tc.config.TLSEnabled = true
+			tc.config.Version = "2.0.0"
+			diagnosis, err := kafka.Diagnose(context.Background(), &tc.config)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := statuses(diagnosis.Brokers[0])
+			if got["tls"] != tc.expected {
+				t.Errorf("Expected tls check to be %s but got %+v", tc.expected, diagnosis.Brokers[0].Checks)
+			}
+		})
+	}
+}
+
+func TestDiagnose_Timeout(t *testing.T) {
+	broker := sarama.NewMockBroker(t, 1)
+	defer broker.Close()
+	broker.SetHandlerByMap(map[string]sarama.MockResponse{
+		"ApiVersionsRequest": newAPIVersionsResponse(map[int16]int16{1: 8, 18: 1, 19: 2, 32: 1, 37: 0}),
+		"MetadataRequest": sarama.NewMockMetadataResponse(t).
+			SetBroker(broker.Addr(), broker.BrokerID()).
+			SetController(broker.BrokerID()),
+	})
+	// the version check answers in time, the metadata check is abandoned when ctx is done
+	broker.SetLatency(200 * time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+	defer cancel()
+	diagnosis, err := kafka.Diagnose(ctx, &kafka.Config{BrokerList: []string{broker.Addr()}, DialTimeout: time.Second})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(diagnosis.Brokers) != 1 {
+		t.Fatalf("Expected only the bootstrap broker but got %+v", diagnosis.Brokers)
+	}
+	got := statuses(diagnosis.Brokers[0])
+	if got["version"] != format.CheckOK || got["metadata"] != format.CheckFailed {
+		t.Errorf("Expected version ok and metadata failed but got %v", got)
+	}
+	// let the abandoned metadata request finish before the broker is closed
+	time.Sleep(200 * time.Millisecond)
+}
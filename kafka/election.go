@@ -0,0 +1,226 @@
+package kafka
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/izolight/kafkalib/format"
+	log "github.com/sirupsen/logrus"
+)
+
+// minimum kafka versions of the leader election apis
+var (
+	// preferredElectionVersion introduced ElectPreferredLeaders
+	preferredElectionVersion = sarama.V2_2_0_0
+	// uncleanElectionVersion introduced ElectLeaders with the election type
+	uncleanElectionVersion = v2_4_0_0
+)
+
+// errElectionNotNeeded is returned for partitions whose leader already is the one to elect, sarama
+// has no constant for it
+const errElectionNotNeeded sarama.KError = 84
+
+// ElectionOptions controls ElectLeaders
+type ElectionOptions struct {
+	// Unclean elects any live replica for partitions without a leader instead of moving leadership
+	// back to the preferred replica. Messages not replicated to the new leader are lost.
+	Unclean bool
+	// DryRun only returns the partitions that need an election
+	DryRun bool
+}
+
+// ElectLeaders finds the partitions of the topics matched by selector whose leader is not the preferred
+// replica, or that have no leader for an unclean election, and elects a new leader for them. All topics
+// are used if selector is nil. Partitions whose preferred replica is not in sync or that the controller
+// couldn't elect a leader for are reported as failed. Preferred elections need kafka 2.2.0 and unclean
+// ones kafka 2.4.0, on older clusters an error matching ErrUnsupported is returned unless it is a dry run.
+func (c Conn) ElectLeaders(selector Selector, options ElectionOptions) (*format.LeaderElection, error) {
+	return c.ElectLeadersContext(context.Background(), selector, options)
+}
+
+// ElectLeadersContext is ElectLeaders with a context
+func (c Conn) ElectLeadersContext(ctx context.Context, selector Selector, options ElectionOptions) (*format.LeaderElection, error) {
+	const op = "electing leaders"
+	if selector == nil {
+		selector = SelectorFunc(func(string, sarama.TopicDetail) bool { return true })
+	}
+	topics, err := c.GetSelectedTopicsContext(ctx, selector)
+	if err != nil {
+		return nil, newError(op, "", err)
+	}
+	election := &format.LeaderElection{Type: format.ElectionPreferred, DryRun: options.DryRun}
+	if options.Unclean {
+		election.Type = format.ElectionUnclean
+	}
+	if len(topics) == 0 {
+		return election, nil
+	}
+
+	var metadata []*sarama.TopicMetadata
+	err = call(ctx, op, "", func() error {
+		var err error
+		metadata, err = c.admin(ctx).DescribeTopics(topics.Sort())
+		return err
+	})
+	if err != nil {
+		return nil, newError(op, "", err)
+	}
+	sort.Slice(metadata, func(i, j int) bool { return metadata[i].Name < metadata[j].Name })
+
+	pending := 0
+	for _, m := range metadata {
+		if m.Err != sarama.ErrNoError {
+			log.Warnf("Error describing topic %s: %s", m.Name, m.Err)
+			continue
+		}
+		for _, p := range format.FromTopicMetadata(m).Partitions {
+			if len(p.Replicas) == 0 {
+				continue
+			}
+			e := format.PartitionElection{
+				Topic:     m.Name,
+				Partition: p.ID,
+				Leader:    p.Leader,
+				Preferred: p.Replicas[0],
+				Replicas:  p.Replicas,
+				ISR:       p.ISR,
+				Status:    format.ElectionPending,
+			}
+			if options.Unclean {
+				if p.Leader >= 0 {
+					continue
+				}
+			} else {
+				if p.Leader == e.Preferred {
+					continue
+				}
+				if !containsBroker(p.ISR, e.Preferred) {
+					e.Status = format.ElectionFailed
+					e.Error = fmt.Sprintf("preferred replica %d is not in sync", e.Preferred)
+				}
+			}
+			if e.Status == format.ElectionPending {
+				pending++
+			}
+			election.Partitions = append(election.Partitions, e)
+		}
+	}
+	if options.DryRun || pending == 0 {
+		return election, nil
+	}
+
+	var results map[string]map[int32]string
+	err = call(ctx, op, "", func() error {
+		controller, err := c.rawController(ctx)
+		if err != nil {
+			return err
+		}
+		defer controller.Close()
+		version, ok := controller.version(apiKeyElectLeaders, 1)
+		if !ok || (options.Unclean && version < 1) {
+			return electionUnsupported(op, election.Type)
+		}
+		results, err = electLeaders(controller, version, election, c.saramaConfig().Admin.Timeout)
+		return err
+	})
+	if err != nil {
+		return nil, newError(op, "", err)
+	}
+	for i, p := range election.Partitions {
+		if p.Status != format.ElectionPending {
+			continue
+		}
+		msg, ok := results[p.Topic][p.Partition]
+		switch {
+		case !ok:
+			election.Partitions[i].Status = format.ElectionFailed
+			election.Partitions[i].Error = "the controller returned no result"
+		case msg != "":
+			election.Partitions[i].Status = format.ElectionFailed
+			election.Partitions[i].Error = msg
+		default:
+			election.Partitions[i].Status = format.ElectionElected
+		}
+	}
+	return election, nil
+}
+
+// electionUnsupported returns the error for a controller that can't run an election of kind
+func electionUnsupported(op, kind string) error {
+	required := preferredElectionVersion
+	if kind == format.ElectionUnclean {
+		required = uncleanElectionVersion
+	}
+	return &Error{Op: op, Kind: ErrUnsupported, Err: fmt.Errorf("%s leader election needs kafka %s", kind, required)}
+}
+
+// electLeaders sends the pending partitions of election to the controller and returns the error message
+// for each partition by topic and partition, it is empty if the leader was elected. Version 0 is the
+// ElectPreferredLeaders request, version 1 adds the election type.
+func electLeaders(controller *rawBroker, version int16, election *format.LeaderElection, timeout time.Duration) (map[string]map[int32]string, error) {
+	byTopic := map[string][]int32{}
+	var topics []string
+	for _, p := range election.Partitions {
+		if p.Status != format.ElectionPending {
+			continue
+		}
+		if _, ok := byTopic[p.Topic]; !ok {
+			topics = append(topics, p.Topic)
+		}
+		byTopic[p.Topic] = append(byTopic[p.Topic], p.Partition)
+	}
+	e := &protocolEncoder{}
+	if version >= 1 {
+		electionType := int8(0)
+		if election.Type == format.ElectionUnclean {
+			electionType = 1
+		}
+		e.int8(electionType)
+	}
+	e.arrayLength(len(topics))
+	for _, topic := range topics {
+		e.string(topic)
+		e.int32Array(byTopic[topic])
+	}
+	e.int32(int32(timeout / time.Millisecond))
+
+	d, err := controller.request(apiKeyElectLeaders, version, false, e.buf.Bytes())
+	if err != nil {
+		return nil, err
+	}
+	d.int32() // throttle time
+	if version >= 1 {
+		if err := kError(d.int16(), nil); err != nil {
+			return nil, err
+		}
+	}
+	results := map[string]map[int32]string{}
+	for i := d.arrayLength(); i > 0; i-- {
+		topic := d.string()
+		results[topic] = map[int32]string{}
+		for j := d.arrayLength(); j > 0; j-- {
+			partition, code, msg := d.int32(), sarama.KError(d.int16()), d.nullableString()
+			switch {
+			case code == sarama.ErrNoError || code == errElectionNotNeeded:
+				results[topic][partition] = ""
+			case msg != nil && *msg != "":
+				results[topic][partition] = *msg
+			default:
+				results[topic][partition] = code.Error()
+			}
+		}
+	}
+	return results, d.err
+}
+
+func containsBroker(ids []int32, id int32) bool {
+	for _, i := range ids {
+		if i == id {
+			return true
+		}
+	}
+	return false
+}
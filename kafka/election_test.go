@@ -0,0 +1,135 @@
+package kafka_test
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/izolight/kafkalib/kafka"
+)
+
+func TestElectLeaders(t *testing.T) {
+	partitions := map[string][]*sarama.PartitionMetadata{
+		"topicWithReplicas": {{ID: 0, Leader: 2, Replicas: []int32{1, 2, 3}, Isr: []int32{1, 2, 3}}},
+		"topicWithPartitionsAndReplicas": {
+			{ID: 0, Leader: 1, Replicas: []int32{1, 2, 3}, Isr: []int32{1, 2, 3}},
+			{ID: 1, Leader: 3, Replicas: []int32{2, 3, 1}, Isr: []int32{3, 1}},
+			{ID: 2, Leader: -1, Replicas: []int32{3, 1, 2}, Isr: []int32{}, Err: sarama.ErrLeaderNotAvailable},
+		},
+	}
+	testCases := []struct {
+		name     string
+		selector string
+		options  kafka.ElectionOptions
+		expected []string
+	}{
+		{"preferred", "", kafka.ElectionOptions{DryRun: true},
+			[]string{"topicWithPartitionsAndReplicas-1=failed", "topicWithPartitionsAndReplicas-2=failed", "topicWithReplicas-0=pending"}},
+		{"selected", "topicWithReplicas", kafka.ElectionOptions{DryRun: true}, []string{"topicWithReplicas-0=pending"}},
+		{"unclean", "", kafka.ElectionOptions{Unclean: true, DryRun: true}, []string{"topicWithPartitionsAndReplicas-2=pending"}},
+		{"nothing to elect", "simpleTopic", kafka.ElectionOptions{}, nil},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			c := kafka.Conn{
+				AdminClient: &degradedClient{ClusterAdmin: NewTestClient(), partitions: partitions},
+			}
+			selector, err := kafka.ParseSelector(tc.selector)
+			if err != nil {
+				t.Fatal(err)
+			}
+			election, err := c.ElectLeaders(selector, tc.options)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, p := range election.Partitions {
+				got = append(got, fmt.Sprintf("%s-%d=%s", p.Topic, p.Partition, p.Status))
+			}
+			if !reflect.DeepEqual(got, tc.expected) {
+				t.Errorf("Expected %v but got %v", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestElectLeaders_Elect(t *testing.T) {
+	partitions := map[string][]*sarama.PartitionMetadata{
+		"topicWithReplicas": {{ID: 0, Leader: 2, Replicas: []int32{1, 2, 3}, Isr: []int32{1, 2, 3}}},
+		"topicWithPartitionsAndReplicas": {
+			{ID: 0, Leader: 2, Replicas: []int32{1, 2, 3}, Isr: []int32{1, 2, 3}},
+			{ID: 1, Leader: 3, Replicas: []int32{2, 3, 1}, Isr: []int32{3, 1, 2}},
+			{ID: 2, Leader: 1, Replicas: []int32{3, 1, 2}, Isr: []int32{1, 2}},
+		},
+	}
+	broker := newTestRawBroker(t, map[int16]int16{18: 0, 43: 1})
+	defer broker.Close()
+	broker.Handle(43, func(version int16, body []byte) []byte {
+		return (&testWriter{}).int32(0).int16(0).
+			int32(2).str("topicWithPartitionsAndReplicas").int32(2).
+			int32(0).int16(84).nullStr("").
+			int32(1).int16(80).str("broker 2 is not available").
+			str("topicWithReplicas").int32(1).int32(0).int16(0).nullStr("").Bytes()
+	})
+	c := newRawConn(broker)
+	c.AdminClient = &degradedClient{ClusterAdmin: c.AdminClient, partitions: partitions}
+	election, err := c.ElectLeaders(nil, kafka.ElectionOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	request := (&testWriter{}).int8(0).int32(2).
+		str("topicWithPartitionsAndReplicas").int32(2).int32(0).int32(1).
+		str("topicWithReplicas").int32(1).int32(0).
+		int32(int32(sarama.NewConfig().Admin.Timeout / time.Millisecond)).Bytes()
+	if requests := broker.Requests(43); len(requests) != 1 || !bytes.Equal(requests[0], request) {
+		t.Errorf("Expected request %x but got %x", request, requests)
+	}
+	var got []string
+	for _, p := range election.Partitions {
+		got = append(got, fmt.Sprintf("%s-%d=%s%s", p.Topic, p.Partition, p.Status, p.Error))
+	}
+	expected := []string{
+		"topicWithPartitionsAndReplicas-0=elected",
+		"topicWithPartitionsAndReplicas-1=failedbroker 2 is not available",
+		"topicWithPartitionsAndReplicas-2=failedpreferred replica 3 is not in sync",
+		"topicWithReplicas-0=elected",
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected %v but got %v", expected, got)
+	}
+}
+
+func TestElectLeaders_Unsupported(t *testing.T) {
+	partitions := map[string][]*sarama.PartitionMetadata{
+		"simpleTopic":       {{ID: 0, Leader: -1, Replicas: []int32{1}, Isr: []int32{}, Err: sarama.ErrLeaderNotAvailable}},
+		"topicWithReplicas": {{ID: 0, Leader: 2, Replicas: []int32{1, 2, 3}, Isr: []int32{1, 2, 3}}},
+	}
+	testCases := []struct {
+		name     string
+		versions map[int16]int16
+		options  kafka.ElectionOptions
+	}{
+		{"preferred on old cluster", map[int16]int16{18: 0}, kafka.ElectionOptions{}},
+		{"unclean", map[int16]int16{18: 0, 43: 0}, kafka.ElectionOptions{Unclean: true}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			broker := newTestRawBroker(t, tc.versions)
+			defer broker.Close()
+			c := newRawConn(broker)
+			c.AdminClient = &degradedClient{ClusterAdmin: c.AdminClient, partitions: partitions}
+			election, err := c.ElectLeaders(nil, tc.options)
+			if !errors.Is(err, kafka.ErrUnsupported) {
+				t.Errorf("Expected ErrUnsupported but got %v", err)
+			}
+			if election != nil {
+				t.Errorf("Expected no election but got %v", election)
+			}
+		})
+	}
+}
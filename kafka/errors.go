@@ -0,0 +1,128 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+
+	"github.com/Shopify/sarama"
+)
+
+// Sentinel errors for classifying failures with errors.Is
+var (
+	ErrTopicNotFound       = errors.New("topic not found")
+	ErrTopicExists         = errors.New("topic already exists")
+	ErrACLExists           = errors.New("acl already exists")
+	ErrAuthorizationDenied = errors.New("authorization denied")
+	ErrInvalidConfig       = errors.New("invalid config")
+	ErrBrokerUnavailable   = errors.New("broker unavailable")
+	// ErrUnsupported is returned for operations the cluster or the kafka client can't do
+	ErrUnsupported = errors.New("operation not supported")
+)
+
+// Error is returned by the Conn methods. It matches its Kind with errors.Is and unwraps
+// to the underlying error, which usually is a sarama.KError.
+type Error struct {
+	// Op describes the failed operation, e.g. "creating topic"
+	Op       string
+	Resource string
+	// Kind is one of the sentinel errors or nil if the error is not classified
+	Kind error
+	// Err is the underlying error
+	Err error
+	// Detail holds the error message sent by the broker
+	Detail string
+}
+
+// Error implements the error interface
+func (e *Error) Error() string {
+	cause := e.Kind
+	if e.Err != nil {
+		cause = e.Err
+	}
+	msg := fmt.Sprintf("Error %s", e.Op)
+	if len(e.Resource) != 0 {
+		msg += " " + e.Resource
+	}
+	if cause != nil {
+		msg += fmt.Sprintf(": %s", cause)
+	}
+	if len(e.Detail) != 0 {
+		msg += fmt.Sprintf(" (%s)", e.Detail)
+	}
+	return msg
+}
+
+// Unwrap returns the underlying error
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Is reports if target is the Kind of the error
+func (e *Error) Is(target error) bool {
+	return e.Kind != nil && e.Kind == target
+}
+
+// kindOfKError maps kafka error codes to the sentinel errors
+var kindOfKError = map[sarama.KError]error{
+	sarama.ErrUnknownTopicOrPartition:            ErrTopicNotFound,
+	sarama.ErrTopicAlreadyExists:                 ErrTopicExists,
+	sarama.ErrTopicAuthorizationFailed:           ErrAuthorizationDenied,
+	sarama.ErrGroupAuthorizationFailed:           ErrAuthorizationDenied,
+	sarama.ErrClusterAuthorizationFailed:         ErrAuthorizationDenied,
+	sarama.ErrTransactionalIDAuthorizationFailed: ErrAuthorizationDenied,
+	sarama.ErrDelegationTokenAuthorizationFailed: ErrAuthorizationDenied,
+	sarama.ErrSASLAuthenticationFailed:           ErrAuthorizationDenied,
+	sarama.ErrInvalidTopic:                       ErrInvalidConfig,
+	sarama.ErrInvalidPartitions:                  ErrInvalidConfig,
+	sarama.ErrInvalidReplicationFactor:           ErrInvalidConfig,
+	sarama.ErrInvalidReplicaAssignment:           ErrInvalidConfig,
+	sarama.ErrInvalidConfig:                      ErrInvalidConfig,
+	sarama.ErrPolicyViolation:                    ErrInvalidConfig,
+	sarama.ErrBrokerNotAvailable:                 ErrBrokerUnavailable,
+	sarama.ErrUnsupportedVersion:                 ErrUnsupported,
+}
+
+// newError wraps err with the operation and resource and classifies it, nil stays nil
+func newError(op, resource string, err error) error {
+	if err == nil {
+		return nil
+	}
+	var e *Error
+	if errors.As(err, &e) {
+		return err
+	}
+	e = &Error{Op: op, Resource: resource, Err: err}
+
+	// unpack the per topic errors so the KError is reachable with errors.Is
+	switch te := err.(type) {
+	case *sarama.TopicError:
+		e.Err = te.Err
+		if te.ErrMsg != nil {
+			e.Detail = *te.ErrMsg
+		}
+	case *sarama.TopicPartitionError:
+		e.Err = te.Err
+		if te.ErrMsg != nil {
+			e.Detail = *te.ErrMsg
+		}
+	}
+
+	var kerr sarama.KError
+	var netErr net.Error
+	switch {
+	case errors.Is(e.Err, context.Canceled), errors.Is(e.Err, context.DeadlineExceeded):
+		// not classified, context errors are matched with errors.Is directly
+	case errors.As(e.Err, &kerr):
+		e.Kind = kindOfKError[kerr]
+	case errors.Is(e.Err, sarama.ErrOutOfBrokers), errors.Is(e.Err, sarama.ErrNotConnected), errors.As(e.Err, &netErr):
+		e.Kind = ErrBrokerUnavailable
+	}
+	return e
+}
+
+// newKindError returns an Error without underlying error
+func newKindError(op, resource string, kind error) error {
+	return &Error{Op: op, Resource: resource, Kind: kind}
+}
@@ -0,0 +1,91 @@
+package kafka_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/Shopify/sarama"
+	"github.com/izolight/kafkalib/kafka"
+)
+
+func TestErrors_Is(t *testing.T) {
+	c := kafka.Conn{
+		AdminClient: NewTestClient(),
+	}
+	testCases := []struct {
+		name string
+		call func() error
+		kind error
+		kerr sarama.KError
+	}{
+		{
+			"create existing topic",
+			func() error {
+				return c.CreateTopic(kafka.NewTopic{Name: "simpleTopic", TopicDetail: sarama.TopicDetail{NumPartitions: 1, ReplicationFactor: 1}})
+			},
+			kafka.ErrTopicExists,
+			sarama.ErrTopicAlreadyExists,
+		},
+		{
+			"delete missing topic",
+			func() error { return c.DeleteTopic("missingTopic") },
+			kafka.ErrTopicNotFound,
+			sarama.ErrUnknownTopicOrPartition,
+		},
+		{
+			"get missing topic",
+			func() error {
+				_, err := c.GetTopic("missingTopic")
+				return err
+			},
+			kafka.ErrTopicNotFound,
+			sarama.ErrNoError,
+		},
+		{
+			"create existing acl",
+			func() error {
+				return c.CreateACL(&sarama.AclCreation{
+					Resource: sarama.Resource{ResourceName: "test", ResourceType: sarama.AclResourceTopic},
+					Acl:      sarama.Acl{Principal: "User:test", Host: "localhost", Operation: sarama.AclOperationAll, PermissionType: sarama.AclPermissionAllow},
+				})
+			},
+			kafka.ErrACLExists,
+			sarama.ErrNoError,
+		},
+	}
+	for _, tc := range testCases {
+		err := tc.call()
+		if !errors.Is(err, tc.kind) {
+			t.Errorf("%s: %v should be %v", tc.name, err, tc.kind)
+		}
+		var kerr sarama.KError
+		if errors.As(err, &kerr) != (tc.kerr != sarama.ErrNoError) || (tc.kerr != sarama.ErrNoError && kerr != tc.kerr) {
+			t.Errorf("%s: %v should unwrap to %v", tc.name, err, tc.kerr)
+		}
+		var kafkaErr *kafka.Error
+		if !errors.As(err, &kafkaErr) {
+			t.Errorf("%s: %v should be a *kafka.Error", tc.name, err)
+		}
+	}
+}
+
+func TestErrors_BrokerUnavailable(t *testing.T) {
+	broker := sarama.NewMockBroker(t, 1)
+	addr := broker.Addr()
+	broker.Close()
+
+	_, err := kafka.NewConn(&kafka.Config{BrokerList: []string{addr}, Version: "2.0.0"})
+	if err == nil {
+		t.Fatal("Connecting to a closed broker should fail")
+	}
+	if !errors.Is(err, kafka.ErrBrokerUnavailable) {
+		t.Errorf("%v should be %v", err, kafka.ErrBrokerUnavailable)
+	}
+}
+
+func TestErrors_InvalidConfig(t *testing.T) {
+	_, err := kafka.NewSaramaConfig(&kafka.Config{SASLMechanism: "GSSAPI"})
+	if !errors.Is(err, kafka.ErrInvalidConfig) {
+		t.Errorf("%v should be %v", err, kafka.ErrInvalidConfig)
+	}
+}
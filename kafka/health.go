@@ -0,0 +1,87 @@
+package kafka
+
+import (
+	"context"
+	"sort"
+	"strconv"
+
+	"github.com/Shopify/sarama"
+	"github.com/izolight/kafkalib/format"
+	log "github.com/sirupsen/logrus"
+)
+
+// minISRConfig is the topic config holding the minimum number of in sync replicas
+const minISRConfig = "min.insync.replicas"
+
+// GetClusterHealth checks the partitions of all topics for being offline, below min.insync.replicas,
+// under-replicated or led by a replica other than the preferred one
+func (c Conn) GetClusterHealth() (*format.ClusterHealth, error) {
+	return c.GetClusterHealthContext(context.Background())
+}
+
+// GetClusterHealthContext is GetClusterHealth with a context
+func (c Conn) GetClusterHealthContext(ctx context.Context) (*format.ClusterHealth, error) {
+	const op = "getting cluster health"
+	topics, err := c.GetAllTopicsContext(ctx)
+	if err != nil {
+		return nil, newError(op, "", err)
+	}
+	names := topics.Sort()
+
+	var metadata []*sarama.TopicMetadata
+	err = call(ctx, op, "", func() error {
+		var err error
+		metadata, err = c.admin(ctx).DescribeTopics(names)
+		return err
+	})
+	if err != nil {
+		return nil, newError(op, "", err)
+	}
+	sort.Slice(metadata, func(i, j int) bool { return metadata[i].Name < metadata[j].Name })
+
+	defaultMinISR := c.defaultMinISR(ctx, topics)
+	health := &format.ClusterHealth{}
+	for _, m := range metadata {
+		if m.Err != sarama.ErrNoError {
+			log.Warnf("Error describing topic %s: %s", m.Name, m.Err)
+			continue
+		}
+		health.Summary.Topics++
+		minISR := defaultMinISR
+		if value, ok := topics[m.Name].ConfigEntries[minISRConfig]; ok && value != nil {
+			if v, err := strconv.Atoi(*value); err == nil {
+				minISR = v
+			}
+		}
+		for _, p := range format.FromTopicMetadata(m).Partitions {
+			health.Add(m.Name, p, minISR)
+		}
+	}
+	return health, nil
+}
+
+// defaultMinISR returns the min.insync.replicas of the brokers. ListTopics only returns the topic
+// overrides, so it is read from the effective config of a topic without override. It falls back
+// to kafka's default of 1.
+func (c Conn) defaultMinISR(ctx context.Context, topics format.Topics) int {
+	for _, name := range topics.Sort() {
+		if _, ok := topics[name].ConfigEntries[minISRConfig]; ok {
+			continue
+		}
+		config, err := c.GetTopicConfigContext(ctx, name)
+		if err != nil {
+			log.Warnf("Error getting default %s, using 1: %s", minISRConfig, err)
+			return 1
+		}
+		for _, e := range config.Entries {
+			if e.Name != minISRConfig {
+				continue
+			}
+			if v, err := strconv.Atoi(e.Value); err == nil {
+				return v
+			}
+		}
+		break
+	}
+	return 1
+}
@@ -0,0 +1,95 @@
+package kafka_test
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/Shopify/sarama"
+	"github.com/izolight/kafkalib/format"
+	"github.com/izolight/kafkalib/kafka"
+)
+
+// degradedClient reports changed partition metadata for some topics
+type degradedClient struct {
+	sarama.ClusterAdmin
+	partitions map[string][]*sarama.PartitionMetadata
+}
+
+func (d *degradedClient) DescribeTopics(topics []string) ([]*sarama.TopicMetadata, error) {
+	metadata, err := d.ClusterAdmin.DescribeTopics(topics)
+	for _, m := range metadata {
+		if p, ok := d.partitions[m.Name]; ok {
+			m.Partitions = p
+		}
+	}
+	return metadata, err
+}
+
+func TestGetClusterHealth(t *testing.T) {
+	testCases := []struct {
+		name       string
+		partitions map[string][]*sarama.PartitionMetadata
+		minISR     string
+		issues     map[string][]string
+		exitCode   int
+	}{
+		{"healthy", nil, "", map[string][]string{}, format.HealthOK},
+		{
+			"non preferred leader",
+			map[string][]*sarama.PartitionMetadata{
+				"topicWithReplicas": {{ID: 0, Leader: 2, Replicas: []int32{1, 2, 3}, Isr: []int32{1, 2, 3}}},
+			},
+			"",
+			map[string][]string{"topicWithReplicas-0": {format.IssueNonPreferredLeader}},
+			format.HealthWarning,
+		},
+		{
+			"under min isr",
+			map[string][]*sarama.PartitionMetadata{
+				"topicWithReplicas": {{ID: 0, Leader: 1, Replicas: []int32{1, 2, 3}, Isr: []int32{1}}},
+			},
+			"2",
+			map[string][]string{"topicWithReplicas-0": {format.IssueUnderMinISR, format.IssueUnderReplicated}},
+			format.HealthCritical,
+		},
+		{
+			"offline",
+			map[string][]*sarama.PartitionMetadata{
+				"simpleTopic": {{ID: 0, Leader: -1, Replicas: []int32{1}, Isr: []int32{}, Err: sarama.ErrLeaderNotAvailable}},
+			},
+			"",
+			map[string][]string{"simpleTopic-0": {format.IssueOffline, format.IssueUnderMinISR, format.IssueUnderReplicated}},
+			format.HealthCritical,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			c := kafka.Conn{
+				AdminClient: &degradedClient{ClusterAdmin: NewTestClient(), partitions: tc.partitions},
+			}
+			if len(tc.minISR) != 0 {
+				if err := c.SetTopicConfig("topicWithReplicas", map[string]string{"min.insync.replicas": tc.minISR}); err != nil {
+					t.Fatal(err)
+				}
+			}
+			health, err := c.GetClusterHealth()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if health.Summary.Topics != 4 || health.Summary.Partitions != 10 {
+				t.Errorf("Expected 4 topics with 10 partitions but got %+v", health.Summary)
+			}
+			issues := map[string][]string{}
+			for _, p := range health.Partitions {
+				issues[fmt.Sprintf("%s-%d", p.Topic, p.Partition)] = p.Issues
+			}
+			if !reflect.DeepEqual(issues, tc.issues) {
+				t.Errorf("Expected issues %v but got %v", tc.issues, issues)
+			}
+			if health.ExitCode() != tc.exitCode {
+				t.Errorf("Expected exit code %d but got %d: %s", tc.exitCode, health.ExitCode(), health)
+			}
+		})
+	}
+}
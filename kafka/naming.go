@@ -0,0 +1,179 @@
+package kafka
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/Shopify/sarama"
+)
+
+// maxTopicLength is the longest topic name kafka accepts
+const maxTopicLength = 249
+
+// legalTopicChars matches the characters kafka allows in topic names
+var legalTopicChars = regexp.MustCompile(`^[a-zA-Z0-9._-]+$`)
+
+// NamingPolicy decides whether a topic may be created. It is consulted before every topic creation of a
+// Conn with the names of the topics that already exist.
+type NamingPolicy interface {
+	Check(name string, existing []string) error
+}
+
+// NamingPolicyFunc adapts a function to the NamingPolicy interface
+type NamingPolicyFunc func(name string, existing []string) error
+
+// Check implements the NamingPolicy interface
+func (f NamingPolicyFunc) Check(name string, existing []string) error {
+	return f(name, existing)
+}
+
+// namePolicy is a NamingPolicy that only looks at the name, so checking it doesn't list the topics
+type namePolicy func(name string) error
+
+// Check implements the NamingPolicy interface
+func (f namePolicy) Check(name string, _ []string) error {
+	return f(name)
+}
+
+// usesExisting reports if policy needs the names of the existing topics
+func usesExisting(policy NamingPolicy) bool {
+	switch p := policy.(type) {
+	case nil, namePolicy:
+		return false
+	case NamingPolicies:
+		for _, policy := range p {
+			if usesExisting(policy) {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+// NamingPolicies combines policies, a name has to pass all of them
+type NamingPolicies []NamingPolicy
+
+// Check implements the NamingPolicy interface and returns the error of the first failing policy
+func (p NamingPolicies) Check(name string, existing []string) error {
+	for _, policy := range p {
+		if err := policy.Check(name, existing); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// LegalTopicName enforces the rules of kafka itself: only ASCII letters, digits, '.', '_' and '-',
+// at most 249 characters and neither "." nor ".."
+var LegalTopicName NamingPolicy = namePolicy(func(name string) error {
+	switch {
+	case name == "":
+		return fmt.Errorf("topic name is empty")
+	case name == "." || name == "..":
+		return fmt.Errorf("topic name can't be %q", name)
+	case len(name) > maxTopicLength:
+		return fmt.Errorf("topic name is %d characters long, the maximum is %d", len(name), maxTopicLength)
+	case !legalTopicChars.MatchString(name):
+		return fmt.Errorf("topic name %q contains characters other than ASCII letters, digits, '.', '_' and '-'", name)
+	}
+	return nil
+})
+
+// NoCollidingTopics rejects names that only differ from an existing topic by '.' and '_'. Kafka replaces
+// '.' with '_' in metric names, so the metrics of such topics would collide.
+var NoCollidingTopics NamingPolicy = NamingPolicyFunc(func(name string, existing []string) error {
+	normalized := strings.Replace(name, ".", "_", -1)
+	for _, e := range existing {
+		if e != name && strings.Replace(e, ".", "_", -1) == normalized {
+			return fmt.Errorf("topic name %s collides with the existing topic %s as '.' and '_' are the same in metric names", name, e)
+		}
+	}
+	return nil
+})
+
+// NoMixedSeparators rejects names that use both '.' and '_'
+var NoMixedSeparators NamingPolicy = namePolicy(func(name string) error {
+	if strings.Contains(name, ".") && strings.Contains(name, "_") {
+		return fmt.Errorf("topic name %s mixes '.' and '_'", name)
+	}
+	return nil
+})
+
+// DefaultNamingPolicy enforces kafka's rules and prevents metric name collisions
+var DefaultNamingPolicy = NamingPolicies{LegalTopicName, NoCollidingTopics}
+
+// MaxTopicLength rejects names longer than max characters
+func MaxTopicLength(max int) NamingPolicy {
+	return namePolicy(func(name string) error {
+		if len(name) > max {
+			return fmt.Errorf("topic name is %d characters long, the maximum is %d", len(name), max)
+		}
+		return nil
+	})
+}
+
+// MatchTopicPattern requires names to match the regular expression expr completely,
+// e.g. `[a-z]+\.[a-z]+\.[a-z]+\.v[0-9]+` for domain.team.entity.version
+func MatchTopicPattern(expr string) (NamingPolicy, error) {
+	r, err := regexp.Compile("^(?:" + expr + ")$")
+	if err != nil {
+		return nil, &Error{Op: "parsing naming policy", Resource: expr, Kind: ErrInvalidConfig, Err: err}
+	}
+	return namePolicy(func(name string) error {
+		if !r.MatchString(name) {
+			return fmt.Errorf("topic name %s doesn't match %s", name, expr)
+		}
+		return nil
+	}), nil
+}
+
+// checkTopicNames checks the names against the naming policy of the connection. Names checked earlier
+// count as existing for the later ones, so a batch can't collide with itself. It returns an error per
+// name, nil if the name passes or there is no policy. The existing topics are only listed, with a single
+// metadata request, if a policy uses them.
+func (c Conn) checkTopicNames(ctx context.Context, names []string) ([]error, error) {
+	errs := make([]error, len(names))
+	if c.NamingPolicy == nil {
+		return errs, nil
+	}
+	var existing []string
+	if usesExisting(c.NamingPolicy) {
+		var err error
+		existing, err = c.topicNames(ctx)
+		if err != nil {
+			return nil, err
+		}
+	}
+	for i, name := range names {
+		if err := c.NamingPolicy.Check(name, existing); err != nil {
+			errs[i] = &Error{Op: "creating topic", Resource: name, Kind: ErrInvalidConfig, Err: err}
+			continue
+		}
+		existing = append(existing, name)
+	}
+	return errs, nil
+}
+
+// topicNames returns the sorted names of all topics from the metadata of the cluster, unlike
+// GetAllTopics it doesn't describe their configs
+func (c Conn) topicNames(ctx context.Context) ([]string, error) {
+	var metadata []*sarama.TopicMetadata
+	err := call(ctx, "getting topics", "", func() error {
+		var err error
+		metadata, err = c.admin(ctx).DescribeTopics(nil)
+		return err
+	})
+	if err != nil {
+		return nil, newError("getting topics", "", err)
+	}
+	names := make([]string, 0, len(metadata))
+	for _, m := range metadata {
+		names = append(names, m.Name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
@@ -0,0 +1,138 @@
+package kafka_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/Shopify/sarama"
+	"github.com/izolight/kafkalib/format"
+	"github.com/izolight/kafkalib/kafka"
+)
+
+func TestNamingPolicies(t *testing.T) {
+	platform, err := kafka.MatchTopicPattern(`[a-z]+\.[a-z]+\.[a-z]+\.v[0-9]+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	existing := []string{"orders_created", "sales.crm.contact.v1"}
+	testCases := []struct {
+		name    string
+		policy  kafka.NamingPolicy
+		topic   string
+		success bool
+	}{
+		{"legal", kafka.LegalTopicName, "orders-created.v1", true},
+		{"illegal character", kafka.LegalTopicName, "orders/created", false},
+		{"empty", kafka.LegalTopicName, "", false},
+		{"dot", kafka.LegalTopicName, "..", false},
+		{"longest", kafka.LegalTopicName, strings.Repeat("a", 249), true},
+		{"too long", kafka.LegalTopicName, strings.Repeat("a", 250), false},
+		{"collision", kafka.NoCollidingTopics, "orders.created", false},
+		{"same name", kafka.NoCollidingTopics, "orders_created", true},
+		{"no collision", kafka.NoCollidingTopics, "orders.updated", true},
+		{"default", kafka.DefaultNamingPolicy, "orders.created", false},
+		{"mixed separators", kafka.NoMixedSeparators, "orders.created_v1", false},
+		{"max length", kafka.MaxTopicLength(10), "orders.created", false},
+		{"pattern", platform, "sales.crm.lead.v2", true},
+		{"pattern mismatch", platform, "Sales.crm.lead.v2", false},
+		{"pattern prefix", platform, "sales.crm.lead.v2.tmp", false},
+		{"combined", kafka.NamingPolicies{kafka.DefaultNamingPolicy, kafka.NoMixedSeparators, platform}, "sales.crm.contact.v2", true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.policy.Check(tc.topic, existing)
+			if tc.success && err != nil {
+				t.Errorf("Expected no error but got %s", err)
+			}
+			if !tc.success && err == nil {
+				t.Error("Expected an error")
+			}
+		})
+	}
+	if _, err := kafka.MatchTopicPattern("("); !errors.Is(err, kafka.ErrInvalidConfig) {
+		t.Errorf("Expected ErrInvalidConfig but got %v", err)
+	}
+}
+
+func TestCreateTopic_NamingPolicy(t *testing.T) {
+	c := kafka.Conn{AdminClient: NewTestClient(), NamingPolicy: kafka.DefaultNamingPolicy}
+	detail := sarama.TopicDetail{NumPartitions: 1, ReplicationFactor: 1}
+	err := c.CreateTopic(kafka.NewTopic{Name: "simple.topic", TopicDetail: detail})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = c.CreateTopic(kafka.NewTopic{Name: "simple_topic", TopicDetail: detail})
+	if !errors.Is(err, kafka.ErrInvalidConfig) {
+		t.Errorf("Expected ErrInvalidConfig but got %v", err)
+	}
+
+	result, err := c.CreateTopics([]kafka.NewTopic{
+		{Name: "batch.topic", TopicDetail: detail},
+		{Name: "batch_topic", TopicDetail: detail},
+		{Name: "invalid topic", TopicDetail: detail},
+	}, kafka.BulkOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{format.StatusCreated, format.StatusFailed, format.StatusFailed}
+	for i, r := range result.Results {
+		if r.Status != expected[i] {
+			t.Errorf("Expected %s to be %s but got %s", r.Topic, expected[i], r.Status)
+		}
+	}
+
+	_, err = c.CreateTopics([]kafka.NewTopic{
+		{Name: "duplicate.topic", TopicDetail: detail},
+		{Name: "duplicate.topic", TopicDetail: detail},
+	}, kafka.BulkOptions{})
+	if !errors.Is(err, kafka.ErrInvalidConfig) {
+		t.Errorf("Expected ErrInvalidConfig but got %v", err)
+	}
+	if _, ok := c.AdminClient.(*testClient).topics["duplicate.topic"]; ok {
+		t.Errorf("Expected duplicate.topic not to be created")
+	}
+}
+
+// listingClient counts the requests listing topics
+type listingClient struct {
+	sarama.ClusterAdmin
+	listed, described int
+}
+
+func (l *listingClient) ListTopics() (map[string]sarama.TopicDetail, error) {
+	l.listed++
+	return l.ClusterAdmin.ListTopics()
+}
+
+func (l *listingClient) DescribeTopics(topics []string) ([]*sarama.TopicMetadata, error) {
+	if topics == nil {
+		l.described++
+	}
+	return l.ClusterAdmin.DescribeTopics(topics)
+}
+
+func TestCreateTopic_NamingPolicyListing(t *testing.T) {
+	testCases := []struct {
+		name      string
+		policy    kafka.NamingPolicy
+		described int
+	}{
+		{"default", kafka.DefaultNamingPolicy, 1},
+		{"only the name", kafka.NamingPolicies{kafka.LegalTopicName, kafka.NoMixedSeparators, kafka.MaxTopicLength(20)}, 0},
+		{"none", kafka.NamingPolicies{}, 0},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			admin := &listingClient{ClusterAdmin: NewTestClient()}
+			c := kafka.Conn{AdminClient: admin, NamingPolicy: tc.policy}
+			err := c.CreateTopic(kafka.NewTopic{Name: "new.topic", TopicDetail: sarama.TopicDetail{NumPartitions: 1, ReplicationFactor: 1}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if admin.listed != 0 || admin.described != tc.described {
+				t.Errorf("Expected %d metadata requests and no topic listing but got %d and %d", tc.described, admin.described, admin.listed)
+			}
+		})
+	}
+}
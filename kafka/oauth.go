@@ -0,0 +1,95 @@
+package kafka
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Shopify/sarama"
+)
+
+// tokenExpiryMargin is subtracted from the token lifetime so tokens get refreshed before they expire
+const tokenExpiryMargin = 30 * time.Second
+
+// ClientCredentialsTokenProvider fetches OAUTHBEARER tokens from an OAuth2 token endpoint
+// using the client credentials grant and caches them until shortly before they expire
+type ClientCredentialsTokenProvider struct {
+	TokenURL     string
+	ClientID     string
+	ClientSecret string
+	Scopes       []string
+	Extensions   map[string]string
+	HTTPClient   *http.Client
+
+	mu     sync.Mutex
+	token  string
+	expiry time.Time
+}
+
+// tokenResponse is the relevant part of an OAuth2 token endpoint response
+type tokenResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int64  `json:"expires_in"`
+	Error       string `json:"error"`
+	ErrorDesc   string `json:"error_description"`
+}
+
+// Token implements the sarama.AccessTokenProvider interface
+func (p *ClientCredentialsTokenProvider) Token() (*sarama.AccessToken, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.token == "" || !time.Now().Before(p.expiry) {
+		if err := p.refresh(); err != nil {
+			return nil, err
+		}
+	}
+	return &sarama.AccessToken{Token: p.token, Extensions: p.Extensions}, nil
+}
+
+func (p *ClientCredentialsTokenProvider) refresh() error {
+	form := url.Values{}
+	form.Set("grant_type", "client_credentials")
+	if len(p.Scopes) > 0 {
+		form.Set("scope", strings.Join(p.Scopes, " "))
+	}
+	req, err := http.NewRequest(http.MethodPost, p.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return fmt.Errorf("Error creating token request: %s", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth(url.QueryEscape(p.ClientID), url.QueryEscape(p.ClientSecret))
+
+	client := p.HTTPClient
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("Error requesting token: %s", err)
+	}
+	defer resp.Body.Close()
+
+	var tr tokenResponse
+	if err := json.NewDecoder(resp.Body).Decode(&tr); err != nil {
+		return fmt.Errorf("Error decoding token response (status %d): %s", resp.StatusCode, err)
+	}
+	if resp.StatusCode != http.StatusOK || tr.Error != "" {
+		return fmt.Errorf("Error requesting token (status %d): %s %s", resp.StatusCode, tr.Error, tr.ErrorDesc)
+	}
+	if tr.AccessToken == "" {
+		return fmt.Errorf("Token endpoint returned no access token")
+	}
+
+	p.token = tr.AccessToken
+	lifetime := time.Duration(tr.ExpiresIn) * time.Second
+	if lifetime > 2*tokenExpiryMargin {
+		lifetime -= tokenExpiryMargin
+	}
+	p.expiry = time.Now().Add(lifetime)
+	return nil
+}
@@ -0,0 +1,57 @@
+package kafka_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/izolight/kafkalib/kafka"
+)
+
+func TestClientCredentialsTokenProvider_Token(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		user, pass, ok := r.BasicAuth()
+		if !ok || user != "client" || pass != "secret" || r.FormValue("grant_type") != "client_credentials" {
+			w.WriteHeader(http.StatusUnauthorized)
+			fmt.Fprint(w, `{"error":"invalid_client"}`)
+			return
+		}
+		fmt.Fprintf(w, `{"access_token":"token-%d","token_type":"bearer","expires_in":3600}`, requests)
+	}))
+	defer server.Close()
+
+	testCases := []struct {
+		secret  string
+		success bool
+	}{
+		{"secret", true},
+		{"wrong", false},
+	}
+	for _, tc := range testCases {
+		p := &kafka.ClientCredentialsTokenProvider{
+			TokenURL:     server.URL,
+			ClientID:     "client",
+			ClientSecret: tc.secret,
+		}
+		token, err := p.Token()
+		if err != nil && tc.success {
+			t.Fatal(err)
+		}
+		if err == nil && !tc.success {
+			t.Fatal("Should return an error")
+		}
+		if !tc.success {
+			continue
+		}
+		cached, err := p.Token()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if token.Token != cached.Token {
+			t.Errorf("Token was not cached: got %s and %s", token.Token, cached.Token)
+		}
+	}
+}
@@ -0,0 +1,154 @@
+package kafka
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/Shopify/sarama"
+	"github.com/izolight/kafkalib/format"
+)
+
+// AddPartitionsOptions controls how partitions are added to a topic
+type AddPartitionsOptions struct {
+	// Count is the new total number of partitions, it must be larger than the current one
+	Count int32
+	// Assignment holds the replicas of each new partition. If it is nil and Generate is not set,
+	// the controller assigns the replicas.
+	Assignment [][]int32
+	// Generate plans the assignment for the new partitions with PlanReplicaAssignment
+	Generate bool
+	// ValidateOnly lets the controller check the request without creating the partitions
+	ValidateOnly bool
+}
+
+// AddPartitions grows a topic to options.Count partitions and returns the resulting layout.
+// With ValidateOnly the returned layout is the planned one, replicas chosen by the controller
+// are unknown in that case and left empty.
+func (c Conn) AddPartitions(topic string, options AddPartitionsOptions) (*format.TopicDescription, error) {
+	return c.AddPartitionsContext(context.Background(), topic, options)
+}
+
+// AddPartitionsContext is AddPartitions with a context
+func (c Conn) AddPartitionsContext(ctx context.Context, topic string, options AddPartitionsOptions) (*format.TopicDescription, error) {
+	const op = "adding partitions to"
+	current, err := c.DescribeTopicContext(ctx, topic)
+	if err != nil {
+		return nil, newError(op, topic, err)
+	}
+	existing := int32(len(current.Partitions))
+	if options.Count <= existing {
+		return nil, &Error{Op: op, Resource: topic, Kind: ErrInvalidConfig,
+			Err: fmt.Errorf("topic has %d partitions, the new count %d must be larger", existing, options.Count)}
+	}
+	replicationFactor := 0
+	if existing > 0 {
+		replicationFactor = len(current.Partitions[0].Replicas)
+	}
+
+	assignment := options.Assignment
+	if assignment == nil && options.Generate {
+		brokers, err := c.brokers(ctx)
+		if err != nil {
+			return nil, newError(op, topic, err)
+		}
+		assignment, err = planNewPartitions(brokers, current, options.Count, replicationFactor)
+		if err != nil {
+			return nil, &Error{Op: op, Resource: topic, Kind: ErrInvalidConfig, Err: err}
+		}
+	}
+	if assignment != nil {
+		if err := validateAssignment(assignment, int(options.Count-existing), replicationFactor); err != nil {
+			return nil, &Error{Op: op, Resource: topic, Kind: ErrInvalidConfig, Err: err}
+		}
+	}
+
+	err = call(ctx, op, topic, func() error {
+		return c.admin(ctx).CreatePartitions(topic, options.Count, assignment, options.ValidateOnly)
+	})
+	if err != nil {
+		return nil, newError(op, topic, err)
+	}
+	if !options.ValidateOnly {
+		return c.DescribeTopicContext(ctx, topic)
+	}
+
+	planned := *current
+	planned.Partitions = append([]format.PartitionDescription{}, current.Partitions...)
+	for i := int32(0); i < options.Count-existing; i++ {
+		partition := format.PartitionDescription{ID: existing + i, Leader: -1}
+		if assignment != nil {
+			partition.Replicas = assignment[i]
+			partition.Leader = assignment[i][0]
+		}
+		planned.Partitions = append(planned.Partitions, partition)
+	}
+	return &planned, nil
+}
+
+// brokers returns the id and rack of the brokers in the cluster
+func (c Conn) brokers(ctx context.Context) ([]BrokerInfo, error) {
+	var brokers []*sarama.Broker
+	err := call(ctx, "describing cluster", "", func() error {
+		var err error
+		brokers, _, err = c.admin(ctx).DescribeCluster()
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return BrokersFromSarama(brokers), nil
+}
+
+// planNewPartitions plans the replicas of the partitions added to a topic. Like kafka, the plan starts
+// at the first replica of the first partition so the new partitions continue the existing layout
+// independent of the current leaders.
+func planNewPartitions(brokers []BrokerInfo, current *format.TopicDescription, count int32, replicationFactor int) ([][]int32, error) {
+	existing := int32(len(current.Partitions))
+	ids := make([]int32, 0, len(brokers))
+	for _, b := range brokers {
+		ids = append(ids, b.ID)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	startIndex := 0
+	if existing > 0 && len(current.Partitions[0].Replicas) > 0 {
+		for i, id := range ids {
+			if id >= current.Partitions[0].Replicas[0] {
+				startIndex = i
+				break
+			}
+		}
+	}
+	planned, err := PlanReplicaAssignment(brokers, count-existing, replicationFactor, AssignmentOptions{FirstPartition: existing, StartIndex: startIndex})
+	if err != nil {
+		return nil, err
+	}
+	assignment := make([][]int32, 0, len(planned))
+	for p := existing; p < count; p++ {
+		assignment = append(assignment, planned[p])
+	}
+	return assignment, nil
+}
+
+// validateAssignment checks that there is one replica set without duplicates per new partition
+func validateAssignment(assignment [][]int32, partitions, replicationFactor int) error {
+	if len(assignment) != partitions {
+		return fmt.Errorf("assignment has %d partitions but %d are added", len(assignment), partitions)
+	}
+	for i, replicas := range assignment {
+		if len(replicas) == 0 {
+			return fmt.Errorf("assignment of new partition %d has no replicas", i)
+		}
+		if replicationFactor > 0 && len(replicas) != replicationFactor {
+			return fmt.Errorf("assignment of new partition %d has %d replicas instead of %d", i, len(replicas), replicationFactor)
+		}
+		seen := map[int32]bool{}
+		for _, id := range replicas {
+			if seen[id] {
+				return fmt.Errorf("assignment of new partition %d contains broker %d twice", i, id)
+			}
+			seen[id] = true
+		}
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+package kafka_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/Shopify/sarama"
+	"github.com/izolight/kafkalib/kafka"
+)
+
+func TestAddPartitions(t *testing.T) {
+	testCases := []struct {
+		name       string
+		topic      string
+		options    kafka.AddPartitionsOptions
+		partitions int
+		// replicas of the last partition, nil if unknown
+		lastReplicas []int32
+		success      bool
+	}{
+		{"controller assignment", "topicWithPartitions", kafka.AddPartitionsOptions{Count: 6}, 6, []int32{3}, true},
+		{"explicit assignment", "topicWithPartitionsAndReplicas", kafka.AddPartitionsOptions{Count: 5, Assignment: [][]int32{{3, 1, 2}}}, 5, []int32{3, 1, 2}, true},
+		{"generated assignment", "topicWithPartitionsAndReplicas", kafka.AddPartitionsOptions{Count: 6, Generate: true}, 6, []int32{3, 1, 2}, true},
+		{"validate only", "simpleTopic", kafka.AddPartitionsOptions{Count: 3, Generate: true, ValidateOnly: true}, 3, []int32{3}, true},
+		{"validate only with controller assignment", "simpleTopic", kafka.AddPartitionsOptions{Count: 2, ValidateOnly: true}, 2, nil, true},
+		{"count not larger", "topicWithPartitions", kafka.AddPartitionsOptions{Count: 4}, 0, nil, false},
+		{"wrong replication factor", "topicWithReplicas", kafka.AddPartitionsOptions{Count: 2, Assignment: [][]int32{{1}}}, 0, nil, false},
+		{"duplicate broker", "topicWithReplicas", kafka.AddPartitionsOptions{Count: 2, Assignment: [][]int32{{1, 1, 2}}}, 0, nil, false},
+		{"assignment for too many partitions", "simpleTopic", kafka.AddPartitionsOptions{Count: 2, Assignment: [][]int32{{1}, {2}}}, 0, nil, false},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			client := NewTestClient()
+			c := kafka.Conn{
+				AdminClient: client,
+			}
+			layout, err := c.AddPartitions(tc.topic, tc.options)
+			if !tc.success {
+				if !errors.Is(err, kafka.ErrInvalidConfig) {
+					t.Errorf("Expected ErrInvalidConfig but got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(layout.Partitions) != tc.partitions {
+				t.Fatalf("Expected %d partitions but got %+v", tc.partitions, layout.Partitions)
+			}
+			last := layout.Partitions[len(layout.Partitions)-1]
+			if !reflect.DeepEqual(last.Replicas, tc.lastReplicas) {
+				t.Errorf("Expected replicas %v for the last partition but got %v", tc.lastReplicas, last.Replicas)
+			}
+			topic, err := c.DescribeTopic(tc.topic)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tc.options.ValidateOnly && len(topic.Partitions) == tc.partitions {
+				t.Errorf("Validate only must not create partitions")
+			}
+		})
+	}
+}
+
+func TestAddPartitions_NotPreferredLeader(t *testing.T) {
+	testCases := []struct {
+		name      string
+		partition *sarama.PartitionMetadata
+	}{
+		{"preferred leader", &sarama.PartitionMetadata{ID: 0, Leader: 2, Replicas: []int32{2, 3, 1}, Isr: []int32{2, 3, 1}}},
+		{"other leader", &sarama.PartitionMetadata{ID: 0, Leader: 3, Replicas: []int32{2, 3, 1}, Isr: []int32{2, 3, 1}}},
+		{"offline", &sarama.PartitionMetadata{ID: 0, Leader: -1, Replicas: []int32{2, 3, 1}, Isr: []int32{}, Err: sarama.ErrLeaderNotAvailable}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			c := kafka.Conn{AdminClient: &degradedClient{
+				ClusterAdmin: NewTestClient(),
+				partitions:   map[string][]*sarama.PartitionMetadata{"topicWithReplicas": {tc.partition}},
+			}}
+			layout, err := c.AddPartitions("topicWithReplicas", kafka.AddPartitionsOptions{Count: 2, Generate: true, ValidateOnly: true})
+			if err != nil {
+				t.Fatal(err)
+			}
+			// the plan continues after the first replica 2 of partition 0 whoever leads it
+			if replicas := layout.Partitions[1].Replicas; !reflect.DeepEqual(replicas, []int32{3, 2, 1}) {
+				t.Errorf("Expected replicas [3 2 1] for the new partition but got %v", replicas)
+			}
+		})
+	}
+}
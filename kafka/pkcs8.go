@@ -0,0 +1,131 @@
+package kafka
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/des"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"fmt"
+	"hash"
+)
+
+// object identifiers of the PBES2 scheme (RFC 8018)
+var (
+	oidPBES2  = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 5, 13}
+	oidPBKDF2 = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 5, 12}
+	// oidHMACWithSHA1 is the default PBKDF2 pseudo random function
+	oidHMACWithSHA1 = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 7}
+)
+
+// pkcs8PRFs maps the supported PBKDF2 pseudo random functions to their hash
+var pkcs8PRFs = map[string]func() hash.Hash{
+	oidHMACWithSHA1.String():                                 sha1.New,
+	asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 8}.String():  sha256.New224,
+	asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 9}.String():  sha256.New,
+	asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 10}.String(): sha512.New384,
+	asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 11}.String(): sha512.New,
+}
+
+// pkcs8Cipher is a CBC encryption scheme of PBES2
+type pkcs8Cipher struct {
+	keyLen   int
+	newBlock func(key []byte) (cipher.Block, error)
+}
+
+// pkcs8Ciphers maps the supported encryption schemes to their cipher
+var pkcs8Ciphers = map[string]pkcs8Cipher{
+	asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 2}.String():  {16, aes.NewCipher},
+	asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 22}.String(): {24, aes.NewCipher},
+	asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 42}.String(): {32, aes.NewCipher},
+	asn1.ObjectIdentifier{1, 2, 840, 113549, 3, 7}.String():         {24, des.NewTripleDESCipher},
+}
+
+type encryptedPrivateKeyInfo struct {
+	Algorithm     pkix.AlgorithmIdentifier
+	EncryptedData []byte
+}
+
+type pbes2Params struct {
+	KeyDerivationFunc pkix.AlgorithmIdentifier
+	EncryptionScheme  pkix.AlgorithmIdentifier
+}
+
+type pbkdf2Params struct {
+	Salt       []byte
+	Iterations int
+	KeyLength  int                      `asn1:"optional"`
+	PRF        pkix.AlgorithmIdentifier `asn1:"optional"`
+}
+
+// decryptPKCS8 decrypts the DER of an ENCRYPTED PRIVATE KEY block and returns the DER of the
+// unencrypted PKCS#8 key. Only PBES2 with PBKDF2 and AES or 3DES in CBC mode is supported, which
+// covers what openssl and keytool produce.
+func decryptPKCS8(der, password []byte) ([]byte, error) {
+	var info encryptedPrivateKeyInfo
+	if _, err := asn1.Unmarshal(der, &info); err != nil {
+		return nil, fmt.Errorf("Error parsing encrypted key: %s", err)
+	}
+	if !info.Algorithm.Algorithm.Equal(oidPBES2) {
+		return nil, fmt.Errorf("Unsupported key encryption %s, only PBES2 is supported", info.Algorithm.Algorithm)
+	}
+	var params pbes2Params
+	if _, err := asn1.Unmarshal(info.Algorithm.Parameters.FullBytes, &params); err != nil {
+		return nil, fmt.Errorf("Error parsing PBES2 parameters: %s", err)
+	}
+	if !params.KeyDerivationFunc.Algorithm.Equal(oidPBKDF2) {
+		return nil, fmt.Errorf("Unsupported key derivation %s, only PBKDF2 is supported", params.KeyDerivationFunc.Algorithm)
+	}
+	var kdf pbkdf2Params
+	if _, err := asn1.Unmarshal(params.KeyDerivationFunc.Parameters.FullBytes, &kdf); err != nil {
+		return nil, fmt.Errorf("Error parsing PBKDF2 parameters: %s", err)
+	}
+	prf := oidHMACWithSHA1
+	if len(kdf.PRF.Algorithm) != 0 {
+		prf = kdf.PRF.Algorithm
+	}
+	hashGen, ok := pkcs8PRFs[prf.String()]
+	if !ok {
+		return nil, fmt.Errorf("Unsupported PBKDF2 function %s", prf)
+	}
+	scheme, ok := pkcs8Ciphers[params.EncryptionScheme.Algorithm.String()]
+	if !ok {
+		return nil, fmt.Errorf("Unsupported key cipher %s", params.EncryptionScheme.Algorithm)
+	}
+	var iv []byte
+	if _, err := asn1.Unmarshal(params.EncryptionScheme.Parameters.FullBytes, &iv); err != nil {
+		return nil, fmt.Errorf("Error parsing cipher parameters: %s", err)
+	}
+	if kdf.KeyLength != 0 && kdf.KeyLength != scheme.keyLen {
+		return nil, fmt.Errorf("Key length %d doesn't match the cipher", kdf.KeyLength)
+	}
+
+	key := pbkdf2(hashGen, password, kdf.Salt, kdf.Iterations, scheme.keyLen)
+	block, err := scheme.newBlock(key)
+	if err != nil {
+		return nil, err
+	}
+	data := info.EncryptedData
+	if len(iv) != block.BlockSize() || len(data) == 0 || len(data)%block.BlockSize() != 0 {
+		return nil, fmt.Errorf("Invalid encrypted key")
+	}
+	plain := make([]byte, len(data))
+	cipher.NewCBCDecrypter(block, iv).CryptBlocks(plain, data)
+
+	// a wrong password shows up as invalid padding or as a key that can't be parsed
+	padding := int(plain[len(plain)-1])
+	if padding == 0 || padding > block.BlockSize() ||
+		!bytes.Equal(plain[len(plain)-padding:], bytes.Repeat([]byte{byte(padding)}, padding)) {
+		return nil, x509.IncorrectPasswordError
+	}
+	plain = plain[:len(plain)-padding]
+	if _, err := x509.ParsePKCS8PrivateKey(plain); err != nil {
+		return nil, x509.IncorrectPasswordError
+	}
+	return plain, nil
+}
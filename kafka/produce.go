@@ -0,0 +1,192 @@
+package kafka
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/Shopify/sarama"
+	"github.com/izolight/kafkalib/format"
+)
+
+var partitioners = map[string]sarama.PartitionerConstructor{
+	"hash":       sarama.NewHashPartitioner,
+	"random":     sarama.NewRandomPartitioner,
+	"roundrobin": sarama.NewRoundRobinPartitioner,
+}
+
+var acks = map[string]sarama.RequiredAcks{
+	"all":    sarama.WaitForAll,
+	"leader": sarama.WaitForLocal,
+	"none":   sarama.NoResponse,
+}
+
+var compressions = map[string]sarama.CompressionCodec{
+	"none":   sarama.CompressionNone,
+	"gzip":   sarama.CompressionGZIP,
+	"snappy": sarama.CompressionSnappy,
+	"lz4":    sarama.CompressionLZ4,
+	"zstd":   sarama.CompressionZSTD,
+}
+
+// ProducerOptions controls how NewProducer sends messages
+type ProducerOptions struct {
+	// Partitioner is one of hash (default), random or roundrobin. It is used for messages without a partition.
+	Partitioner string
+	// Acks is one of all (default), leader or none
+	Acks string
+	// Compression is one of none (default), gzip, snappy, lz4 or zstd, zstd needs kafka 2.1
+	Compression string
+}
+
+// Producer sends messages one by one and reports the offset of each of them
+type Producer struct {
+	SyncProducer sarama.SyncProducer
+	// NoAcks is set if the producer doesn't wait for acks, the brokers don't report offsets then
+	NoAcks bool
+}
+
+// NewProducer creates a Producer with the connection settings of config
+func NewProducer(config *Config, options ProducerOptions) (*Producer, error) {
+	cfg, err := newConnectedConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	if err := applyProducerOptions(cfg, options); err != nil {
+		return nil, &Error{Op: "building config", Kind: ErrInvalidConfig, Err: err}
+	}
+	producer, err := sarama.NewSyncProducer(config.BrokerList, cfg)
+	if err != nil {
+		return nil, newError("connecting to", strings.Join(config.BrokerList, ","), err)
+	}
+	return &Producer{SyncProducer: producer, NoAcks: cfg.Producer.RequiredAcks == sarama.NoResponse}, nil
+}
+
+// applyProducerOptions sets the producer settings of cfg
+func applyProducerOptions(cfg *sarama.Config, options ProducerOptions) error {
+	partitioner, ok := partitioners[defaultString(options.Partitioner, "hash")]
+	if !ok {
+		return fmt.Errorf("unknown partitioner %s", options.Partitioner)
+	}
+	ack, ok := acks[defaultString(options.Acks, "all")]
+	if !ok {
+		return fmt.Errorf("unknown acks %s", options.Acks)
+	}
+	compression, ok := compressions[defaultString(options.Compression, "none")]
+	if !ok {
+		return fmt.Errorf("unknown compression %s", options.Compression)
+	}
+	cfg.Producer.Partitioner = func(topic string) sarama.Partitioner {
+		return explicitPartitioner{partitioner(topic)}
+	}
+	cfg.Producer.RequiredAcks = ack
+	cfg.Producer.Compression = compression
+	// required by the SyncProducer
+	cfg.Producer.Return.Successes = true
+	cfg.Producer.Return.Errors = true
+	return cfg.Validate()
+}
+
+func defaultString(value, fallback string) string {
+	if len(value) == 0 {
+		return fallback
+	}
+	return value
+}
+
+// explicitPartition is the Metadata of messages whose partition is set by the input
+type explicitPartition int32
+
+// explicitPartitioner sends messages with an explicitPartition to it and uses its Partitioner for the others
+type explicitPartitioner struct {
+	sarama.Partitioner
+}
+
+// Partition implements the sarama.Partitioner interface
+func (p explicitPartitioner) Partition(message *sarama.ProducerMessage, numPartitions int32) (int32, error) {
+	if partition, ok := message.Metadata.(explicitPartition); ok {
+		if int32(partition) >= numPartitions {
+			return -1, sarama.ErrInvalidPartition
+		}
+		return int32(partition), nil
+	}
+	return p.Partitioner.Partition(message, numPartitions)
+}
+
+// MessageRequiresConsistency implements the sarama.DynamicConsistencyPartitioner interface. An explicit
+// partition has to be chosen from all partitions, not only the ones with a leader.
+func (p explicitPartitioner) MessageRequiresConsistency(message *sarama.ProducerMessage) bool {
+	if _, ok := message.Metadata.(explicitPartition); ok {
+		return true
+	}
+	if dynamic, ok := p.Partitioner.(sarama.DynamicConsistencyPartitioner); ok {
+		return dynamic.MessageRequiresConsistency(message)
+	}
+	return p.Partitioner.RequiresConsistency()
+}
+
+// Produce sends the messages read from reader to topic in the order they are read. The topic of the
+// messages is ignored, the partition is used if it is set. A message that fails doesn't stop the others,
+// reading stops at the first invalid input. With NoAcks the offsets of the records are -1.
+func (p Producer) Produce(topic string, reader *format.MessageReader) (*format.ProduceResult, error) {
+	return p.ProduceContext(context.Background(), topic, reader)
+}
+
+// ProduceContext is Produce with a context, the records not sent before ctx is done are not reported
+func (p Producer) ProduceContext(ctx context.Context, topic string, reader *format.MessageReader) (*format.ProduceResult, error) {
+	const op = "producing to"
+	result := &format.ProduceResult{Topic: topic}
+	for {
+		if err := ctx.Err(); err != nil {
+			return result, newError(op, topic, err)
+		}
+		message, err := reader.Read()
+		if err == io.EOF {
+			return result, nil
+		}
+		if err != nil {
+			return result, &Error{Op: op, Resource: topic, Kind: ErrInvalidConfig, Err: err}
+		}
+		record := format.ProducedRecord{Key: message.Key, Partition: -1, Offset: -1}
+		err = call(ctx, op, topic, func() error {
+			var err error
+			record.Partition, record.Offset, err = p.SyncProducer.SendMessage(toProducerMessage(topic, message))
+			return err
+		})
+		if err != nil {
+			if ctx.Err() != nil {
+				return result, newError(op, topic, err)
+			}
+			record.Partition, record.Offset = -1, -1
+			record.Error = newError(op, topic, err).Error()
+		} else if p.NoAcks {
+			record.Offset = -1
+		}
+		result.Records = append(result.Records, record)
+	}
+}
+
+// Close closes the underlying producer
+func (p Producer) Close() error {
+	return p.SyncProducer.Close()
+}
+
+// toProducerMessage converts a message read from the input, an empty key is sent as null
+func toProducerMessage(topic string, message format.Message) *sarama.ProducerMessage {
+	m := &sarama.ProducerMessage{
+		Topic:     topic,
+		Value:     sarama.StringEncoder(message.Value),
+		Timestamp: message.Timestamp,
+	}
+	if len(message.Key) != 0 {
+		m.Key = sarama.StringEncoder(message.Key)
+	}
+	if message.Partition >= 0 {
+		m.Metadata = explicitPartition(message.Partition)
+	}
+	for _, h := range message.Headers {
+		m.Headers = append(m.Headers, sarama.RecordHeader{Key: []byte(h.Key), Value: []byte(h.Value)})
+	}
+	return m
+}
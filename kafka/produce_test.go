@@ -0,0 +1,126 @@
+package kafka_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/Shopify/sarama"
+	"github.com/Shopify/sarama/mocks"
+	"github.com/izolight/kafkalib/format"
+	"github.com/izolight/kafkalib/kafka"
+)
+
+func TestProducer_Produce(t *testing.T) {
+	mock := mocks.NewSyncProducer(t, nil)
+	mock.ExpectSendMessageAndSucceed()
+	mock.ExpectSendMessageAndFail(sarama.ErrNotEnoughReplicas)
+	mock.ExpectSendMessageAndSucceed()
+	producer := kafka.Producer{SyncProducer: mock}
+	defer producer.Close()
+
+	reader, err := format.NewMessageReader(strings.NewReader("a=1\nb=2\nc=3\n"), format.InputText, "=")
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := producer.Produce("simpleTopic", reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []format.ProducedRecord{
+		{Key: "a", Partition: 0, Offset: 1},
+		{Key: "b", Partition: -1, Offset: -1},
+		{Key: "c", Partition: 0, Offset: 2},
+	}
+	if len(result.Records) != len(expected) {
+		t.Fatalf("Expected %d records but got %d", len(expected), len(result.Records))
+	}
+	for i, r := range result.Records {
+		if r.Key != expected[i].Key || r.Partition != expected[i].Partition || r.Offset != expected[i].Offset {
+			t.Errorf("Expected %v but got %v", expected[i], r)
+		}
+	}
+	if result.Failed() != 1 || !strings.Contains(result.Records[1].Error, sarama.ErrNotEnoughReplicas.Error()) {
+		t.Errorf("Expected the second record to fail but got %v", result.Records)
+	}
+}
+
+func TestProducer_NoAcks(t *testing.T) {
+	mock := mocks.NewSyncProducer(t, nil)
+	mock.ExpectSendMessageAndSucceed()
+	producer := kafka.Producer{SyncProducer: mock, NoAcks: true}
+	defer producer.Close()
+
+	reader, err := format.NewMessageReader(strings.NewReader("a=1\n"), format.InputText, "=")
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := producer.Produce("simpleTopic", reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Records) != 1 || result.Records[0].Offset != -1 || result.Records[0].Partition != 0 {
+		t.Errorf("Expected the record in partition 0 with an unknown offset but got %v", result.Records)
+	}
+}
+
+func TestProducer_InvalidInput(t *testing.T) {
+	mock := mocks.NewSyncProducer(t, nil)
+	mock.ExpectSendMessageAndSucceed()
+	producer := kafka.Producer{SyncProducer: mock}
+	defer producer.Close()
+
+	reader, err := format.NewMessageReader(strings.NewReader(`{"value":"1"} {"value":`), format.InputJSON, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := producer.Produce("simpleTopic", reader)
+	if !errors.Is(err, kafka.ErrInvalidConfig) {
+		t.Errorf("Expected ErrInvalidConfig but got %v", err)
+	}
+	if len(result.Records) != 1 {
+		t.Errorf("Expected the record before the invalid input to be produced but got %v", result.Records)
+	}
+}
+
+func TestNewProducer(t *testing.T) {
+	broker := newMockCluster(t)
+	defer broker.Close()
+	broker.SetHandlerByMap(map[string]sarama.MockResponse{
+		"MetadataRequest": sarama.NewMockMetadataResponse(t).
+			SetBroker(broker.Addr(), broker.BrokerID()).
+			SetLeader("simpleTopic", 0, broker.BrokerID()),
+		"ProduceRequest": sarama.NewMockProduceResponse(t).SetVersion(3),
+	})
+	config := &kafka.Config{BrokerList: []string{broker.Addr()}, Version: "2.1.0"}
+
+	if _, err := kafka.NewProducer(config, kafka.ProducerOptions{Compression: "brotli"}); !errors.Is(err, kafka.ErrInvalidConfig) {
+		t.Errorf("Expected ErrInvalidConfig but got %v", err)
+	}
+	producer, err := kafka.NewProducer(config, kafka.ProducerOptions{Partitioner: "roundrobin", Acks: "leader", Compression: "gzip"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer producer.Close()
+	if producer.NoAcks {
+		t.Error("Expected a producer waiting for the leader")
+	}
+
+	input := `{"key":"a","value":"1","partition":0}
+{"key":"b","value":"2","partition":5}
+{"key":"c","value":"3"}`
+	reader, err := format.NewMessageReader(strings.NewReader(input), format.InputJSON, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := producer.Produce("simpleTopic", reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	failed := []bool{false, true, false}
+	for i, r := range result.Records {
+		if (len(r.Error) != 0) != failed[i] {
+			t.Errorf("Expected record %s to fail: %t but got %v", r.Key, failed[i], r)
+		}
+	}
+}
@@ -0,0 +1,463 @@
+package kafka
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Shopify/sarama"
+)
+
+// api keys of the requests encoded by hand, sarama has no types for them
+const (
+	apiKeySaslHandshake               int16 = 17
+	apiKeyApiVersions                 int16 = 18
+	apiKeyDescribeLogDirs             int16 = 35
+	apiKeySaslAuthenticate            int16 = 36
+	apiKeyElectLeaders                int16 = 43
+	apiKeyAlterPartitionReassignments int16 = 45
+	apiKeyListPartitionReassignments  int16 = 46
+)
+
+// rawBroker is a connection to a single broker for the requests sarama can't send. It authenticates
+// like sarama with the TLS and SASL settings of the config and learns the api versions of the broker.
+type rawBroker struct {
+	addr          string
+	conf          *sarama.Config
+	conn          net.Conn
+	correlationID int32
+	// versions holds the max version of each api supported by the broker
+	versions map[int16]int16
+}
+
+// openRawBroker connects and authenticates to the broker at addr
+func openRawBroker(addr string, conf *sarama.Config) (*rawBroker, error) {
+	dialer := &net.Dialer{Timeout: conf.Net.DialTimeout, KeepAlive: conf.Net.KeepAlive, LocalAddr: conf.Net.LocalAddr}
+	var conn net.Conn
+	var err error
+	if conf.Net.TLS.Enable {
+		tlsConfig := conf.Net.TLS.Config
+		if tlsConfig == nil {
+			tlsConfig = &tls.Config{}
+		}
+		conn, err = tls.DialWithDialer(dialer, "tcp", addr, tlsConfig)
+	} else {
+		conn, err = dialer.Dial("tcp", addr)
+	}
+	if err != nil {
+		return nil, err
+	}
+	b := &rawBroker{addr: addr, conf: conf, conn: conn}
+	if err := b.apiVersions(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if conf.Net.SASL.Enable {
+		if err := b.authenticate(); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	return b, nil
+}
+
+// rawController opens a rawBroker to the controller of the cluster
+func (c Conn) rawController(ctx context.Context) (*rawBroker, error) {
+	brokers, controllerID, err := c.admin(ctx).DescribeCluster()
+	if err != nil {
+		return nil, err
+	}
+	for _, b := range brokers {
+		if b.ID() == controllerID {
+			return openRawBroker(b.Addr(), c.saramaConfig())
+		}
+	}
+	return nil, sarama.ErrControllerNotAvailable
+}
+
+// saramaConfig returns the config of the client or the sarama defaults if the Conn has no client
+func (c Conn) saramaConfig() *sarama.Config {
+	if c.Client != nil {
+		return c.Client.Config()
+	}
+	return sarama.NewConfig()
+}
+
+// Close closes the connection
+func (b *rawBroker) Close() error {
+	return b.conn.Close()
+}
+
+// version returns the highest version of the api up to max that the broker supports
+func (b *rawBroker) version(apiKey, max int16) (int16, bool) {
+	v, ok := b.versions[apiKey]
+	if !ok {
+		return 0, false
+	}
+	if v > max {
+		v = max
+	}
+	return v, true
+}
+
+// request sends a request with body and returns the decoder for the body of the response.
+// flexible selects the request and response headers with tagged fields introduced by KIP-482.
+func (b *rawBroker) request(apiKey, version int16, flexible bool, body []byte) (*protocolDecoder, error) {
+	b.correlationID++
+	header := &protocolEncoder{}
+	header.int16(apiKey)
+	header.int16(version)
+	header.int32(b.correlationID)
+	header.string(b.conf.ClientID)
+	if flexible {
+		header.tags()
+	}
+	size := &protocolEncoder{}
+	size.int32(int32(header.buf.Len() + len(body)))
+
+	if err := b.conn.SetWriteDeadline(time.Now().Add(b.conf.Net.WriteTimeout)); err != nil {
+		return nil, err
+	}
+	if _, err := b.conn.Write(append(append(size.buf.Bytes(), header.buf.Bytes()...), body...)); err != nil {
+		return nil, err
+	}
+
+	if err := b.conn.SetReadDeadline(time.Now().Add(b.conf.Net.ReadTimeout)); err != nil {
+		return nil, err
+	}
+	var length [4]byte
+	if _, err := io.ReadFull(b.conn, length[:]); err != nil {
+		return nil, err
+	}
+	response := make([]byte, binary.BigEndian.Uint32(length[:]))
+	if _, err := io.ReadFull(b.conn, response); err != nil {
+		return nil, err
+	}
+	d := &protocolDecoder{b: response}
+	if id := d.int32(); d.err == nil && id != b.correlationID {
+		return nil, fmt.Errorf("correlation id %d of the response doesn't match %d", id, b.correlationID)
+	}
+	if flexible {
+		d.skipTags()
+	}
+	return d, d.err
+}
+
+// apiVersions fetches the api versions supported by the broker
+func (b *rawBroker) apiVersions() error {
+	d, err := b.request(apiKeyApiVersions, 0, false, nil)
+	if err != nil {
+		return err
+	}
+	if kerr := sarama.KError(d.int16()); kerr != sarama.ErrNoError {
+		return kerr
+	}
+	b.versions = map[int16]int16{}
+	for i := d.arrayLength(); i > 0; i-- {
+		key, _, max := d.int16(), d.int16(), d.int16()
+		b.versions[key] = max
+	}
+	return d.err
+}
+
+// authenticate runs the SASL handshake and the exchange of the configured mechanism,
+// each message is sent in a SaslAuthenticate request
+func (b *rawBroker) authenticate() error {
+	sasl := b.conf.Net.SASL
+	mechanism := sasl.Mechanism
+	if mechanism == "" {
+		mechanism = sarama.SASLTypePlaintext
+	}
+	handshake := &protocolEncoder{}
+	handshake.string(string(mechanism))
+	d, err := b.request(apiKeySaslHandshake, 1, false, handshake.buf.Bytes())
+	if err != nil {
+		return err
+	}
+	if kerr := sarama.KError(d.int16()); kerr != sarama.ErrNoError {
+		return kerr
+	}
+
+	switch mechanism {
+	case sarama.SASLTypePlaintext:
+		_, err := b.saslAuthenticate([]byte("\x00" + sasl.User + "\x00" + sasl.Password))
+		return err
+	case sarama.SASLTypeOAuth:
+		token, err := sasl.TokenProvider.Token()
+		if err != nil {
+			return err
+		}
+		extensions := make([]string, 0, len(token.Extensions))
+		for k, v := range token.Extensions {
+			extensions = append(extensions, "\x01"+k+"="+v)
+		}
+		sort.Strings(extensions)
+		_, err = b.saslAuthenticate([]byte(fmt.Sprintf("n,,\x01auth=Bearer %s%s\x01\x01", token.Token, strings.Join(extensions, ""))))
+		return err
+	case sarama.SASLTypeSCRAMSHA256, sarama.SASLTypeSCRAMSHA512:
+		client := sasl.SCRAMClientGeneratorFunc()
+		if err := client.Begin(sasl.User, sasl.Password, sasl.SCRAMAuthzID); err != nil {
+			return err
+		}
+		msg, err := client.Step("")
+		if err != nil {
+			return err
+		}
+		for !client.Done() {
+			challenge, err := b.saslAuthenticate([]byte(msg))
+			if err != nil {
+				return err
+			}
+			if msg, err = client.Step(string(challenge)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return fmt.Errorf("SASL mechanism %s is not supported", mechanism)
+}
+
+// saslAuthenticate sends a SaslAuthenticate v0 request and returns the bytes of the server
+func (b *rawBroker) saslAuthenticate(auth []byte) ([]byte, error) {
+	e := &protocolEncoder{}
+	e.bytes(auth)
+	d, err := b.request(apiKeySaslAuthenticate, 0, false, e.buf.Bytes())
+	if err != nil {
+		return nil, err
+	}
+	kerr := sarama.KError(d.int16())
+	msg := d.nullableString()
+	challenge := d.bytes()
+	if kerr != sarama.ErrNoError {
+		return nil, &sarama.TopicError{Err: kerr, ErrMsg: msg}
+	}
+	return challenge, d.err
+}
+
+// protocolEncoder writes the primitive types of the kafka protocol
+type protocolEncoder struct {
+	buf bytes.Buffer
+}
+
+func (e *protocolEncoder) int8(v int8) {
+	e.buf.WriteByte(byte(v))
+}
+
+func (e *protocolEncoder) int16(v int16) {
+	var b [2]byte
+	binary.BigEndian.PutUint16(b[:], uint16(v))
+	e.buf.Write(b[:])
+}
+
+func (e *protocolEncoder) int32(v int32) {
+	var b [4]byte
+	binary.BigEndian.PutUint32(b[:], uint32(v))
+	e.buf.Write(b[:])
+}
+
+func (e *protocolEncoder) uvarint(v uint64) {
+	var b [binary.MaxVarintLen64]byte
+	e.buf.Write(b[:binary.PutUvarint(b[:], v)])
+}
+
+func (e *protocolEncoder) string(s string) {
+	e.int16(int16(len(s)))
+	e.buf.WriteString(s)
+}
+
+func (e *protocolEncoder) bytes(b []byte) {
+	e.int32(int32(len(b)))
+	e.buf.Write(b)
+}
+
+// arrayLength writes the length of an array, -1 is a null array
+func (e *protocolEncoder) arrayLength(n int) {
+	e.int32(int32(n))
+}
+
+func (e *protocolEncoder) int32Array(values []int32) {
+	e.arrayLength(len(values))
+	for _, v := range values {
+		e.int32(v)
+	}
+}
+
+func (e *protocolEncoder) compactString(s string) {
+	e.uvarint(uint64(len(s)) + 1)
+	e.buf.WriteString(s)
+}
+
+// compactArrayLength writes the length of a compact array, -1 is a null array
+func (e *protocolEncoder) compactArrayLength(n int) {
+	e.uvarint(uint64(n + 1))
+}
+
+func (e *protocolEncoder) compactInt32Array(values []int32) {
+	if values == nil {
+		e.compactArrayLength(-1)
+		return
+	}
+	e.compactArrayLength(len(values))
+	for _, v := range values {
+		e.int32(v)
+	}
+}
+
+// tags writes an empty set of tagged fields
+func (e *protocolEncoder) tags() {
+	e.uvarint(0)
+}
+
+// protocolDecoder reads the primitive types of the kafka protocol. The first error is kept in err
+// and all following reads return zero values.
+type protocolDecoder struct {
+	b   []byte
+	err error
+}
+
+// next returns the next n bytes
+func (d *protocolDecoder) next(n int) []byte {
+	if d.err != nil {
+		return nil
+	}
+	if n < 0 || n > len(d.b) {
+		d.err = fmt.Errorf("response is too short")
+		return nil
+	}
+	b := d.b[:n]
+	d.b = d.b[n:]
+	return b
+}
+
+func (d *protocolDecoder) int8() int8 {
+	if b := d.next(1); b != nil {
+		return int8(b[0])
+	}
+	return 0
+}
+
+func (d *protocolDecoder) bool() bool {
+	return d.int8() != 0
+}
+
+func (d *protocolDecoder) int16() int16 {
+	if b := d.next(2); b != nil {
+		return int16(binary.BigEndian.Uint16(b))
+	}
+	return 0
+}
+
+func (d *protocolDecoder) int32() int32 {
+	if b := d.next(4); b != nil {
+		return int32(binary.BigEndian.Uint32(b))
+	}
+	return 0
+}
+
+func (d *protocolDecoder) int64() int64 {
+	if b := d.next(8); b != nil {
+		return int64(binary.BigEndian.Uint64(b))
+	}
+	return 0
+}
+
+func (d *protocolDecoder) uvarint() uint64 {
+	if d.err != nil {
+		return 0
+	}
+	v, n := binary.Uvarint(d.b)
+	if n <= 0 {
+		d.err = fmt.Errorf("invalid varint in response")
+		return 0
+	}
+	d.b = d.b[n:]
+	return v
+}
+
+func (d *protocolDecoder) string() string {
+	return string(d.next(int(d.int16())))
+}
+
+func (d *protocolDecoder) nullableString() *string {
+	n := d.int16()
+	if n < 0 {
+		return nil
+	}
+	s := string(d.next(int(n)))
+	return &s
+}
+
+func (d *protocolDecoder) bytes() []byte {
+	n := d.int32()
+	if n < 0 {
+		return nil
+	}
+	return d.next(int(n))
+}
+
+// arrayLength returns the length of an array, null arrays are empty
+func (d *protocolDecoder) arrayLength() int {
+	n := int(d.int32())
+	if n < 0 || d.err != nil {
+		return 0
+	}
+	return n
+}
+
+func (d *protocolDecoder) compactString() string {
+	n := int(d.uvarint()) - 1
+	if n < 0 {
+		return ""
+	}
+	return string(d.next(n))
+}
+
+func (d *protocolDecoder) compactNullableString() *string {
+	n := int(d.uvarint()) - 1
+	if n < 0 {
+		return nil
+	}
+	s := string(d.next(n))
+	return &s
+}
+
+// compactArrayLength returns the length of a compact array, null arrays are empty
+func (d *protocolDecoder) compactArrayLength() int {
+	n := int(d.uvarint()) - 1
+	if n < 0 || d.err != nil {
+		return 0
+	}
+	return n
+}
+
+func (d *protocolDecoder) compactInt32Array() []int32 {
+	values := []int32{}
+	for i := d.compactArrayLength(); i > 0; i-- {
+		values = append(values, d.int32())
+	}
+	return values
+}
+
+// skipTags skips the tagged fields, none of them are used
+func (d *protocolDecoder) skipTags() {
+	for i := d.uvarint(); i > 0 && d.err == nil; i-- {
+		d.uvarint()
+		d.next(int(d.uvarint()))
+	}
+}
+
+// kError returns the error of a response as a partition error carrying the message of the broker,
+// or nil for no error
+func kError(code int16, msg *string) error {
+	if sarama.KError(code) == sarama.ErrNoError {
+		return nil
+	}
+	return &sarama.TopicPartitionError{Err: sarama.KError(code), ErrMsg: msg}
+}
@@ -0,0 +1,263 @@
+package kafka_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"sync"
+	"testing"
+
+	"github.com/Shopify/sarama"
+	"github.com/izolight/kafkalib/format"
+	"github.com/izolight/kafkalib/kafka"
+)
+
+// testWriter encodes the primitive types of the kafka protocol for the responses of a testRawBroker
+type testWriter struct {
+	bytes.Buffer
+}
+
+func (w *testWriter) int8(v int8) *testWriter {
+	w.WriteByte(byte(v))
+	return w
+}
+
+func (w *testWriter) int16(v int16) *testWriter {
+	binary.Write(w, binary.BigEndian, v)
+	return w
+}
+
+func (w *testWriter) int32(v int32) *testWriter {
+	binary.Write(w, binary.BigEndian, v)
+	return w
+}
+
+func (w *testWriter) int64(v int64) *testWriter {
+	binary.Write(w, binary.BigEndian, v)
+	return w
+}
+
+func (w *testWriter) uvarint(v uint64) *testWriter {
+	var b [binary.MaxVarintLen64]byte
+	w.Write(b[:binary.PutUvarint(b[:], v)])
+	return w
+}
+
+func (w *testWriter) str(s string) *testWriter {
+	w.int16(int16(len(s)))
+	w.WriteString(s)
+	return w
+}
+
+// nullStr writes a nullable string, an empty string is written as null
+func (w *testWriter) nullStr(s string) *testWriter {
+	if len(s) == 0 {
+		return w.int16(-1)
+	}
+	return w.str(s)
+}
+
+func (w *testWriter) compactStr(s string) *testWriter {
+	w.uvarint(uint64(len(s)) + 1)
+	w.WriteString(s)
+	return w
+}
+
+// compactNullStr writes a compact nullable string, an empty string is written as null
+func (w *testWriter) compactNullStr(s string) *testWriter {
+	if len(s) == 0 {
+		return w.uvarint(0)
+	}
+	return w.compactStr(s)
+}
+
+func (w *testWriter) compactInt32s(values ...int32) *testWriter {
+	w.uvarint(uint64(len(values)) + 1)
+	for _, v := range values {
+		w.int32(v)
+	}
+	return w
+}
+
+func (w *testWriter) tags() *testWriter {
+	return w.uvarint(0)
+}
+
+// testRawBroker is a kafka broker for the requests sarama can't send. It answers ApiVersions with
+// versions and the other requests with the handler registered for their api key.
+type testRawBroker struct {
+	t        *testing.T
+	listener net.Listener
+	versions map[int16]int16
+	// flexible reports if an api version uses the request and response headers with tagged fields
+	flexible func(apiKey, version int16) bool
+
+	mu       sync.Mutex
+	handlers map[int16]func(version int16, body []byte) []byte
+	requests map[int16][][]byte
+}
+
+func newTestRawBroker(t *testing.T, versions map[int16]int16) *testRawBroker {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := &testRawBroker{
+		t:        t,
+		listener: l,
+		versions: versions,
+		flexible: func(apiKey, version int16) bool {
+			return apiKey == 45 || apiKey == 46 || (apiKey == 43 && version >= 2) || (apiKey == 35 && version >= 2)
+		},
+		handlers: map[int16]func(int16, []byte) []byte{},
+		requests: map[int16][][]byte{},
+	}
+	go b.serve()
+	return b
+}
+
+func (b *testRawBroker) Addr() string {
+	return b.listener.Addr().String()
+}
+
+func (b *testRawBroker) Close() {
+	b.listener.Close()
+}
+
+// Handle registers the handler for requests with apiKey, it returns the response body
+func (b *testRawBroker) Handle(apiKey int16, handler func(version int16, body []byte) []byte) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.handlers[apiKey] = handler
+}
+
+// Requests returns the bodies of the received requests with apiKey
+func (b *testRawBroker) Requests(apiKey int16) [][]byte {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.requests[apiKey]
+}
+
+func (b *testRawBroker) serve() {
+	for {
+		conn, err := b.listener.Accept()
+		if err != nil {
+			return
+		}
+		go b.handle(conn)
+	}
+}
+
+func (b *testRawBroker) handle(conn net.Conn) {
+	defer conn.Close()
+	for {
+		var size int32
+		if err := binary.Read(conn, binary.BigEndian, &size); err != nil {
+			return
+		}
+		frame := make([]byte, size)
+		if _, err := io.ReadFull(conn, frame); err != nil {
+			return
+		}
+		apiKey := int16(binary.BigEndian.Uint16(frame[0:]))
+		version := int16(binary.BigEndian.Uint16(frame[2:]))
+		correlationID := int32(binary.BigEndian.Uint32(frame[4:]))
+		clientIDLength := int16(binary.BigEndian.Uint16(frame[8:]))
+		body := frame[10:]
+		if clientIDLength > 0 {
+			body = body[clientIDLength:]
+		}
+		flexible := b.flexible(apiKey, version)
+		if flexible {
+			// no tagged fields are sent
+			body = body[1:]
+		}
+
+		response := &testWriter{}
+		response.int32(correlationID)
+		if flexible {
+			response.tags()
+		}
+		b.mu.Lock()
+		b.requests[apiKey] = append(b.requests[apiKey], body)
+		handler, ok := b.handlers[apiKey]
+		b.mu.Unlock()
+		switch {
+		case apiKey == 18:
+			response.int16(0).int32(int32(len(b.versions)))
+			for key, max := range b.versions {
+				response.int16(key).int16(0).int16(max)
+			}
+		case ok:
+			response.Write(handler(version, body))
+		default:
+			b.t.Errorf("No handler for api key %d", apiKey)
+			return
+		}
+		binary.Write(conn, binary.BigEndian, int32(response.Len()))
+		conn.Write(response.Bytes())
+	}
+}
+
+// configClient is a sarama.Client that only has a config
+type configClient struct {
+	sarama.Client
+	conf *sarama.Config
+}
+
+func (c configClient) Config() *sarama.Config {
+	return c.conf
+}
+
+// newRawConn returns a Conn on a testClient whose controller is broker
+func newRawConn(broker *testRawBroker) kafka.Conn {
+	client := NewTestClient()
+	client.(*testClient).brokerAddrs = map[int32]string{1: broker.Addr()}
+	return kafka.Conn{AdminClient: client}
+}
+
+func TestRawBroker_SASL(t *testing.T) {
+	broker := newTestRawBroker(t, map[int16]int16{17: 1, 18: 0, 36: 0, 46: 0})
+	defer broker.Close()
+	broker.Handle(17, func(version int16, body []byte) []byte {
+		return (&testWriter{}).int16(0).int32(1).str("PLAIN").Bytes()
+	})
+	broker.Handle(36, func(version int16, body []byte) []byte {
+		if !bytes.Equal(body[4:], []byte("\x00user\x00secret")) {
+			return (&testWriter{}).int16(int16(sarama.ErrSASLAuthenticationFailed)).str("invalid credentials").int32(0).Bytes()
+		}
+		return (&testWriter{}).int16(0).nullStr("").int32(0).Bytes()
+	})
+	broker.Handle(46, func(version int16, body []byte) []byte {
+		return (&testWriter{}).int32(0).int16(0).compactNullStr("").uvarint(1).tags().Bytes()
+	})
+
+	plan := &format.Reassignment{Partitions: []format.PartitionReassignment{
+		{Topic: "topicWithPartitions", Partition: 0, Current: []int32{1}, Proposed: []int32{2}},
+	}}
+	testCases := []struct {
+		password string
+		err      error
+	}{
+		{"secret", nil},
+		{"wrong", kafka.ErrAuthorizationDenied},
+	}
+	for _, tc := range testCases {
+		conf := sarama.NewConfig()
+		conf.Net.SASL.Enable = true
+		conf.Net.SASL.Mechanism = sarama.SASLTypePlaintext
+		conf.Net.SASL.User = "user"
+		conf.Net.SASL.Password = tc.password
+		c := newRawConn(broker)
+		c.Client = configClient{conf: conf}
+		_, err := c.GetReassignmentStatus(plan)
+		if tc.err == nil && err != nil {
+			t.Errorf("Expected no error but got %s", err)
+		}
+		if tc.err != nil && !errors.Is(err, tc.err) {
+			t.Errorf("Expected %s but got %v", tc.err, err)
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package kafka
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/izolight/kafkalib/format"
+)
+
+// PurgeOptions selects the records deleted by PurgeTopic, exactly one of Offsets, Before and All must be set
+type PurgeOptions struct {
+	// Offsets deletes the records before the given offset of each listed partition
+	Offsets map[int32]int64
+	// Before deletes the records with a timestamp before it from all partitions
+	Before time.Time
+	// All deletes all records currently in the topic
+	All bool
+	// DryRun only reports how many messages would be deleted
+	DryRun bool
+}
+
+// PurgeTopic deletes records from the beginning of the partitions of a topic, the topic itself is kept.
+// Partitions without records to delete are reported but not sent to the brokers.
+func (c Conn) PurgeTopic(topic string, options PurgeOptions) (*format.Purge, error) {
+	return c.PurgeTopicContext(context.Background(), topic, options)
+}
+
+// PurgeTopicContext is PurgeTopic with a context
+func (c Conn) PurgeTopicContext(ctx context.Context, topic string, options PurgeOptions) (*format.Purge, error) {
+	const op = "purging topic"
+	selected := 0
+	for _, set := range []bool{options.Offsets != nil, !options.Before.IsZero(), options.All} {
+		if set {
+			selected++
+		}
+	}
+	if selected != 1 {
+		return nil, &Error{Op: op, Resource: topic, Kind: ErrInvalidConfig, Err: fmt.Errorf("exactly one of offsets, before and all must be set")}
+	}
+
+	var purge *format.Purge
+	err := call(ctx, op, topic, func() error {
+		var err error
+		purge, err = c.planPurge(topic, options)
+		return err
+	})
+	if err != nil {
+		return nil, newError(op, topic, err)
+	}
+	if options.DryRun {
+		return purge, nil
+	}
+
+	offsets := map[int32]int64{}
+	for _, p := range purge.Partitions {
+		if p.Messages > 0 {
+			offsets[p.Partition] = p.DeleteBefore
+		}
+	}
+	if len(offsets) == 0 {
+		return purge, nil
+	}
+	err = call(ctx, op, topic, func() error {
+		return c.admin(ctx).DeleteRecords(topic, offsets)
+	})
+	if err != nil {
+		return nil, newError(op, topic, err)
+	}
+	return purge, nil
+}
+
+// planPurge resolves the offset to delete before for each partition
+func (c Conn) planPurge(topic string, options PurgeOptions) (*format.Purge, error) {
+	partitions, err := c.Client.Partitions(topic)
+	if err != nil {
+		return nil, err
+	}
+	if options.Offsets != nil {
+		known := map[int32]bool{}
+		for _, p := range partitions {
+			known[p] = true
+		}
+		partitions = make([]int32, 0, len(options.Offsets))
+		for p := range options.Offsets {
+			if !known[p] {
+				return nil, &Error{Op: "purging topic", Resource: topic, Kind: ErrInvalidConfig, Err: fmt.Errorf("partition %d doesn't exist", p)}
+			}
+			partitions = append(partitions, p)
+		}
+	}
+	// the client returns its cached slice, sort a copy
+	partitions = append([]int32{}, partitions...)
+	sort.Slice(partitions, func(i, j int) bool { return partitions[i] < partitions[j] })
+
+	purge := &format.Purge{Topic: topic, DryRun: options.DryRun}
+	for _, p := range partitions {
+		low, high, err := c.watermarks(topic, p)
+		if err != nil {
+			return nil, err
+		}
+		before := high
+		switch {
+		case options.Offsets != nil:
+			before = options.Offsets[p]
+			if before > high {
+				return nil, &Error{Op: "purging topic", Resource: topic, Kind: ErrInvalidConfig, Err: fmt.Errorf("offset %d of partition %d is after the high watermark %d", before, p, high)}
+			}
+		case !options.Before.IsZero():
+			// the offset of the first record at or after the timestamp, -1 if all records are older
+			offset, err := c.Client.GetOffset(topic, p, options.Before.UnixNano()/int64(time.Millisecond))
+			if err != nil {
+				return nil, err
+			}
+			if offset >= 0 {
+				before = offset
+			}
+		}
+		messages := before - low
+		if messages < 0 {
+			messages = 0
+		}
+		purge.Partitions = append(purge.Partitions, format.PartitionPurge{
+			Partition:     p,
+			LowWatermark:  low,
+			HighWatermark: high,
+			DeleteBefore:  before,
+			Messages:      messages,
+		})
+	}
+	return purge, nil
+}
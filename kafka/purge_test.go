@@ -0,0 +1,95 @@
+package kafka_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/izolight/kafkalib/kafka"
+)
+
+func TestPurgeTopic(t *testing.T) {
+	testCases := []struct {
+		name     string
+		topic    string
+		options  kafka.PurgeOptions
+		messages []int64
+		deleted  map[int32]int64
+		success  bool
+	}{
+		{"all", "topicWithPartitions", kafka.PurgeOptions{All: true}, []int64{10, 0, 50, 10}, map[int32]int64{0: 10, 2: 50, 3: 30}, true},
+		{"all dry run", "topicWithPartitions", kafka.PurgeOptions{All: true, DryRun: true}, []int64{10, 0, 50, 10}, nil, true},
+		{"offsets", "topicWithPartitions", kafka.PurgeOptions{Offsets: map[int32]int64{2: 20, 3: 10}}, []int64{20, 0}, map[int32]int64{2: 20}, true},
+		{"before", "simpleTopic", kafka.PurgeOptions{Before: testEpoch.Add(42 * time.Second)}, []int64{32}, map[int32]int64{0: 42}, true},
+		{"before everything", "simpleTopic", kafka.PurgeOptions{Before: testEpoch}, []int64{0}, nil, true},
+		{"after everything", "simpleTopic", kafka.PurgeOptions{Before: testEpoch.Add(time.Hour)}, []int64{90}, map[int32]int64{0: 100}, true},
+		{"nothing selected", "simpleTopic", kafka.PurgeOptions{}, nil, nil, false},
+		{"unknown partition", "simpleTopic", kafka.PurgeOptions{Offsets: map[int32]int64{1: 10}}, nil, nil, false},
+		{"offset after high watermark", "simpleTopic", kafka.PurgeOptions{Offsets: map[int32]int64{0: 101}}, nil, nil, false},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			admin := NewTestClient()
+			c := kafka.Conn{
+				AdminClient: admin,
+				Client:      newTestSaramaClient(),
+			}
+			purge, err := c.PurgeTopic(tc.topic, tc.options)
+			if !tc.success {
+				if !errors.Is(err, kafka.ErrInvalidConfig) {
+					t.Errorf("Expected ErrInvalidConfig but got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			var messages []int64
+			for _, p := range purge.Partitions {
+				messages = append(messages, p.Messages)
+			}
+			if !reflect.DeepEqual(messages, tc.messages) {
+				t.Errorf("Expected messages %v but got %v", tc.messages, messages)
+			}
+			if deleted := admin.(*testClient).deletedRecords[tc.topic]; !reflect.DeepEqual(deleted, tc.deleted) {
+				t.Errorf("Expected deleted records %v but got %v", tc.deleted, deleted)
+			}
+		})
+	}
+}
+
+func TestNewConn_DeleteRecords(t *testing.T) {
+	broker := newMockCluster(t)
+	defer broker.Close()
+	c, err := kafka.NewConn(&kafka.Config{BrokerList: []string{broker.Addr()}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	testCases := []struct {
+		kerr    sarama.KError
+		success bool
+	}{
+		{sarama.ErrNoError, true},
+		{sarama.ErrOffsetOutOfRange, false},
+	}
+	for _, tc := range testCases {
+		broker.SetHandlerByMap(map[string]sarama.MockResponse{
+			"DeleteRecordsRequest": sarama.NewMockWrapper(&sarama.DeleteRecordsResponse{
+				Topics: map[string]*sarama.DeleteRecordsResponseTopic{
+					"simpleTopic": {Partitions: map[int32]*sarama.DeleteRecordsResponsePartition{0: {LowWatermark: 5, Err: tc.kerr}}},
+				},
+			}),
+		})
+		err := c.AdminClient.DeleteRecords("simpleTopic", map[int32]int64{0: 5})
+		if tc.success && err != nil {
+			t.Errorf("Expected no error but got %s", err)
+		}
+		if !tc.success && err == nil {
+			t.Errorf("Expected error %s", tc.kerr)
+		}
+	}
+}
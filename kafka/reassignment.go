@@ -0,0 +1,477 @@
+package kafka
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/izolight/kafkalib/format"
+)
+
+// PlanReplicationFactor plans changing the replication factor of a topic with as few moves as possible.
+// When increasing, the current replicas are kept and new ones are added on brokers in racks the
+// partition doesn't use yet, preferring the brokers with the fewest replicas of the topic.
+// When decreasing, the last replicas are removed so the preferred leader stays.
+func (c Conn) PlanReplicationFactor(topic string, replicationFactor int) (*format.Reassignment, error) {
+	return c.PlanReplicationFactorContext(context.Background(), topic, replicationFactor)
+}
+
+// PlanReplicationFactorContext is PlanReplicationFactor with a context
+func (c Conn) PlanReplicationFactorContext(ctx context.Context, topic string, replicationFactor int) (*format.Reassignment, error) {
+	const op = "planning replication factor of"
+	current, err := c.DescribeTopicContext(ctx, topic)
+	if err != nil {
+		return nil, newError(op, topic, err)
+	}
+	brokers, err := c.brokers(ctx)
+	if err != nil {
+		return nil, newError(op, topic, err)
+	}
+	if replicationFactor <= 0 || replicationFactor > len(brokers) {
+		return nil, &Error{Op: op, Resource: topic, Kind: ErrInvalidConfig,
+			Err: fmt.Errorf("replication factor must be between 1 and the %d available brokers", len(brokers))}
+	}
+	return planReplicationFactor(current, brokers, replicationFactor), nil
+}
+
+// planReplicationFactor changes the number of replicas of each partition of topic
+func planReplicationFactor(topic *format.TopicDescription, brokers []BrokerInfo, replicationFactor int) *format.Reassignment {
+	rackOf := map[int32]string{}
+	load := map[int32]int{}
+	for _, b := range brokers {
+		rackOf[b.ID] = b.Rack
+		load[b.ID] = 0
+	}
+	for _, p := range topic.Partitions {
+		for _, id := range p.Replicas {
+			load[id]++
+		}
+	}
+	sorted := append([]BrokerInfo{}, brokers...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].ID < sorted[j].ID })
+
+	plan := &format.Reassignment{}
+	for _, p := range topic.Partitions {
+		proposed := append([]int32{}, p.Replicas...)
+		if len(proposed) > replicationFactor {
+			for _, id := range proposed[replicationFactor:] {
+				load[id]--
+			}
+			proposed = proposed[:replicationFactor]
+		}
+		for len(proposed) < replicationFactor {
+			used := map[int32]bool{}
+			usedRacks := map[string]bool{}
+			for _, id := range proposed {
+				used[id] = true
+				usedRacks[rackOf[id]] = true
+			}
+			best := int32(-1)
+			bestNewRack := false
+			for _, b := range sorted {
+				if used[b.ID] {
+					continue
+				}
+				newRack := !usedRacks[b.Rack]
+				if best == -1 || (newRack && !bestNewRack) || (newRack == bestNewRack && load[b.ID] < load[best]) {
+					best = b.ID
+					bestNewRack = newRack
+				}
+			}
+			proposed = append(proposed, best)
+			load[best]++
+		}
+		plan.Partitions = append(plan.Partitions, format.PartitionReassignment{
+			Topic: topic.Name, Partition: p.ID, Current: p.Replicas, Proposed: proposed,
+		})
+	}
+	return plan
+}
+
+// PlanBrokerMove plans moving all replicas of the topics on broker from to broker to,
+// e.g. for replacing a broker. The position of the replica, and so the preferred leader, is kept.
+func (c Conn) PlanBrokerMove(topics []string, from, to int32) (*format.Reassignment, error) {
+	return c.PlanBrokerMoveContext(context.Background(), topics, from, to)
+}
+
+// PlanBrokerMoveContext is PlanBrokerMove with a context
+func (c Conn) PlanBrokerMoveContext(ctx context.Context, topics []string, from, to int32) (*format.Reassignment, error) {
+	const op = "planning broker move"
+	resource := fmt.Sprintf("%d->%d", from, to)
+	brokers, err := c.brokers(ctx)
+	if err != nil {
+		return nil, newError(op, resource, err)
+	}
+	known := false
+	for _, b := range brokers {
+		known = known || b.ID == to
+	}
+	if !known {
+		return nil, &Error{Op: op, Resource: resource, Kind: ErrInvalidConfig, Err: fmt.Errorf("broker %d is not part of the cluster", to)}
+	}
+
+	plan := &format.Reassignment{}
+	for _, topic := range topics {
+		current, err := c.DescribeTopicContext(ctx, topic)
+		if err != nil {
+			return nil, newError(op, resource, err)
+		}
+		for _, p := range current.Partitions {
+			proposed := append([]int32{}, p.Replicas...)
+			moved := false
+			for i, id := range proposed {
+				if id == from {
+					proposed[i] = to
+					moved = true
+				}
+			}
+			for _, id := range p.Replicas {
+				if moved && id == to {
+					return nil, &Error{Op: op, Resource: resource, Kind: ErrInvalidConfig,
+						Err: fmt.Errorf("partition %s-%d already has a replica on broker %d", topic, p.ID, to)}
+				}
+			}
+			plan.Partitions = append(plan.Partitions, format.PartitionReassignment{
+				Topic: topic, Partition: p.ID, Current: p.Replicas, Proposed: proposed,
+			})
+		}
+	}
+	return plan, nil
+}
+
+// ExecuteReassignment checks that plan still matches the cluster, writes the rollback plan in the
+// kafka-reassign-partitions format to rollback and starts moving the replicas of the changed partitions.
+// It returns once the controller accepted the reassignment, GetReassignmentStatus monitors it.
+// Reassignments need kafka 2.4, older clusters return an error matching ErrUnsupported before the
+// rollback plan is written. PlanFromReassignmentJSON turns the rollback plan into a plan to execute.
+func (c Conn) ExecuteReassignment(plan *format.Reassignment, rollback io.Writer) error {
+	return c.ExecuteReassignmentContext(context.Background(), plan, rollback)
+}
+
+// ExecuteReassignmentContext is ExecuteReassignment with a context
+func (c Conn) ExecuteReassignmentContext(ctx context.Context, plan *format.Reassignment, rollback io.Writer) error {
+	const op = "executing reassignment"
+	if rollback == nil {
+		return &Error{Op: op, Kind: ErrInvalidConfig, Err: fmt.Errorf("a writer for the rollback plan is required")}
+	}
+	descriptions, err := c.describePlanTopics(ctx, plan)
+	if err != nil {
+		return newError(op, "", err)
+	}
+	for _, p := range plan.Partitions {
+		partition, ok := findPartition(descriptions[p.Topic], p.Partition)
+		if ok && equalReplicas(partition.Replicas, p.Current) {
+			continue
+		}
+		return &Error{Op: op, Resource: fmt.Sprintf("%s-%d", p.Topic, p.Partition), Kind: ErrInvalidConfig,
+			Err: fmt.Errorf("plan is outdated, the replicas are %v instead of %v", partition.Replicas, p.Current)}
+	}
+
+	// each call opens its own connection to the controller, so an abandoned call still closes it
+	var supported bool
+	err = call(ctx, op, "", func() error {
+		controller, err := c.rawController(ctx)
+		if err != nil {
+			return err
+		}
+		defer controller.Close()
+		_, supported = controller.version(apiKeyAlterPartitionReassignments, 0)
+		return nil
+	})
+	if err != nil {
+		return newError(op, "", err)
+	}
+	if !supported {
+		return &Error{Op: op, Kind: ErrUnsupported, Err: fmt.Errorf("reassigning partitions needs kafka %s", v2_4_0_0)}
+	}
+	if err := format.Format(plan.Rollback(), format.Config{Output: rollback, Format: "json"}); err != nil {
+		return newError(op, "", fmt.Errorf("Error writing rollback plan: %s", err))
+	}
+	var changed []format.PartitionReassignment
+	for _, p := range plan.Partitions {
+		if p.Changed() {
+			changed = append(changed, p)
+		}
+	}
+	if len(changed) == 0 {
+		return nil
+	}
+	return newError(op, "", call(ctx, op, "", func() error {
+		controller, err := c.rawController(ctx)
+		if err != nil {
+			return err
+		}
+		defer controller.Close()
+		return alterPartitionReassignments(controller, changed, c.saramaConfig().Admin.Timeout)
+	}))
+}
+
+// alterPartitionReassignments sends the proposed replicas of partitions to the controller and returns
+// the first error of a partition
+func alterPartitionReassignments(controller *rawBroker, partitions []format.PartitionReassignment, timeout time.Duration) error {
+	const op = "executing reassignment"
+	byTopic := map[string][]format.PartitionReassignment{}
+	var topics []string
+	for _, p := range partitions {
+		if _, ok := byTopic[p.Topic]; !ok {
+			topics = append(topics, p.Topic)
+		}
+		byTopic[p.Topic] = append(byTopic[p.Topic], p)
+	}
+	e := &protocolEncoder{}
+	e.int32(int32(timeout / time.Millisecond))
+	e.compactArrayLength(len(topics))
+	for _, topic := range topics {
+		e.compactString(topic)
+		e.compactArrayLength(len(byTopic[topic]))
+		for _, p := range byTopic[topic] {
+			e.int32(p.Partition)
+			e.compactInt32Array(p.Proposed)
+			e.tags()
+		}
+		e.tags()
+	}
+	e.tags()
+
+	d, err := controller.request(apiKeyAlterPartitionReassignments, 0, true, e.buf.Bytes())
+	if err != nil {
+		return newError(op, "", err)
+	}
+	d.int32() // throttle time
+	if err := kError(d.int16(), d.compactNullableString()); err != nil {
+		return newError(op, "", err)
+	}
+	for i := d.compactArrayLength(); i > 0; i-- {
+		topic := d.compactString()
+		for j := d.compactArrayLength(); j > 0; j-- {
+			partition := d.int32()
+			if err := kError(d.int16(), d.compactNullableString()); err != nil {
+				return newError(op, fmt.Sprintf("%s-%d", topic, partition), err)
+			}
+			d.skipTags()
+		}
+		d.skipTags()
+	}
+	return newError(op, "", d.err)
+}
+
+// listPartitionReassignments returns the target replicas of the ongoing reassignments of partitions by
+// topic and partition
+func listPartitionReassignments(controller *rawBroker, partitions []format.PartitionReassignment, timeout time.Duration) (map[string]map[int32][]int32, error) {
+	byTopic := map[string][]int32{}
+	var topics []string
+	for _, p := range partitions {
+		if _, ok := byTopic[p.Topic]; !ok {
+			topics = append(topics, p.Topic)
+		}
+		byTopic[p.Topic] = append(byTopic[p.Topic], p.Partition)
+	}
+	e := &protocolEncoder{}
+	e.int32(int32(timeout / time.Millisecond))
+	e.compactArrayLength(len(topics))
+	for _, topic := range topics {
+		e.compactString(topic)
+		e.compactInt32Array(byTopic[topic])
+		e.tags()
+	}
+	e.tags()
+
+	d, err := controller.request(apiKeyListPartitionReassignments, 0, true, e.buf.Bytes())
+	if err != nil {
+		return nil, err
+	}
+	d.int32() // throttle time
+	if err := kError(d.int16(), d.compactNullableString()); err != nil {
+		return nil, err
+	}
+	ongoing := map[string]map[int32][]int32{}
+	for i := d.compactArrayLength(); i > 0; i-- {
+		topic := d.compactString()
+		ongoing[topic] = map[int32][]int32{}
+		for j := d.compactArrayLength(); j > 0; j-- {
+			partition := d.int32()
+			replicas := d.compactInt32Array()
+			d.compactInt32Array() // adding replicas, they are part of the target
+			removing := d.compactInt32Array()
+			d.skipTags()
+			// the replicas are the union of the target and the removed ones
+			var target []int32
+			for _, id := range replicas {
+				if !containsBroker(removing, id) {
+					target = append(target, id)
+				}
+			}
+			ongoing[topic][partition] = target
+		}
+		d.skipTags()
+	}
+	return ongoing, d.err
+}
+
+// PlanFromReassignmentJSON turns a file in the kafka-reassign-partitions format, like the rollback plan
+// written by ExecuteReassignment, into a plan from the current replicas to the ones in the file
+func (c Conn) PlanFromReassignmentJSON(file *format.ReassignmentJSON) (*format.Reassignment, error) {
+	return c.PlanFromReassignmentJSONContext(context.Background(), file)
+}
+
+// PlanFromReassignmentJSONContext is PlanFromReassignmentJSON with a context
+func (c Conn) PlanFromReassignmentJSONContext(ctx context.Context, file *format.ReassignmentJSON) (*format.Reassignment, error) {
+	const op = "planning reassignment"
+	plan := &format.Reassignment{}
+	for _, e := range file.Partitions {
+		plan.Partitions = append(plan.Partitions, format.PartitionReassignment{Topic: e.Topic, Partition: e.Partition, Proposed: e.Replicas})
+	}
+	descriptions, err := c.describePlanTopics(ctx, plan)
+	if err != nil {
+		return nil, newError(op, "", err)
+	}
+	for i, p := range plan.Partitions {
+		partition, ok := findPartition(descriptions[p.Topic], p.Partition)
+		if !ok {
+			return nil, &Error{Op: op, Resource: fmt.Sprintf("%s-%d", p.Topic, p.Partition), Kind: ErrInvalidConfig,
+				Err: fmt.Errorf("partition doesn't exist")}
+		}
+		plan.Partitions[i].Current = partition.Replicas
+	}
+	plan.Sort()
+	return plan, nil
+}
+
+// GetReassignmentStatus compares the replicas of the partitions in plan with their proposed replicas.
+// On kafka 2.4 and newer the controller reports which partitions are still being reassigned.
+func (c Conn) GetReassignmentStatus(plan *format.Reassignment) (*format.ReassignmentStatus, error) {
+	return c.GetReassignmentStatusContext(context.Background(), plan)
+}
+
+// GetReassignmentStatusContext is GetReassignmentStatus with a context
+func (c Conn) GetReassignmentStatusContext(ctx context.Context, plan *format.Reassignment) (*format.ReassignmentStatus, error) {
+	const op = "getting reassignment status"
+	descriptions, err := c.describePlanTopics(ctx, plan)
+	if err != nil {
+		return nil, newError(op, "", err)
+	}
+	var changed []format.PartitionReassignment
+	for _, p := range plan.Partitions {
+		if p.Changed() {
+			changed = append(changed, p)
+		}
+	}
+	var ongoing map[string]map[int32][]int32
+	if len(changed) != 0 {
+		err = call(ctx, op, "", func() error {
+			controller, err := c.rawController(ctx)
+			if err != nil {
+				return err
+			}
+			defer controller.Close()
+			if _, ok := controller.version(apiKeyListPartitionReassignments, 0); !ok {
+				return nil
+			}
+			ongoing, err = listPartitionReassignments(controller, changed, c.saramaConfig().Admin.Timeout)
+			return err
+		})
+		if err != nil {
+			return nil, newError(op, "", err)
+		}
+	}
+
+	status := &format.ReassignmentStatus{}
+	for _, p := range changed {
+		partition, _ := findPartition(descriptions[p.Topic], p.Partition)
+		state := reassignmentState(partition, p.Proposed)
+		if ongoing != nil {
+			state = listedReassignmentState(partition, p.Proposed, ongoing[p.Topic])
+		}
+		status.Partitions = append(status.Partitions, format.PartitionReassignmentStatus{
+			Topic:     p.Topic,
+			Partition: p.Partition,
+			Replicas:  partition.Replicas,
+			ISR:       partition.ISR,
+			Target:    p.Proposed,
+			State:     state,
+		})
+	}
+	return status, nil
+}
+
+// listedReassignmentState derives the state from the reassignments listed by the controller, a partition
+// that is not listed either reached its target or was never reassigned to it
+func listedReassignmentState(partition format.PartitionDescription, target []int32, ongoing map[int32][]int32) string {
+	if listed, ok := ongoing[partition.ID]; ok {
+		if equalReplicas(listed, target) {
+			return format.ReassignmentInProgress
+		}
+		return format.ReassignmentPending
+	}
+	if equalReplicas(partition.Replicas, target) {
+		return format.ReassignmentCompleted
+	}
+	return format.ReassignmentPending
+}
+
+// reassignmentState derives the state from the replicas, kafka first adds the target replicas
+// and removes the others once they joined the ISR
+func reassignmentState(partition format.PartitionDescription, target []int32) string {
+	replicas := map[int32]bool{}
+	for _, id := range partition.Replicas {
+		replicas[id] = true
+	}
+	isr := map[int32]bool{}
+	for _, id := range partition.ISR {
+		isr[id] = true
+	}
+	inSync := true
+	for _, id := range target {
+		if !replicas[id] {
+			return format.ReassignmentPending
+		}
+		inSync = inSync && isr[id]
+	}
+	if len(partition.Replicas) == len(target) && inSync {
+		return format.ReassignmentCompleted
+	}
+	return format.ReassignmentInProgress
+}
+
+// describePlanTopics describes every topic of plan once
+func (c Conn) describePlanTopics(ctx context.Context, plan *format.Reassignment) (map[string]*format.TopicDescription, error) {
+	descriptions := map[string]*format.TopicDescription{}
+	for _, p := range plan.Partitions {
+		if _, ok := descriptions[p.Topic]; ok {
+			continue
+		}
+		description, err := c.DescribeTopicContext(ctx, p.Topic)
+		if err != nil {
+			return nil, err
+		}
+		descriptions[p.Topic] = description
+	}
+	return descriptions, nil
+}
+
+// findPartition returns the partition with id from description
+func findPartition(description *format.TopicDescription, id int32) (format.PartitionDescription, bool) {
+	if description != nil {
+		for _, p := range description.Partitions {
+			if p.ID == id {
+				return p, true
+			}
+		}
+	}
+	return format.PartitionDescription{}, false
+}
+
+// equalReplicas reports if a and b contain the same brokers in the same order
+func equalReplicas(a, b []int32) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,232 @@
+package kafka_test
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/Shopify/sarama"
+	"github.com/izolight/kafkalib/format"
+	"github.com/izolight/kafkalib/kafka"
+)
+
+// proposed returns the proposed replicas of each partition of plan
+func proposed(plan *format.Reassignment) [][]int32 {
+	var replicas [][]int32
+	for _, p := range plan.Partitions {
+		replicas = append(replicas, p.Proposed)
+	}
+	return replicas
+}
+
+func TestPlanReplicationFactor(t *testing.T) {
+	testCases := []struct {
+		topic             string
+		replicationFactor int
+		expected          [][]int32
+		success           bool
+	}{
+		{"topicWithPartitions", 2, [][]int32{{1, 2}, {2, 3}, {3, 1}, {1, 2}}, true},
+		{"topicWithPartitionsAndReplicas", 1, [][]int32{{1}, {2}, {3}, {1}}, true},
+		{"topicWithPartitions", 4, nil, false},
+		{"topicWithPartitions", 0, nil, false},
+	}
+	for _, tc := range testCases {
+		c := kafka.Conn{
+			AdminClient: NewTestClient(),
+		}
+		plan, err := c.PlanReplicationFactor(tc.topic, tc.replicationFactor)
+		if !tc.success {
+			if !errors.Is(err, kafka.ErrInvalidConfig) {
+				t.Errorf("Expected ErrInvalidConfig but got %v", err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := proposed(plan); !reflect.DeepEqual(got, tc.expected) {
+			t.Errorf("Expected %v but got %v", tc.expected, got)
+		}
+	}
+}
+
+func TestPlanBrokerMove(t *testing.T) {
+	c := kafka.Conn{
+		AdminClient: NewTestClient(),
+	}
+	plan, err := c.PlanBrokerMove([]string{"topicWithPartitions"}, 1, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := [][]int32{{2}, {2}, {3}, {2}}
+	if got := proposed(plan); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected %v but got %v", expected, got)
+	}
+
+	_, err = c.PlanBrokerMove([]string{"topicWithPartitionsAndReplicas"}, 1, 2)
+	if !errors.Is(err, kafka.ErrInvalidConfig) {
+		t.Errorf("Expected ErrInvalidConfig for an existing replica but got %v", err)
+	}
+	_, err = c.PlanBrokerMove([]string{"topicWithPartitions"}, 1, 4)
+	if !errors.Is(err, kafka.ErrInvalidConfig) {
+		t.Errorf("Expected ErrInvalidConfig for an unknown broker but got %v", err)
+	}
+}
+
+func TestPlanFromReassignmentJSON(t *testing.T) {
+	c := kafka.Conn{
+		AdminClient: NewTestClient(),
+	}
+	plan, err := c.PlanReplicationFactor("topicWithPartitions", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the rollback plan of the increase, as ExecuteReassignment writes it
+	output := new(bytes.Buffer)
+	format.Format(plan.Rollback(), format.Config{Output: output, Format: "json"})
+	file, err := format.ParseReassignmentJSON(output.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.Partitions[0].Replicas = []int32{2}
+	rollback, err := c.PlanFromReassignmentJSON(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []format.PartitionReassignment{
+		{Topic: "topicWithPartitions", Partition: 0, Current: []int32{1}, Proposed: []int32{2}},
+		{Topic: "topicWithPartitions", Partition: 1, Current: []int32{2}, Proposed: []int32{2}},
+		{Topic: "topicWithPartitions", Partition: 2, Current: []int32{3}, Proposed: []int32{3}},
+		{Topic: "topicWithPartitions", Partition: 3, Current: []int32{1}, Proposed: []int32{1}},
+	}
+	if !reflect.DeepEqual(rollback.Partitions, expected) {
+		t.Errorf("Expected %+v but got %+v", expected, rollback.Partitions)
+	}
+
+	file.Partitions[0].Partition = 9
+	if _, err := c.PlanFromReassignmentJSON(file); !errors.Is(err, kafka.ErrInvalidConfig) {
+		t.Errorf("Expected ErrInvalidConfig but got %v", err)
+	}
+}
+
+func TestExecuteReassignment(t *testing.T) {
+	broker := newTestRawBroker(t, map[int16]int16{18: 3, 45: 0})
+	defer broker.Close()
+	var partitionErr sarama.KError
+	broker.Handle(45, func(version int16, body []byte) []byte {
+		w := (&testWriter{}).int32(0).int16(0).compactNullStr("")
+		w.uvarint(2).compactStr("topicWithPartitions").uvarint(3)
+		w.int32(0).int16(int16(partitionErr)).compactNullStr("").tags()
+		w.int32(3).int16(0).compactNullStr("").tags()
+		return w.tags().tags().Bytes()
+	})
+	c := newRawConn(broker)
+	plan, err := c.PlanBrokerMove([]string{"topicWithPartitions"}, 1, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rollback := new(bytes.Buffer)
+	if err := c.ExecuteReassignment(plan, rollback); err != nil {
+		t.Fatal(err)
+	}
+	expected := `{"version":1,"partitions":[{"topic":"topicWithPartitions","partition":0,"replicas":[1]},{"topic":"topicWithPartitions","partition":3,"replicas":[1]}]}`
+	if got := strings.TrimSuffix(rollback.String(), "\n"); got != expected {
+		t.Errorf("Unexpected rollback plan:\nGot:\t%s\nWant:\t%s", got, expected)
+	}
+	request := (&testWriter{}).int32(3000).uvarint(2).compactStr("topicWithPartitions").uvarint(3).
+		int32(0).compactInt32s(2).tags().int32(3).compactInt32s(2).tags().tags().tags()
+	if requests := broker.Requests(45); len(requests) != 1 || !bytes.Equal(requests[0], request.Bytes()) {
+		t.Errorf("Unexpected reassignment requests %v, expected %v", requests, request.Bytes())
+	}
+
+	partitionErr = sarama.ErrInvalidReplicaAssignment
+	err = c.ExecuteReassignment(plan, new(bytes.Buffer))
+	if !errors.Is(err, kafka.ErrInvalidConfig) || !strings.Contains(err.Error(), "topicWithPartitions-0") {
+		t.Errorf("Expected the error of partition 0 but got %v", err)
+	}
+
+	plan.Partitions[0].Current = []int32{3}
+	rollback.Reset()
+	err = c.ExecuteReassignment(plan, rollback)
+	if !errors.Is(err, kafka.ErrInvalidConfig) || rollback.Len() != 0 {
+		t.Errorf("Expected an outdated plan to fail without rollback but got %v", err)
+	}
+}
+
+func TestExecuteReassignment_Unsupported(t *testing.T) {
+	broker := newTestRawBroker(t, map[int16]int16{18: 2, 43: 0})
+	defer broker.Close()
+	c := newRawConn(broker)
+	plan, err := c.PlanBrokerMove([]string{"topicWithPartitions"}, 1, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rollback := new(bytes.Buffer)
+	err = c.ExecuteReassignment(plan, rollback)
+	if !errors.Is(err, kafka.ErrUnsupported) || rollback.Len() != 0 {
+		t.Errorf("Expected ErrUnsupported without rollback but got %v", err)
+	}
+}
+
+func TestGetReassignmentStatus(t *testing.T) {
+	testCases := []struct {
+		name     string
+		versions map[int16]int16
+		// states after applying the first two partitions
+		states []string
+	}{
+		{"from metadata", map[int16]int16{18: 2},
+			[]string{format.ReassignmentCompleted, format.ReassignmentInProgress, format.ReassignmentPending, format.ReassignmentPending}},
+		{"listed by the controller", map[int16]int16{18: 3, 46: 0},
+			[]string{format.ReassignmentCompleted, format.ReassignmentInProgress, format.ReassignmentPending, format.ReassignmentPending}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			broker := newTestRawBroker(t, tc.versions)
+			defer broker.Close()
+			ongoing := false
+			broker.Handle(46, func(version int16, body []byte) []byte {
+				w := (&testWriter{}).int32(0).int16(0).compactNullStr("")
+				if !ongoing {
+					return w.uvarint(1).tags().Bytes()
+				}
+				// partition 1 moves from 2 to 2, 3
+				w.uvarint(2).compactStr("topicWithPartitions").uvarint(2)
+				w.int32(1).compactInt32s(2, 3).compactInt32s(3).compactInt32s().tags()
+				return w.tags().tags().Bytes()
+			})
+			c := newRawConn(broker)
+			plan, err := c.PlanReplicationFactor("topicWithPartitions", 2)
+			if err != nil {
+				t.Fatal(err)
+			}
+			status, err := c.GetReassignmentStatus(plan)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if status.Done() || status.Partitions[0].State != format.ReassignmentPending {
+				t.Errorf("Expected the reassignment to be pending but got %+v", status.Partitions[0])
+			}
+
+			// partition 0 is done, partition 1 is still copying to broker 3
+			fake := c.AdminClient.(*testClient)
+			detail := fake.topics["topicWithPartitions"]
+			detail.ReplicaAssignment = map[int32][]int32{0: {1, 2}, 1: {2, 3, 1}}
+			fake.topics["topicWithPartitions"] = detail
+			ongoing = true
+			status, err = c.GetReassignmentStatus(plan)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for i, p := range status.Partitions {
+				if p.State != tc.states[i] {
+					t.Errorf("Expected partition %d to be %s but got %s", p.Partition, tc.states[i], p.State)
+				}
+			}
+		})
+	}
+}
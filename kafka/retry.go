@@ -0,0 +1,325 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"time"
+
+	"github.com/Shopify/sarama"
+	log "github.com/sirupsen/logrus"
+)
+
+// RetryPolicy defines how admin calls are retried on transient errors
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts including the first one
+	MaxAttempts    int
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	// Multiplier is applied to the backoff after every attempt
+	Multiplier float64
+	// Jitter randomizes each backoff by up to this fraction, e.g. 0.2 for +-20%
+	Jitter float64
+	// Retryable lists the error codes that are retried, DefaultRetryableErrors if it is nil
+	Retryable []sarama.KError
+}
+
+// DefaultRetryableErrors are the errors returned while leadership moves, e.g. during rolling restarts
+var DefaultRetryableErrors = []sarama.KError{
+	sarama.ErrNotController,
+	sarama.ErrRequestTimedOut,
+	sarama.ErrLeaderNotAvailable,
+	sarama.ErrNotLeaderForPartition,
+	sarama.ErrBrokerNotAvailable,
+	sarama.ErrNetworkException,
+	sarama.ErrConsumerCoordinatorNotAvailable,
+	sarama.ErrNotCoordinatorForConsumer,
+}
+
+// DefaultRetryPolicy returns a policy with 5 attempts and backoff from 100ms up to 5s
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts:    5,
+		InitialBackoff: 100 * time.Millisecond,
+		MaxBackoff:     5 * time.Second,
+		Multiplier:     2,
+		Jitter:         0.2,
+	}
+}
+
+// isRetryable reports if err is one of the retryable error codes of the policy
+func (p RetryPolicy) isRetryable(err error) bool {
+	kerr, ok := kErrorOf(err)
+	if !ok {
+		return false
+	}
+	retryable := p.Retryable
+	if retryable == nil {
+		retryable = DefaultRetryableErrors
+	}
+	for _, r := range retryable {
+		if kerr == r {
+			return true
+		}
+	}
+	return false
+}
+
+// backoff returns the wait time before the given retry, starting at 1
+func (p RetryPolicy) backoff(retry int) time.Duration {
+	d := float64(p.InitialBackoff)
+	multiplier := p.Multiplier
+	if multiplier < 1 {
+		multiplier = 1
+	}
+	for i := 1; i < retry; i++ {
+		d *= multiplier
+	}
+	if p.MaxBackoff > 0 && d > float64(p.MaxBackoff) {
+		d = float64(p.MaxBackoff)
+	}
+	if p.Jitter > 0 {
+		d += d * p.Jitter * (2*rand.Float64() - 1)
+	}
+	return time.Duration(d)
+}
+
+// kErrorOf extracts the kafka error code from err
+func kErrorOf(err error) (sarama.KError, bool) {
+	switch e := err.(type) {
+	case *sarama.TopicError:
+		return e.Err, true
+	case *sarama.TopicPartitionError:
+		return e.Err, true
+	}
+	var kerr sarama.KError
+	if errors.As(err, &kerr) {
+		return kerr, true
+	}
+	return sarama.ErrNoError, false
+}
+
+// retryingAdmin retries the calls of a sarama.ClusterAdmin according to a RetryPolicy
+type retryingAdmin struct {
+	sarama.ClusterAdmin
+	// client is refreshed before retrying errors caused by stale metadata, it may be nil
+	client sarama.Client
+	policy RetryPolicy
+	// ctx stops the retries, it is set by withContext
+	ctx context.Context
+}
+
+// NewRetryingAdmin wraps admin so calls failing with a retryable error are retried according to policy.
+// Create and delete calls whose retry reports that the previous attempt already succeeded
+// on the server are treated as successful. If client is the client used by admin its metadata is
+// refreshed before retrying ErrNotController and leadership errors, so the retry goes to the new
+// controller or leader.
+func NewRetryingAdmin(admin sarama.ClusterAdmin, client sarama.Client, policy RetryPolicy) sarama.ClusterAdmin {
+	return &retryingAdmin{ClusterAdmin: admin, client: client, policy: policy, ctx: context.Background()}
+}
+
+// withContext returns a copy of the admin whose retries stop when ctx is done
+func (r *retryingAdmin) withContext(ctx context.Context) sarama.ClusterAdmin {
+	bound := *r
+	bound.ctx = ctx
+	return &bound
+}
+
+// admin returns the AdminClient of the connection, bound to ctx if it supports it
+func (c Conn) admin(ctx context.Context) sarama.ClusterAdmin {
+	if a, ok := c.AdminClient.(interface {
+		withContext(ctx context.Context) sarama.ClusterAdmin
+	}); ok {
+		return a.withContext(ctx)
+	}
+	return c.AdminClient
+}
+
+// do runs f until it succeeds, fails with a non retryable error, runs out of attempts or ctx is done.
+// succeeded is consulted after a failed retry to detect that an earlier attempt took effect.
+func (r *retryingAdmin) do(ctx context.Context, op string, f func() error, succeeded func(err error) bool) error {
+	attempts := r.policy.MaxAttempts
+	if attempts < 1 {
+		attempts = 1
+	}
+	var err error
+	for attempt := 1; attempt <= attempts; attempt++ {
+		err = f()
+		if err == nil {
+			return nil
+		}
+		if attempt > 1 && succeeded != nil && succeeded(err) {
+			log.Infof("%s: previous attempt succeeded on the server, ignoring %s", op, err)
+			return nil
+		}
+		if !r.policy.isRetryable(err) || attempt == attempts {
+			return err
+		}
+		wait := r.policy.backoff(attempt)
+		log.Warnf("%s failed (attempt %d/%d), retrying in %s: %s", op, attempt, attempts, wait, err)
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+		r.refresh(err)
+	}
+	return err
+}
+
+// refresh updates the metadata of the client if err means that it points to the wrong broker
+func (r *retryingAdmin) refresh(err error) {
+	if r.client == nil {
+		return
+	}
+	kerr, _ := kErrorOf(err)
+	switch kerr {
+	case sarama.ErrNotController, sarama.ErrNotLeaderForPartition, sarama.ErrLeaderNotAvailable:
+		// the metadata response carries the controller id as well as the partition leaders
+		if rErr := r.client.RefreshMetadata(); rErr != nil {
+			log.Debugf("Error refreshing metadata: %s", rErr)
+		}
+	}
+}
+
+func isKError(target sarama.KError) func(err error) bool {
+	return func(err error) bool {
+		kerr, ok := kErrorOf(err)
+		return ok && kerr == target
+	}
+}
+
+// CreateTopic implements the sarama.ClusterAdmin interface
+func (r *retryingAdmin) CreateTopic(topic string, detail *sarama.TopicDetail, validateOnly bool) error {
+	return r.do(r.ctx, "creating topic "+topic, func() error {
+		return r.ClusterAdmin.CreateTopic(topic, detail, validateOnly)
+	}, func(err error) bool {
+		// a validation creates nothing, so the topic existed before
+		return !validateOnly && isKError(sarama.ErrTopicAlreadyExists)(err)
+	})
+}
+
+// ListTopics implements the sarama.ClusterAdmin interface
+func (r *retryingAdmin) ListTopics() (topics map[string]sarama.TopicDetail, err error) {
+	err = r.do(r.ctx, "listing topics", func() error {
+		topics, err = r.ClusterAdmin.ListTopics()
+		return err
+	}, nil)
+	return topics, err
+}
+
+// DescribeTopics implements the sarama.ClusterAdmin interface
+func (r *retryingAdmin) DescribeTopics(topics []string) (metadata []*sarama.TopicMetadata, err error) {
+	err = r.do(r.ctx, "describing topics", func() error {
+		metadata, err = r.ClusterAdmin.DescribeTopics(topics)
+		return err
+	}, nil)
+	return metadata, err
+}
+
+// DeleteTopic implements the sarama.ClusterAdmin interface
+func (r *retryingAdmin) DeleteTopic(topic string) error {
+	return r.do(r.ctx, "deleting topic "+topic, func() error {
+		return r.ClusterAdmin.DeleteTopic(topic)
+	}, isKError(sarama.ErrUnknownTopicOrPartition))
+}
+
+// CreatePartitions implements the sarama.ClusterAdmin interface
+func (r *retryingAdmin) CreatePartitions(topic string, count int32, assignment [][]int32, validateOnly bool) error {
+	return r.do(r.ctx, "creating partitions for "+topic, func() error {
+		return r.ClusterAdmin.CreatePartitions(topic, count, assignment, validateOnly)
+	}, func(err error) bool {
+		if validateOnly || !isKError(sarama.ErrInvalidPartitions)(err) {
+			return false
+		}
+		// the count is rejected if the topic already has it, check if an earlier attempt created them
+		metadata, mErr := r.ClusterAdmin.DescribeTopics([]string{topic})
+		return mErr == nil && len(metadata) == 1 && int32(len(metadata[0].Partitions)) == count
+	})
+}
+
+// DeleteRecords implements the sarama.ClusterAdmin interface
+func (r *retryingAdmin) DeleteRecords(topic string, partitionOffsets map[int32]int64) error {
+	return r.do(r.ctx, "deleting records of "+topic, func() error {
+		return r.ClusterAdmin.DeleteRecords(topic, partitionOffsets)
+	}, nil)
+}
+
+// DescribeConfig implements the sarama.ClusterAdmin interface
+func (r *retryingAdmin) DescribeConfig(resource sarama.ConfigResource) (entries []sarama.ConfigEntry, err error) {
+	err = r.do(r.ctx, "describing config of "+resource.Name, func() error {
+		entries, err = r.ClusterAdmin.DescribeConfig(resource)
+		return err
+	}, nil)
+	return entries, err
+}
+
+// AlterConfig implements the sarama.ClusterAdmin interface
+func (r *retryingAdmin) AlterConfig(resourceType sarama.ConfigResourceType, name string, entries map[string]*string, validateOnly bool) error {
+	return r.do(r.ctx, "altering config of "+name, func() error {
+		return r.ClusterAdmin.AlterConfig(resourceType, name, entries, validateOnly)
+	}, nil)
+}
+
+// CreateACL implements the sarama.ClusterAdmin interface
+func (r *retryingAdmin) CreateACL(resource sarama.Resource, acl sarama.Acl) error {
+	return r.do(r.ctx, "creating acl for "+resource.ResourceName, func() error {
+		return r.ClusterAdmin.CreateACL(resource, acl)
+	}, nil)
+}
+
+// ListAcls implements the sarama.ClusterAdmin interface
+func (r *retryingAdmin) ListAcls(filter sarama.AclFilter) (acls []sarama.ResourceAcls, err error) {
+	err = r.do(r.ctx, "listing acls", func() error {
+		acls, err = r.ClusterAdmin.ListAcls(filter)
+		return err
+	}, nil)
+	return acls, err
+}
+
+// DeleteACL implements the sarama.ClusterAdmin interface
+func (r *retryingAdmin) DeleteACL(filter sarama.AclFilter, validateOnly bool) (acls []sarama.MatchingAcl, err error) {
+	err = r.do(r.ctx, "deleting acls", func() error {
+		acls, err = r.ClusterAdmin.DeleteACL(filter, validateOnly)
+		return err
+	}, nil)
+	return acls, err
+}
+
+// ListConsumerGroups implements the sarama.ClusterAdmin interface
+func (r *retryingAdmin) ListConsumerGroups() (groups map[string]string, err error) {
+	err = r.do(r.ctx, "listing consumer groups", func() error {
+		groups, err = r.ClusterAdmin.ListConsumerGroups()
+		return err
+	}, nil)
+	return groups, err
+}
+
+// DescribeConsumerGroups implements the sarama.ClusterAdmin interface
+func (r *retryingAdmin) DescribeConsumerGroups(groups []string) (descriptions []*sarama.GroupDescription, err error) {
+	err = r.do(r.ctx, "describing consumer groups", func() error {
+		descriptions, err = r.ClusterAdmin.DescribeConsumerGroups(groups)
+		return err
+	}, nil)
+	return descriptions, err
+}
+
+// ListConsumerGroupOffsets implements the sarama.ClusterAdmin interface
+func (r *retryingAdmin) ListConsumerGroupOffsets(group string, topicPartitions map[string][]int32) (offsets *sarama.OffsetFetchResponse, err error) {
+	err = r.do(r.ctx, "listing offsets of "+group, func() error {
+		offsets, err = r.ClusterAdmin.ListConsumerGroupOffsets(group, topicPartitions)
+		return err
+	}, nil)
+	return offsets, err
+}
+
+// DescribeCluster implements the sarama.ClusterAdmin interface
+func (r *retryingAdmin) DescribeCluster() (brokers []*sarama.Broker, controllerID int32, err error) {
+	err = r.do(r.ctx, "describing cluster", func() error {
+		brokers, controllerID, err = r.ClusterAdmin.DescribeCluster()
+		return err
+	}, nil)
+	return brokers, controllerID, err
+}
@@ -0,0 +1,196 @@
+package kafka_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/izolight/kafkalib/kafka"
+)
+
+// flakyClient fails the first calls with err. If applied is set the failing calls still take effect,
+// like a request that timed out on the client but succeeded on the controller.
+type flakyClient struct {
+	sarama.ClusterAdmin
+	failures int
+	err      error
+	applied  bool
+	mu       sync.Mutex
+	calls    int
+}
+
+func (f *flakyClient) fail(apply func() error) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls++
+	if f.calls <= f.failures {
+		if f.applied {
+			apply()
+		}
+		return f.err
+	}
+	return apply()
+}
+
+func (f *flakyClient) CreateTopic(topic string, detail *sarama.TopicDetail, validateOnly bool) error {
+	return f.fail(func() error { return f.ClusterAdmin.CreateTopic(topic, detail, validateOnly) })
+}
+
+func (f *flakyClient) DeleteTopic(topic string) error {
+	return f.fail(func() error { return f.ClusterAdmin.DeleteTopic(topic) })
+}
+
+func (f *flakyClient) CreateACL(resource sarama.Resource, acl sarama.Acl) error {
+	return f.fail(func() error { return f.ClusterAdmin.CreateACL(resource, acl) })
+}
+
+func TestRetryingAdmin(t *testing.T) {
+	policy := kafka.RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond, Multiplier: 2}
+	testCases := []struct {
+		name          string
+		client        *flakyClient
+		op            func(admin sarama.ClusterAdmin) error
+		expectedCalls int
+		expectedErr   error
+	}{
+		{
+			name:          "create topic after not controller",
+			client:        &flakyClient{failures: 2, err: sarama.ErrNotController},
+			op:            func(admin sarama.ClusterAdmin) error { return admin.CreateTopic("new", &sarama.TopicDetail{}, false) },
+			expectedCalls: 3,
+		},
+		{
+			name:          "create topic timed out but succeeded",
+			client:        &flakyClient{failures: 1, err: sarama.ErrRequestTimedOut, applied: true},
+			op:            func(admin sarama.ClusterAdmin) error { return admin.CreateTopic("new", &sarama.TopicDetail{}, false) },
+			expectedCalls: 2,
+		},
+		{
+			name:   "create existing topic is not retried",
+			client: &flakyClient{},
+			op: func(admin sarama.ClusterAdmin) error {
+				return admin.CreateTopic("simpleTopic", &sarama.TopicDetail{}, false)
+			},
+			expectedCalls: 1,
+			expectedErr:   sarama.ErrTopicAlreadyExists,
+		},
+		{
+			name:   "validate existing topic after time out",
+			client: &flakyClient{failures: 1, err: sarama.ErrRequestTimedOut, applied: true},
+			op: func(admin sarama.ClusterAdmin) error {
+				return admin.CreateTopic("simpleTopic", &sarama.TopicDetail{}, true)
+			},
+			expectedCalls: 2,
+			expectedErr:   sarama.ErrTopicAlreadyExists,
+		},
+		{
+			name:          "delete topic timed out but succeeded",
+			client:        &flakyClient{failures: 1, err: &sarama.TopicError{Err: sarama.ErrRequestTimedOut}, applied: true},
+			op:            func(admin sarama.ClusterAdmin) error { return admin.DeleteTopic("simpleTopic") },
+			expectedCalls: 2,
+		},
+		{
+			name:          "create acl gives up",
+			client:        &flakyClient{failures: 5, err: sarama.ErrLeaderNotAvailable},
+			op:            func(admin sarama.ClusterAdmin) error { return admin.CreateACL(sarama.Resource{}, sarama.Acl{}) },
+			expectedCalls: 3,
+			expectedErr:   sarama.ErrLeaderNotAvailable,
+		},
+		{
+			name:          "not retryable",
+			client:        &flakyClient{failures: 1, err: sarama.ErrPolicyViolation},
+			op:            func(admin sarama.ClusterAdmin) error { return admin.CreateTopic("new", &sarama.TopicDetail{}, false) },
+			expectedCalls: 1,
+			expectedErr:   sarama.ErrPolicyViolation,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.client.ClusterAdmin = NewTestClient()
+			admin := kafka.NewRetryingAdmin(tc.client, nil, policy)
+			err := tc.op(admin)
+			if tc.expectedErr == nil && err != nil {
+				t.Errorf("Expected no error but got %s", err)
+			}
+			if tc.expectedErr != nil {
+				var kerr sarama.KError
+				if !errors.As(err, &kerr) && err != nil {
+					if te, ok := err.(*sarama.TopicError); ok {
+						kerr = te.Err
+					}
+				}
+				if kerr != tc.expectedErr {
+					t.Errorf("Expected error %s but got %v", tc.expectedErr, err)
+				}
+			}
+			if tc.client.calls != tc.expectedCalls {
+				t.Errorf("Expected %d calls but got %d", tc.expectedCalls, tc.client.calls)
+			}
+		})
+	}
+}
+
+func TestRetryingAdmin_Context(t *testing.T) {
+	client := &flakyClient{ClusterAdmin: NewTestClient(), failures: 5, err: sarama.ErrNotController}
+	policy := kafka.RetryPolicy{MaxAttempts: 5, InitialBackoff: 10 * time.Millisecond, Multiplier: 1}
+	c := kafka.Conn{AdminClient: kafka.NewRetryingAdmin(client, nil, policy)}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Millisecond)
+	defer cancel()
+	if err := c.CreateTopicContext(ctx, kafka.NewTopic{Name: "new"}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Expected deadline exceeded but got %v", err)
+	}
+	// without cancelled backoffs all 5 attempts would be done by now
+	time.Sleep(100 * time.Millisecond)
+	client.mu.Lock()
+	defer client.mu.Unlock()
+	if client.calls != 1 {
+		t.Errorf("Expected 1 call but got %d", client.calls)
+	}
+}
+
+func TestRetryingAdmin_ControllerMoved(t *testing.T) {
+	oldController := sarama.NewMockBroker(t, 1)
+	defer oldController.Close()
+	newController := sarama.NewMockBroker(t, 2)
+	defer newController.Close()
+	metadata := func(controller int32) *sarama.MockMetadataResponse {
+		return sarama.NewMockMetadataResponse(t).
+			SetBroker(oldController.Addr(), oldController.BrokerID()).
+			SetBroker(newController.Addr(), newController.BrokerID()).
+			SetController(controller)
+	}
+	// the controller moves after the client fetched its first metadata
+	oldController.SetHandlerByMap(map[string]sarama.MockResponse{
+		"MetadataRequest": sarama.NewMockSequence(metadata(oldController.BrokerID()), metadata(newController.BrokerID())),
+		"CreateTopicsRequest": sarama.NewMockWrapper(&sarama.CreateTopicsResponse{
+			TopicErrors: map[string]*sarama.TopicError{"new": {Err: sarama.ErrNotController}},
+		}),
+	})
+	newController.SetHandlerByMap(map[string]sarama.MockResponse{
+		"MetadataRequest":     metadata(newController.BrokerID()),
+		"CreateTopicsRequest": sarama.NewMockCreateTopicsResponse(t),
+	})
+
+	policy := kafka.RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond}
+	c, err := kafka.NewConn(&kafka.Config{BrokerList: []string{oldController.Addr()}, Version: "0.10.2.0", Retry: &policy,
+		NamingPolicy: kafka.NamingPolicies{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	if err := c.CreateTopic(kafka.NewTopic{Name: "new", TopicDetail: sarama.TopicDetail{NumPartitions: 1, ReplicationFactor: 1}}); err != nil {
+		t.Fatal(err)
+	}
+	created := 0
+	for _, r := range newController.History() {
+		if _, ok := r.Request.(*sarama.CreateTopicsRequest); ok {
+			created++
+		}
+	}
+	if created != 1 {
+		t.Errorf("Expected the retry to go to the new controller, it got %d requests", created)
+	}
+}
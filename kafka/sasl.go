@@ -0,0 +1,57 @@
+package kafka
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Shopify/sarama"
+)
+
+// configureSASL sets up the SASL mechanism selected in config
+func configureSASL(cfg *sarama.Config, config *Config) error {
+	mechanism := sarama.SASLMechanism(strings.ToUpper(config.SASLMechanism))
+	if mechanism == "" {
+		mechanism = sarama.SASLTypePlaintext
+	}
+
+	switch mechanism {
+	case sarama.SASLTypePlaintext:
+		if len(config.User) == 0 || len(config.Password) == 0 {
+			if len(config.SASLMechanism) != 0 {
+				return fmt.Errorf("SASL mechanism %s needs a user and password", mechanism)
+			}
+			return nil
+		}
+	case sarama.SASLTypeSCRAMSHA256, sarama.SASLTypeSCRAMSHA512:
+		if len(config.User) == 0 || len(config.Password) == 0 {
+			return fmt.Errorf("SASL mechanism %s needs a user and password", mechanism)
+		}
+		generator, err := newSCRAMClientGenerator(mechanism)
+		if err != nil {
+			return err
+		}
+		cfg.Net.SASL.SCRAMClientGeneratorFunc = generator
+	case sarama.SASLTypeOAuth:
+		provider := config.TokenProvider
+		if provider == nil && len(config.OAuthTokenURL) != 0 {
+			provider = &ClientCredentialsTokenProvider{
+				TokenURL:     config.OAuthTokenURL,
+				ClientID:     config.OAuthClientID,
+				ClientSecret: config.OAuthClientSecret,
+				Scopes:       config.OAuthScopes,
+			}
+		}
+		if provider == nil {
+			return fmt.Errorf("SASL mechanism %s needs a token provider or token url", mechanism)
+		}
+		cfg.Net.SASL.TokenProvider = provider
+	default:
+		return fmt.Errorf("SASL mechanism %s is not supported", config.SASLMechanism)
+	}
+
+	cfg.Net.SASL.Enable = true
+	cfg.Net.SASL.Mechanism = mechanism
+	cfg.Net.SASL.User = config.User
+	cfg.Net.SASL.Password = config.Password
+	return nil
+}
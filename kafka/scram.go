@@ -0,0 +1,198 @@
+package kafka
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"hash"
+	"strconv"
+	"strings"
+
+	"github.com/Shopify/sarama"
+)
+
+// scramClient implements the client side of a SCRAM exchange (RFC 5802)
+// as required by sarama.SCRAMClient
+type scramClient struct {
+	hashGen func() hash.Hash
+	nonce   string
+
+	user     string
+	password string
+	authzID  string
+
+	step            int
+	clientFirstBare string
+	serverSignature []byte
+}
+
+// newSCRAMClientGenerator returns a generator for the given SCRAM mechanism
+func newSCRAMClientGenerator(mechanism sarama.SASLMechanism) (func() sarama.SCRAMClient, error) {
+	var hashGen func() hash.Hash
+	switch mechanism {
+	case sarama.SASLTypeSCRAMSHA256:
+		hashGen = sha256.New
+	case sarama.SASLTypeSCRAMSHA512:
+		hashGen = sha512.New
+	default:
+		return nil, fmt.Errorf("%s is not a SCRAM mechanism", mechanism)
+	}
+	return func() sarama.SCRAMClient {
+		return &scramClient{hashGen: hashGen}
+	}, nil
+}
+
+// Begin implements the sarama.SCRAMClient interface
+func (s *scramClient) Begin(userName, password, authzID string) error {
+	if s.nonce == "" {
+		b := make([]byte, 24)
+		if _, err := rand.Read(b); err != nil {
+			return err
+		}
+		s.nonce = base64.RawStdEncoding.EncodeToString(b)
+	}
+	s.user = userName
+	s.password = password
+	s.authzID = authzID
+	s.step = 0
+	s.serverSignature = nil
+	return nil
+}
+
+// Step implements the sarama.SCRAMClient interface
+func (s *scramClient) Step(challenge string) (string, error) {
+	s.step++
+	switch s.step {
+	case 1:
+		return s.clientFirst(), nil
+	case 2:
+		return s.clientFinal(challenge)
+	case 3:
+		return "", s.verifyServerFinal(challenge)
+	default:
+		return "", fmt.Errorf("SCRAM exchange already finished")
+	}
+}
+
+// Done implements the sarama.SCRAMClient interface
+func (s *scramClient) Done() bool {
+	return s.step >= 3
+}
+
+func (s *scramClient) clientFirst() string {
+	s.clientFirstBare = fmt.Sprintf("n=%s,r=%s", scramEscape(s.user), s.nonce)
+	gs2Header := "n,,"
+	if s.authzID != "" {
+		gs2Header = fmt.Sprintf("n,a=%s,", scramEscape(s.authzID))
+	}
+	return gs2Header + s.clientFirstBare
+}
+
+func (s *scramClient) clientFinal(serverFirst string) (string, error) {
+	attrs := scramAttributes(serverFirst)
+	if msg, ok := attrs["e"]; ok {
+		return "", fmt.Errorf("SCRAM server error: %s", msg)
+	}
+	nonce := attrs["r"]
+	if !strings.HasPrefix(nonce, s.nonce) || len(nonce) == len(s.nonce) {
+		return "", fmt.Errorf("SCRAM server nonce does not extend the client nonce")
+	}
+	salt, err := base64.StdEncoding.DecodeString(attrs["s"])
+	if err != nil {
+		return "", fmt.Errorf("Invalid SCRAM salt: %s", err)
+	}
+	iterations, err := strconv.Atoi(attrs["i"])
+	if err != nil || iterations < 1 {
+		return "", fmt.Errorf("Invalid SCRAM iteration count %q", attrs["i"])
+	}
+
+	gs2Header := "n,,"
+	if s.authzID != "" {
+		gs2Header = fmt.Sprintf("n,a=%s,", scramEscape(s.authzID))
+	}
+	clientFinalWithoutProof := fmt.Sprintf("c=%s,r=%s", base64.StdEncoding.EncodeToString([]byte(gs2Header)), nonce)
+	authMessage := s.clientFirstBare + "," + serverFirst + "," + clientFinalWithoutProof
+
+	saltedPassword := pbkdf2(s.hashGen, []byte(s.password), salt, iterations, s.hashGen().Size())
+	clientKey := s.hmac(saltedPassword, []byte("Client Key"))
+	h := s.hashGen()
+	h.Write(clientKey)
+	storedKey := h.Sum(nil)
+	clientSignature := s.hmac(storedKey, []byte(authMessage))
+	proof := make([]byte, len(clientKey))
+	for i := range clientKey {
+		proof[i] = clientKey[i] ^ clientSignature[i]
+	}
+	serverKey := s.hmac(saltedPassword, []byte("Server Key"))
+	s.serverSignature = s.hmac(serverKey, []byte(authMessage))
+
+	return fmt.Sprintf("%s,p=%s", clientFinalWithoutProof, base64.StdEncoding.EncodeToString(proof)), nil
+}
+
+func (s *scramClient) verifyServerFinal(serverFinal string) error {
+	attrs := scramAttributes(serverFinal)
+	if msg, ok := attrs["e"]; ok {
+		return fmt.Errorf("SCRAM server error: %s", msg)
+	}
+	signature, err := base64.StdEncoding.DecodeString(attrs["v"])
+	if err != nil {
+		return fmt.Errorf("Invalid SCRAM server signature: %s", err)
+	}
+	if !hmac.Equal(signature, s.serverSignature) {
+		return fmt.Errorf("SCRAM server signature does not match")
+	}
+	return nil
+}
+
+func (s *scramClient) hmac(key, data []byte) []byte {
+	mac := hmac.New(s.hashGen, key)
+	mac.Write(data)
+	return mac.Sum(nil)
+}
+
+// scramEscape escapes a username or authzid as required by RFC 5802
+func scramEscape(s string) string {
+	return strings.NewReplacer("=", "=3D", ",", "=2C").Replace(s)
+}
+
+// scramAttributes splits a SCRAM message into its attributes
+func scramAttributes(msg string) map[string]string {
+	attrs := make(map[string]string)
+	for _, part := range strings.Split(msg, ",") {
+		if len(part) < 2 || part[1] != '=' {
+			continue
+		}
+		attrs[part[:1]] = part[2:]
+	}
+	return attrs
+}
+
+// pbkdf2 derives a key of keyLen bytes as defined in RFC 8018
+func pbkdf2(hashGen func() hash.Hash, password, salt []byte, iterations, keyLen int) []byte {
+	mac := hmac.New(hashGen, password)
+	var out []byte
+	var block [4]byte
+	for n := uint32(1); len(out) < keyLen; n++ {
+		mac.Reset()
+		mac.Write(salt)
+		binary.BigEndian.PutUint32(block[:], n)
+		mac.Write(block[:])
+		u := mac.Sum(nil)
+		t := make([]byte, len(u))
+		copy(t, u)
+		for i := 1; i < iterations; i++ {
+			mac.Reset()
+			mac.Write(u)
+			u = mac.Sum(u[:0])
+			for j := range t {
+				t[j] ^= u[j]
+			}
+		}
+		out = append(out, t...)
+	}
+	return out[:keyLen]
+}
@@ -0,0 +1,108 @@
+package kafka
+
+import (
+	"testing"
+
+	"github.com/Shopify/sarama"
+)
+
+// TestSCRAMClient_Step uses the example exchange from RFC 7677
+func TestSCRAMClient_Step(t *testing.T) {
+	generator, err := newSCRAMClientGenerator(sarama.SASLTypeSCRAMSHA256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := generator().(*scramClient)
+	client.nonce = "rOprNGfwEbeRWgbNEkqO"
+	if err := client.Begin("user", "pencil", ""); err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		challenge string
+		expected  string
+	}{
+		{
+			"",
+			"n,,n=user,r=rOprNGfwEbeRWgbNEkqO",
+		},
+		{
+			"r=rOprNGfwEbeRWgbNEkqO%hvYDpWUa2RaTCAfuxFIlj)hNlF$k0,s=W22ZaJ0SNY7soEsUEjb6gQ==,i=4096",
+			"c=biws,r=rOprNGfwEbeRWgbNEkqO%hvYDpWUa2RaTCAfuxFIlj)hNlF$k0,p=dHzbZapWIk4jUhN+Ute9ytag9zjfMHgsqmmiz7AndVQ=",
+		},
+		{
+			"v=6rriTRBi23WpRR/wtup+mMhUZUn/dB5nLTJRsjl95G4=",
+			"",
+		},
+	}
+	for _, tc := range testCases {
+		if client.Done() {
+			t.Fatal("SCRAM exchange finished too early")
+		}
+		got, err := client.Step(tc.challenge)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tc.expected {
+			t.Errorf("scramClient.Step():\nGot:\t%s\nWant:\t%s", got, tc.expected)
+		}
+	}
+	if !client.Done() {
+		t.Fatal("SCRAM exchange should be finished")
+	}
+}
+
+func TestSCRAMClient_BadServerSignature(t *testing.T) {
+	generator, err := newSCRAMClientGenerator(sarama.SASLTypeSCRAMSHA512)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := generator()
+	if err := client.Begin("user", "pencil", ""); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.Step(""); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.Step("r=foreignnonce,s=W22ZaJ0SNY7soEsUEjb6gQ==,i=4096"); err == nil {
+		t.Fatal("Should reject a server nonce that doesn't extend the client nonce")
+	}
+}
+
+func TestConfigureSASL(t *testing.T) {
+	testCases := []struct {
+		config    Config
+		enabled   bool
+		mechanism sarama.SASLMechanism
+		success   bool
+	}{
+		{Config{}, false, "", true},
+		{Config{User: "test", Password: "secret"}, true, sarama.SASLTypePlaintext, true},
+		{Config{SASLMechanism: "scram-sha-512", User: "test", Password: "secret"}, true, sarama.SASLTypeSCRAMSHA512, true},
+		{Config{SASLMechanism: "SCRAM-SHA-256"}, false, "", false},
+		{Config{SASLMechanism: "OAUTHBEARER", OAuthTokenURL: "http://localhost/token"}, true, sarama.SASLTypeOAuth, true},
+		{Config{SASLMechanism: "OAUTHBEARER"}, false, "", false},
+		{Config{SASLMechanism: "GSSAPI", User: "test", Password: "secret"}, false, "", false},
+	}
+	for _, tc := range testCases {
+		cfg := sarama.NewConfig()
+		err := configureSASL(cfg, &tc.config)
+		if err != nil && tc.success {
+			t.Fatal(err)
+		}
+		if err == nil && !tc.success {
+			t.Fatalf("Should return an error for mechanism %s", tc.config.SASLMechanism)
+		}
+		if cfg.Net.SASL.Enable != tc.enabled {
+			t.Errorf("SASL enabled is %t, expected %t", cfg.Net.SASL.Enable, tc.enabled)
+		}
+		if tc.enabled && cfg.Net.SASL.Mechanism != tc.mechanism {
+			t.Errorf("SASL mechanism is %s, expected %s", cfg.Net.SASL.Mechanism, tc.mechanism)
+		}
+		if tc.success {
+			if err := cfg.Validate(); err != nil {
+				t.Error(err)
+			}
+		}
+	}
+}
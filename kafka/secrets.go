@@ -0,0 +1,108 @@
+package kafka
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// prefixes for secret references
+const (
+	secretEnvPrefix  = "env:"
+	secretFilePrefix = "file:"
+	secretExecPrefix = "exec:"
+)
+
+// redacted replaces literal secrets in the String and JSON representation of a Config
+const redacted = "REDACTED"
+
+// ResolveSecret resolves a secret reference. env:NAME reads an environment variable,
+// file:/path reads a file and exec:command runs a command through sh and uses its output.
+// Trailing newlines are removed, any other value is returned as is.
+func ResolveSecret(ref string) (string, error) {
+	switch {
+	case strings.HasPrefix(ref, secretEnvPrefix):
+		name := strings.TrimPrefix(ref, secretEnvPrefix)
+		value, ok := os.LookupEnv(name)
+		if !ok {
+			return "", fmt.Errorf("Environment variable %s for secret is not set", name)
+		}
+		return value, nil
+	case strings.HasPrefix(ref, secretFilePrefix):
+		path := strings.TrimPrefix(ref, secretFilePrefix)
+		b, err := ioutil.ReadFile(path)
+		if err != nil {
+			return "", fmt.Errorf("Error reading secret: %s", err)
+		}
+		return strings.TrimRight(string(b), "\r\n"), nil
+	case strings.HasPrefix(ref, secretExecPrefix):
+		command := strings.TrimPrefix(ref, secretExecPrefix)
+		cmd := exec.Command("sh", "-c", command)
+		var stderr bytes.Buffer
+		cmd.Stderr = &stderr
+		out, err := cmd.Output()
+		if err != nil {
+			return "", fmt.Errorf("Error running secret command %q: %s %s", command, err, strings.TrimSpace(stderr.String()))
+		}
+		return strings.TrimRight(string(out), "\r\n"), nil
+	default:
+		return ref, nil
+	}
+}
+
+// isSecretRef returns if value references a secret instead of holding it
+func isSecretRef(value string) bool {
+	return strings.HasPrefix(value, secretEnvPrefix) ||
+		strings.HasPrefix(value, secretFilePrefix) ||
+		strings.HasPrefix(value, secretExecPrefix)
+}
+
+// secretFields returns pointers to all fields of config holding secrets
+func (config *Config) secretFields() []*string {
+	return []*string{
+		&config.Password,
+		&config.TLSKeyPassword,
+		&config.TLSKeyPEM,
+		&config.OAuthClientSecret,
+	}
+}
+
+// resolveSecrets returns a copy of config with all secret references resolved
+func (config *Config) resolveSecrets() (*Config, error) {
+	resolved := *config
+	for _, field := range resolved.secretFields() {
+		value, err := ResolveSecret(*field)
+		if err != nil {
+			return nil, err
+		}
+		*field = value
+	}
+	return &resolved, nil
+}
+
+// plainConfig has the fields of Config without its methods
+type plainConfig Config
+
+// redacted returns a copy of config where literal secrets are replaced, references are kept
+func (config Config) redacted() plainConfig {
+	for _, field := range config.secretFields() {
+		if len(*field) != 0 && !isSecretRef(*field) {
+			*field = redacted
+		}
+	}
+	return plainConfig(config)
+}
+
+// String implements the Stringer interface and redacts secrets
+func (config Config) String() string {
+	return fmt.Sprintf("%+v", config.redacted())
+}
+
+// MarshalJSON implements the Marshaler interface and redacts secrets
+func (config Config) MarshalJSON() ([]byte, error) {
+	return json.Marshal(config.redacted())
+}
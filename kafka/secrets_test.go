@@ -0,0 +1,83 @@
+package kafka_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/izolight/kafkalib/kafka"
+)
+
+func TestResolveSecret(t *testing.T) {
+	path, cleanup := writeTestFile(t, "password", "fromfile\n")
+	defer cleanup()
+	os.Setenv("KAFKALIB_TEST_SECRET", "fromenv")
+	defer os.Unsetenv("KAFKALIB_TEST_SECRET")
+
+	testCases := []struct {
+		ref      string
+		expected string
+		success  bool
+	}{
+		{"literal", "literal", true},
+		{"env:KAFKALIB_TEST_SECRET", "fromenv", true},
+		{"env:KAFKALIB_TEST_SECRET_UNSET", "", false},
+		{"file:" + path, "fromfile", true},
+		{"file:" + path + ".missing", "", false},
+		{"exec:echo fromexec", "fromexec", true},
+		{"exec:exit 1", "", false},
+	}
+	for _, tc := range testCases {
+		got, err := kafka.ResolveSecret(tc.ref)
+		if err != nil && tc.success {
+			t.Fatal(err)
+		}
+		if err == nil && !tc.success {
+			t.Fatalf("Resolving %s should return an error", tc.ref)
+		}
+		if got != tc.expected {
+			t.Errorf("ResolveSecret(%s):\nGot:\t%s\nWant:\t%s", tc.ref, got, tc.expected)
+		}
+	}
+}
+
+func TestConfig_Redacted(t *testing.T) {
+	config := kafka.Config{
+		User:              "admin",
+		Password:          "hunter2",
+		OAuthClientSecret: "env:CLIENT_SECRET",
+	}
+	b, err := json.Marshal(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, out := range map[string]string{"String": config.String(), "Sprint": fmt.Sprint(&config), "JSON": string(b)} {
+		if strings.Contains(out, "hunter2") {
+			t.Errorf("%s contains the password: %s", name, out)
+		}
+		if !strings.Contains(out, "REDACTED") || !strings.Contains(out, "env:CLIENT_SECRET") {
+			t.Errorf("%s should contain the redacted password and the secret reference: %s", name, out)
+		}
+	}
+	if config.Password != "hunter2" {
+		t.Error("Redacting must not modify the config")
+	}
+}
+
+func TestNewSaramaConfig_ResolvesSecrets(t *testing.T) {
+	os.Setenv("KAFKALIB_TEST_PASSWORD", "hunter2")
+	defer os.Unsetenv("KAFKALIB_TEST_PASSWORD")
+	config := &kafka.Config{User: "admin", Password: "env:KAFKALIB_TEST_PASSWORD"}
+	cfg, err := kafka.NewSaramaConfig(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Net.SASL.Password != "hunter2" {
+		t.Errorf("SASL password is %s, expected the resolved secret", cfg.Net.SASL.Password)
+	}
+	if config.Password != "env:KAFKALIB_TEST_PASSWORD" {
+		t.Error("Resolving secrets must not modify the config")
+	}
+}
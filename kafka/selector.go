@@ -0,0 +1,193 @@
+package kafka
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/Shopify/sarama"
+)
+
+// internal topic handling of a TopicSelector
+const (
+	internalExclude = iota
+	internalInclude
+	internalOnly
+)
+
+// TopicSelector selects topics by name and metadata. It implements the Selector interface and is
+// created by ParseSelector.
+type TopicSelector struct {
+	expr       string
+	include    []func(string) bool
+	exclude    []func(string) bool
+	predicates []func(sarama.TopicDetail) bool
+	internal   int
+}
+
+// ParseSelector parses a whitespace separated list of terms:
+//
+//	orders.*               glob on the topic name
+//	/^orders-[0-9]+$/      regular expression on the topic name
+//	!orders.dlq !/-tmp$/   excludes names matched by a glob or regular expression
+//	partitions>=6          compares the partition count with =, !=, <, <=, > or >=
+//	replicas=3             compares the replication factor, rf is an alias
+//	config.cleanup.policy=compact
+//	                       compares a topic config override with = or !=
+//	internal=true          includes internal topics, internal=only selects only them
+//
+// A topic is selected if it matches any name pattern, or there are none, no exclusion and all
+// predicates. Internal topics are excluded unless internal is set.
+func ParseSelector(expr string) (*TopicSelector, error) {
+	s := &TopicSelector{expr: expr}
+	for _, term := range strings.Fields(expr) {
+		if err := s.parseTerm(term); err != nil {
+			return nil, &Error{Op: "parsing selector", Resource: expr, Kind: ErrInvalidConfig, Err: err}
+		}
+	}
+	return s, nil
+}
+
+// Match implements the Selector interface
+func (s *TopicSelector) Match(name string, detail sarama.TopicDetail) bool {
+	internal := isInternalTopic(name)
+	if (internal && s.internal == internalExclude) || (!internal && s.internal == internalOnly) {
+		return false
+	}
+	if len(s.include) > 0 && !matchAny(s.include, name) {
+		return false
+	}
+	if matchAny(s.exclude, name) {
+		return false
+	}
+	for _, p := range s.predicates {
+		if !p(detail) {
+			return false
+		}
+	}
+	return true
+}
+
+// String returns the expression the selector was parsed from
+func (s *TopicSelector) String() string {
+	return s.expr
+}
+
+func (s *TopicSelector) parseTerm(term string) error {
+	// topic names can't contain operators, a term with one outside of a pattern is a predicate
+	if i := strings.IndexAny(term, "!=<>"); i > 0 && !strings.HasPrefix(term, "/") {
+		op := term[i : i+1]
+		if strings.HasPrefix(term[i+1:], "=") {
+			op = term[i : i+2]
+		}
+		return s.parsePredicate(term[:i], op, term[i+len(op):])
+	}
+	exclude := strings.HasPrefix(term, "!")
+	pattern := strings.TrimPrefix(term, "!")
+	var m func(string) bool
+	if len(pattern) >= 2 && strings.HasPrefix(pattern, "/") && strings.HasSuffix(pattern, "/") {
+		r, err := regexp.Compile(pattern[1 : len(pattern)-1])
+		if err != nil {
+			return err
+		}
+		m = r.MatchString
+	} else {
+		if _, err := path.Match(pattern, ""); err != nil || pattern == "" {
+			return fmt.Errorf("invalid glob %q", pattern)
+		}
+		m = func(name string) bool {
+			ok, _ := path.Match(pattern, name)
+			return ok
+		}
+	}
+	if exclude {
+		s.exclude = append(s.exclude, m)
+	} else {
+		s.include = append(s.include, m)
+	}
+	return nil
+}
+
+func (s *TopicSelector) parsePredicate(key, op, value string) error {
+	switch key {
+	case "partitions", "replicas", "rf":
+		n, err := strconv.ParseInt(value, 10, 32)
+		if err != nil {
+			return fmt.Errorf("invalid number %q for %s", value, key)
+		}
+		compare, err := compareInt(op)
+		if err != nil {
+			return err
+		}
+		if key == "partitions" {
+			s.predicates = append(s.predicates, func(d sarama.TopicDetail) bool { return compare(int64(d.NumPartitions), n) })
+		} else {
+			s.predicates = append(s.predicates, func(d sarama.TopicDetail) bool { return compare(int64(d.ReplicationFactor), n) })
+		}
+	case "internal":
+		if op != "=" {
+			return fmt.Errorf("internal only supports =")
+		}
+		switch value {
+		case "true":
+			s.internal = internalInclude
+		case "false":
+			s.internal = internalExclude
+		case "only":
+			s.internal = internalOnly
+		default:
+			return fmt.Errorf("internal must be true, false or only but is %q", value)
+		}
+	default:
+		config := strings.TrimPrefix(key, "config.")
+		if config == key || config == "" {
+			return fmt.Errorf("unknown key %q", key)
+		}
+		if op != "=" && op != "!=" {
+			return fmt.Errorf("config values only support = and !=")
+		}
+		// the config entries of a topic only hold its overrides, a missing key never equals a value
+		s.predicates = append(s.predicates, func(d sarama.TopicDetail) bool {
+			v, ok := d.ConfigEntries[config]
+			equal := ok && v != nil && *v == value
+			return equal == (op == "=")
+		})
+	}
+	return nil
+}
+
+// compareInt returns the comparison of op
+func compareInt(op string) (func(a, b int64) bool, error) {
+	switch op {
+	case "=":
+		return func(a, b int64) bool { return a == b }, nil
+	case "!=":
+		return func(a, b int64) bool { return a != b }, nil
+	case "<":
+		return func(a, b int64) bool { return a < b }, nil
+	case "<=":
+		return func(a, b int64) bool { return a <= b }, nil
+	case ">":
+		return func(a, b int64) bool { return a > b }, nil
+	case ">=":
+		return func(a, b int64) bool { return a >= b }, nil
+	}
+	return nil, fmt.Errorf("unknown operator %q", op)
+}
+
+func matchAny(matchers []func(string) bool, name string) bool {
+	for _, m := range matchers {
+		if m(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// isInternalTopic reports whether the topic is internal like __consumer_offsets. The topic list
+// doesn't carry the internal flag, so it relies on the double underscore prefix used by them.
+func isInternalTopic(name string) bool {
+	return strings.HasPrefix(name, "__")
+}
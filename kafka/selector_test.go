@@ -0,0 +1,107 @@
+package kafka_test
+
+import (
+	"errors"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/Shopify/sarama"
+	"github.com/izolight/kafkalib/kafka"
+)
+
+func TestParseSelector(t *testing.T) {
+	compact := "compact"
+	topics := map[string]sarama.TopicDetail{
+		"orders":             {NumPartitions: 6, ReplicationFactor: 3, ConfigEntries: map[string]*string{"cleanup.policy": &compact}},
+		"orders-dlq":         {NumPartitions: 1, ReplicationFactor: 3},
+		"payments":           {NumPartitions: 12, ReplicationFactor: 2},
+		"payments-tmp":       {NumPartitions: 1, ReplicationFactor: 1},
+		"__consumer_offsets": {NumPartitions: 50, ReplicationFactor: 3, ConfigEntries: map[string]*string{"cleanup.policy": &compact}},
+	}
+	testCases := []struct {
+		expr     string
+		expected []string
+	}{
+		{"", []string{"orders", "orders-dlq", "payments", "payments-tmp"}},
+		{"orders*", []string{"orders", "orders-dlq"}},
+		{"orders* !*-dlq", []string{"orders"}},
+		{"/^pay.*s$/ orders", []string{"orders", "payments"}},
+		{"!/-(dlq|tmp)$/", []string{"orders", "payments"}},
+		{"partitions>1", []string{"orders", "payments"}},
+		{"partitions>=6 partitions<12", []string{"orders"}},
+		{"replicas=3", []string{"orders", "orders-dlq"}},
+		{"rf!=3", []string{"payments", "payments-tmp"}},
+		{"rf<=2 payments*", []string{"payments", "payments-tmp"}},
+		{"config.cleanup.policy=compact", []string{"orders"}},
+		{"config.cleanup.policy!=compact", []string{"orders-dlq", "payments", "payments-tmp"}},
+		{"config.cleanup.policy=compact internal=true", []string{"__consumer_offsets", "orders"}},
+		{"internal=only", []string{"__consumer_offsets"}},
+		{"missing", nil},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.expr, func(t *testing.T) {
+			selector, err := kafka.ParseSelector(tc.expr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var selected []string
+			for name, detail := range topics {
+				if selector.Match(name, detail) {
+					selected = append(selected, name)
+				}
+			}
+			sort.Strings(selected)
+			if !reflect.DeepEqual(selected, tc.expected) {
+				t.Errorf("Expected %v but got %v", tc.expected, selected)
+			}
+		})
+	}
+}
+
+func TestParseSelector_Invalid(t *testing.T) {
+	testCases := []string{
+		"/orders(/",
+		"orders[",
+		"partitions>many",
+		"partitions!3",
+		"size>10",
+		"config.cleanup.policy>compact",
+		"internal=maybe",
+		"config.=x",
+	}
+	for _, expr := range testCases {
+		_, err := kafka.ParseSelector(expr)
+		if !errors.Is(err, kafka.ErrInvalidConfig) {
+			t.Errorf("ParseSelector(%q): expected ErrInvalidConfig but got %v", expr, err)
+		}
+	}
+}
+
+func TestGetSelectedTopics(t *testing.T) {
+	c := kafka.Conn{AdminClient: NewTestClient()}
+	selector, err := kafka.ParseSelector("topicWith* partitions=4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	topics, err := c.GetSelectedTopics(selector)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(topics) != 2 {
+		t.Errorf("Expected 2 topics but got %v", topics)
+	}
+	for _, name := range []string{"topicWithPartitions", "topicWithPartitionsAndReplicas"} {
+		if _, ok := topics[name]; !ok {
+			t.Errorf("Expected %s to be selected", name)
+		}
+	}
+}
+
+func TestGetTopic_InvalidFilter(t *testing.T) {
+	c := kafka.Conn{AdminClient: NewTestClient()}
+	_, err := c.GetTopic("simple(")
+	if !errors.Is(err, kafka.ErrInvalidConfig) {
+		t.Errorf("Expected ErrInvalidConfig but got %v", err)
+	}
+}
@@ -0,0 +1,120 @@
+package kafka
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+)
+
+// tlsVersions maps the accepted TLSMinVersion values to the tls constants
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// NewTLSConfig builds the tls config for connecting to the brokers from the TLS fields of config
+func NewTLSConfig(config *Config) (*tls.Config, error) {
+	config, err := config.resolveSecrets()
+	if err != nil {
+		return nil, err
+	}
+	return newTLSConfig(config)
+}
+
+// newTLSConfig builds the tls config from a config with resolved secrets
+func newTLSConfig(config *Config) (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		InsecureSkipVerify: config.TLSInsecureSkipVerify,
+		ServerName:         config.TLSServerName,
+	}
+
+	if len(config.TLSMinVersion) != 0 {
+		version, ok := tlsVersions[config.TLSMinVersion]
+		if !ok {
+			return nil, fmt.Errorf("TLS version %s is not supported", config.TLSMinVersion)
+		}
+		tlsConfig.MinVersion = version
+	}
+
+	caPEM := []byte(config.TLSCAPEM)
+	if len(config.TLSCAFile) != 0 {
+		b, err := ioutil.ReadFile(config.TLSCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("Error reading CA file: %s", err)
+		}
+		caPEM = append(caPEM, '\n')
+		caPEM = append(caPEM, b...)
+	}
+	if len(caPEM) != 0 {
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(caPEM) {
+			return nil, fmt.Errorf("No CA certificates found")
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	certPEM := []byte(config.TLSCertPEM)
+	if len(config.TLSCertFile) != 0 {
+		b, err := ioutil.ReadFile(config.TLSCertFile)
+		if err != nil {
+			return nil, fmt.Errorf("Error reading client certificate: %s", err)
+		}
+		certPEM = b
+	}
+	keyPEM := []byte(config.TLSKeyPEM)
+	if len(config.TLSKeyFile) != 0 {
+		b, err := ioutil.ReadFile(config.TLSKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("Error reading client key: %s", err)
+		}
+		keyPEM = b
+	}
+	if len(certPEM) != 0 || len(keyPEM) != 0 {
+		if len(certPEM) == 0 || len(keyPEM) == 0 {
+			return nil, fmt.Errorf("Client certificate and key must both be set")
+		}
+		keyPEM, err := decryptKey(keyPEM, config.TLSKeyPassword)
+		if err != nil {
+			return nil, err
+		}
+		cert, err := tls.X509KeyPair(certPEM, keyPEM)
+		if err != nil {
+			return nil, fmt.Errorf("Error loading client certificate: %s", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	return tlsConfig, nil
+}
+
+// decryptKey decrypts a PEM encoded private key if it is encrypted. Both PKCS#8 encrypted keys and
+// the legacy PEM encryption (Proc-Type: 4,ENCRYPTED) are supported.
+func decryptKey(keyPEM []byte, password string) ([]byte, error) {
+	block, _ := pem.Decode(keyPEM)
+	if block == nil {
+		return nil, fmt.Errorf("No PEM data found in client key")
+	}
+	encrypted := block.Type == "ENCRYPTED PRIVATE KEY"
+	if !encrypted && !x509.IsEncryptedPEMBlock(block) {
+		return keyPEM, nil
+	}
+	if len(password) == 0 {
+		return nil, fmt.Errorf("Client key is encrypted but no password is set")
+	}
+	if encrypted {
+		der, err := decryptPKCS8(block.Bytes, []byte(password))
+		if err != nil {
+			return nil, fmt.Errorf("Error decrypting client key: %s", err)
+		}
+		return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), nil
+	}
+	der, err := x509.DecryptPEMBlock(block, []byte(password))
+	if err != nil {
+		return nil, fmt.Errorf("Error decrypting client key: %s", err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: block.Type, Bytes: der}), nil
+}
@@ -0,0 +1,139 @@
+package kafka_test
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/izolight/kafkalib/kafka"
+)
+
+// newTestCertificate creates a self signed certificate and returns the cert and key PEM encoded
+func newTestCertificate(t *testing.T) ([]byte, []byte, *rsa.PrivateKey) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "kafkalib test"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(365 * 24 * time.Hour),
+		DNSNames:              []string{"localhost"},
+		IPAddresses:           []net.IP{net.IPv4(127, 0, 0, 1)},
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth, x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+	return certPEM, keyPEM, key
+}
+
+func TestNewTLSConfig(t *testing.T) {
+	certPEM, keyPEM, key := newTestCertificate(t)
+	encryptedBlock, err := x509.EncryptPEMBlock(rand.Reader, "RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(key), []byte("secret"), x509.PEMCipherAES256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	encryptedKeyPEM := pem.EncodeToMemory(encryptedBlock)
+
+	dir, err := ioutil.TempDir("", "kafkalib")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	caFile := filepath.Join(dir, "ca.pem")
+	certFile := filepath.Join(dir, "cert.pem")
+	keyFile := filepath.Join(dir, "key.pem")
+	for file, content := range map[string][]byte{caFile: certPEM, certFile: certPEM, keyFile: encryptedKeyPEM} {
+		if err := ioutil.WriteFile(file, content, 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	testCases := []struct {
+		config  kafka.Config
+		certs   int
+		rootCAs bool
+		success bool
+	}{
+		{kafka.Config{}, 0, false, true},
+		{kafka.Config{TLSCAPEM: string(certPEM), TLSServerName: "broker", TLSMinVersion: "1.2"}, 0, true, true},
+		{kafka.Config{TLSCAFile: caFile, TLSCertPEM: string(certPEM), TLSKeyPEM: string(keyPEM)}, 1, true, true},
+		{kafka.Config{TLSCertFile: certFile, TLSKeyFile: keyFile, TLSKeyPassword: "secret"}, 1, false, true},
+		{kafka.Config{TLSCertFile: certFile, TLSKeyFile: keyFile, TLSKeyPassword: "wrong"}, 0, false, false},
+		{kafka.Config{TLSCertFile: certFile, TLSKeyFile: keyFile}, 0, false, false},
+		{kafka.Config{TLSCertFile: certFile}, 0, false, false},
+		{kafka.Config{TLSCAPEM: "no certificate"}, 0, false, false},
+		{kafka.Config{TLSCAFile: filepath.Join(dir, "missing.pem")}, 0, false, false},
+		{kafka.Config{TLSMinVersion: "2.0"}, 0, false, false},
+	}
+	for i, tc := range testCases {
+		tlsConfig, err := kafka.NewTLSConfig(&tc.config)
+		if err != nil && tc.success {
+			t.Fatalf("Case %d: %s", i, err)
+		}
+		if err == nil && !tc.success {
+			t.Fatalf("Case %d: should return an error", i)
+		}
+		if !tc.success {
+			continue
+		}
+		if len(tlsConfig.Certificates) != tc.certs {
+			t.Errorf("Case %d: got %d client certificates, expected %d", i, len(tlsConfig.Certificates), tc.certs)
+		}
+		if (tlsConfig.RootCAs != nil) != tc.rootCAs {
+			t.Errorf("Case %d: root CAs set is %t, expected %t", i, tlsConfig.RootCAs != nil, tc.rootCAs)
+		}
+		if tlsConfig.ServerName != tc.config.TLSServerName {
+			t.Errorf("Case %d: server name is %s, expected %s", i, tlsConfig.ServerName, tc.config.TLSServerName)
+		}
+		if tc.config.TLSMinVersion == "1.2" && tlsConfig.MinVersion != tls.VersionTLS12 {
+			t.Errorf("Case %d: min version is %x, expected %x", i, tlsConfig.MinVersion, tls.VersionTLS12)
+		}
+	}
+}
+
+func TestNewTLSConfig_PKCS8(t *testing.T) {
+	certFile := filepath.Join("testdata", "client-cert.pem")
+	testCases := []struct {
+		keyFile  string
+		password string
+		success  bool
+	}{
+		{"client-key-aes256.pem", "secret", true},
+		{"client-key-des3.pem", "secret", true},
+		{"client-key-aes256.pem", "wrong", false},
+		{"client-key-des3.pem", "wrong", false},
+		{"client-key-aes256.pem", "", false},
+	}
+	for i, tc := range testCases {
+		config := kafka.Config{TLSCertFile: certFile, TLSKeyFile: filepath.Join("testdata", tc.keyFile), TLSKeyPassword: tc.password}
+		tlsConfig, err := kafka.NewTLSConfig(&config)
+		if err != nil && tc.success {
+			t.Fatalf("Case %d: %s", i, err)
+		}
+		if err == nil && !tc.success {
+			t.Fatalf("Case %d: should return an error", i)
+		}
+		if tc.success && len(tlsConfig.Certificates) != 1 {
+			t.Errorf("Case %d: got %d client certificates, expected 1", i, len(tlsConfig.Certificates))
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package kafka
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Shopify/sarama"
+	"github.com/izolight/kafkalib/format"
+)
+
+// GetTopicConfig returns all config entries of a topic with their source and flags
+func (c Conn) GetTopicConfig(topic string) (*format.TopicConfig, error) {
+	return c.GetTopicConfigContext(context.Background(), topic)
+}
+
+// GetTopicConfigContext is GetTopicConfig with a context
+func (c Conn) GetTopicConfigContext(ctx context.Context, topic string) (*format.TopicConfig, error) {
+	var entries []sarama.ConfigEntry
+	err := call(ctx, "getting config of", topic, func() error {
+		var err error
+		entries, err = c.admin(ctx).DescribeConfig(sarama.ConfigResource{Type: sarama.TopicResource, Name: topic})
+		return err
+	})
+	if err != nil {
+		return nil, newError("getting config of", topic, err)
+	}
+	return format.FromConfigEntries(topic, entries), nil
+}
+
+// SetTopicConfig sets the given entries on a topic. AlterConfig replaces all overrides of a topic,
+// so the current overrides are read first and kept unless they are changed. Brokers before 1.1 don't
+// report config sources, there the values of the static broker config are written back as overrides
+// of the topic as well, see format.FromConfigEntries.
+func (c Conn) SetTopicConfig(topic string, entries map[string]string) error {
+	return c.SetTopicConfigContext(context.Background(), topic, entries)
+}
+
+// SetTopicConfigContext is SetTopicConfig with a context
+func (c Conn) SetTopicConfigContext(ctx context.Context, topic string, entries map[string]string) error {
+	return c.alterTopicConfig(ctx, "setting config of", topic, func(overrides map[string]*string) {
+		for k, v := range entries {
+			value := v
+			overrides[k] = &value
+		}
+	})
+}
+
+// UnsetTopicConfig removes the given overrides from a topic so the defaults apply again,
+// keys that are not overridden are ignored. It keeps the other overrides like SetTopicConfig.
+func (c Conn) UnsetTopicConfig(topic string, keys []string) error {
+	return c.UnsetTopicConfigContext(context.Background(), topic, keys)
+}
+
+// UnsetTopicConfigContext is UnsetTopicConfig with a context
+func (c Conn) UnsetTopicConfigContext(ctx context.Context, topic string, keys []string) error {
+	return c.alterTopicConfig(ctx, "unsetting config of", topic, func(overrides map[string]*string) {
+		for _, k := range keys {
+			delete(overrides, k)
+		}
+	})
+}
+
+// alterTopicConfig reads the current overrides of a topic, applies change and writes them back
+func (c Conn) alterTopicConfig(ctx context.Context, op, topic string, change func(overrides map[string]*string)) error {
+	current, err := c.GetTopicConfigContext(ctx, topic)
+	if err != nil {
+		return newError(op, topic, err)
+	}
+	overrides := map[string]*string{}
+	var sensitive []string
+	for _, e := range current.Overrides() {
+		if e.Sensitive {
+			sensitive = append(sensitive, e.Name)
+		}
+		value := e.Value
+		overrides[e.Name] = &value
+	}
+	change(overrides)
+	for _, name := range sensitive {
+		// the broker doesn't return sensitive values, writing them back would clear them
+		if value, ok := overrides[name]; ok && len(*value) == 0 {
+			return &Error{Op: op, Resource: topic, Kind: ErrInvalidConfig,
+				Err: fmt.Errorf("sensitive config %s can't be preserved, set it explicitly", name)}
+		}
+	}
+	err = call(ctx, op, topic, func() error {
+		return c.admin(ctx).AlterConfig(sarama.TopicResource, topic, overrides, false)
+	})
+	return newError(op, topic, err)
+}
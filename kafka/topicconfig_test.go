@@ -0,0 +1,66 @@
+package kafka_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/izolight/kafkalib/format"
+	"github.com/izolight/kafkalib/kafka"
+)
+
+// configValues returns the value and source of each entry by name
+func configValues(config *format.TopicConfig) map[string]format.ConfigEntry {
+	values := map[string]format.ConfigEntry{}
+	for _, e := range config.Entries {
+		values[e.Name] = e
+	}
+	return values
+}
+
+func TestTopicConfig(t *testing.T) {
+	c := kafka.Conn{
+		AdminClient: NewTestClient(),
+	}
+
+	if err := c.SetTopicConfig("simpleTopic", map[string]string{"retention.ms": "1000", "segment.ms": "100"}); err != nil {
+		t.Fatal(err)
+	}
+	// setting another key must keep the existing overrides
+	if err := c.SetTopicConfig("simpleTopic", map[string]string{"cleanup.policy": "compact"}); err != nil {
+		t.Fatal(err)
+	}
+	config, err := c.GetTopicConfig("simpleTopic")
+	if err != nil {
+		t.Fatal(err)
+	}
+	values := configValues(config)
+	for name, value := range map[string]string{"retention.ms": "1000", "segment.ms": "100", "cleanup.policy": "compact"} {
+		if values[name].Value != value || values[name].Source != format.ConfigSourceTopic {
+			t.Errorf("Expected %s to be overridden with %s but got %+v", name, value, values[name])
+		}
+	}
+
+	if err := c.UnsetTopicConfig("simpleTopic", []string{"retention.ms", "unknown"}); err != nil {
+		t.Fatal(err)
+	}
+	config, err = c.GetTopicConfig("simpleTopic")
+	if err != nil {
+		t.Fatal(err)
+	}
+	values = configValues(config)
+	if e := values["retention.ms"]; e.Source != format.ConfigSourceDefault || e.Value != "604800000" {
+		t.Errorf("Expected retention.ms to be the default but got %+v", e)
+	}
+	if len(config.Overrides()) != 2 {
+		t.Errorf("Expected 2 overrides but got %+v", config.Overrides())
+	}
+
+	_, err = c.GetTopicConfig("can't find topic")
+	if !errors.Is(err, kafka.ErrTopicNotFound) {
+		t.Errorf("Expected ErrTopicNotFound but got %v", err)
+	}
+	err = c.SetTopicConfig("can't find topic", map[string]string{"retention.ms": "1"})
+	if !errors.Is(err, kafka.ErrTopicNotFound) {
+		t.Errorf("Expected ErrTopicNotFound but got %v", err)
+	}
+}
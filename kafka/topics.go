@@ -1,7 +1,7 @@
 package kafka
 
 import (
-	"fmt"
+	"context"
 	"github.com/izolight/kafkalib/format"
 	"regexp"
 
@@ -16,46 +16,124 @@ type NewTopic struct {
 
 // GetTopic returns the topic defined in the Name of the client
 func (c Conn) GetTopic(filter string) (format.Topics, error) {
-	allTopics, err := c.AdminClient.ListTopics()
+	return c.GetTopicContext(context.Background(), filter)
+}
+
+// GetTopicContext is GetTopic with a context
+func (c Conn) GetTopicContext(ctx context.Context, filter string) (format.Topics, error) {
+	allTopics, err := c.GetAllTopicsContext(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("Error getting allTopics: %s", err)
+		return nil, err
 	}
-	topics := format.Topics{}
 	r, err := regexp.Compile(filter)
-	for k, v := range allTopics {
-		if r.MatchString(k) {
-			topics[k] = v
-		}
+	if err != nil {
+		return nil, &Error{Op: "getting topic", Resource: filter, Kind: ErrInvalidConfig, Err: err}
 	}
+	topics := allTopics.Filter(func(name string, _ sarama.TopicDetail) bool {
+		return r.MatchString(name)
+	})
 	if len(topics) > 0 {
 		return topics, nil
 	}
-	return nil, fmt.Errorf("Topic %s not found", filter)
+	return nil, newKindError("getting topic", filter, ErrTopicNotFound)
+}
+
+// GetSelectedTopics returns the topics matched by selector
+func (c Conn) GetSelectedTopics(selector Selector) (format.Topics, error) {
+	return c.GetSelectedTopicsContext(context.Background(), selector)
+}
+
+// GetSelectedTopicsContext is GetSelectedTopics with a context
+func (c Conn) GetSelectedTopicsContext(ctx context.Context, selector Selector) (format.Topics, error) {
+	allTopics, err := c.GetAllTopicsContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return allTopics.Filter(selector.Match), nil
 }
 
 // GetAllTopics returns all known topics
 func (c Conn) GetAllTopics() (format.Topics, error) {
-	topics, err := c.AdminClient.ListTopics()
+	return c.GetAllTopicsContext(context.Background())
+}
+
+// GetAllTopicsContext is GetAllTopics with a context
+func (c Conn) GetAllTopicsContext(ctx context.Context) (format.Topics, error) {
+	var topics map[string]sarama.TopicDetail
+	err := call(ctx, "getting topics", "", func() error {
+		var err error
+		topics, err = c.admin(ctx).ListTopics()
+		return err
+	})
 	if err != nil {
-		return nil, fmt.Errorf("Error getting topics: %s", err)
+		return nil, newError("getting topics", "", err)
 	}
 	return topics, nil
 }
 
 // CreateTopic creates the topic defined in the TopicClient
 func (c Conn) CreateTopic(topic NewTopic) error {
-	err := c.AdminClient.CreateTopic(topic.Name, &topic.TopicDetail, false)
+	return c.CreateTopicContext(context.Background(), topic)
+}
+
+// CreateTopicContext is CreateTopic with a context
+func (c Conn) CreateTopicContext(ctx context.Context, topic NewTopic) error {
+	return c.createTopic(ctx, topic, false)
+}
+
+// createTopic checks the name against the naming policy and creates the topic or only validates
+// the request with validateOnly
+func (c Conn) createTopic(ctx context.Context, topic NewTopic, validateOnly bool) error {
+	errs, err := c.checkTopicNames(ctx, []string{topic.Name})
 	if err != nil {
-		return fmt.Errorf("Error creating topic: %s", err)
+		return newError("creating topic", topic.Name, err)
 	}
-	return nil
+	if errs[0] != nil {
+		return errs[0]
+	}
+	err = call(ctx, "creating topic", topic.Name, func() error {
+		return c.admin(ctx).CreateTopic(topic.Name, &topic.TopicDetail, validateOnly)
+	})
+	return newError("creating topic", topic.Name, err)
 }
 
 // DeleteTopic deletes a topic
 func (c Conn) DeleteTopic(topic string) error {
-	err := c.AdminClient.DeleteTopic(topic)
-	if err != nil {
+	return c.DeleteTopicContext(context.Background(), topic)
+}
+
+// DeleteTopicContext is DeleteTopic with a context
+func (c Conn) DeleteTopicContext(ctx context.Context, topic string) error {
+	err := call(ctx, "deleting topic", topic, func() error {
+		return c.admin(ctx).DeleteTopic(topic)
+	})
+	return newError("deleting topic", topic, err)
+}
+
+// DescribeTopic returns the leader, replicas, ISR and offline replicas of each partition of a topic
+func (c Conn) DescribeTopic(topic string) (*format.TopicDescription, error) {
+	return c.DescribeTopicContext(context.Background(), topic)
+}
+
+// DescribeTopicContext is DescribeTopic with a context
+func (c Conn) DescribeTopicContext(ctx context.Context, topic string) (*format.TopicDescription, error) {
+	var metadata []*sarama.TopicMetadata
+	err := call(ctx, "describing topic", topic, func() error {
+		var err error
+		metadata, err = c.admin(ctx).DescribeTopics([]string{topic})
 		return err
+	})
+	if err != nil {
+		return nil, newError("describing topic", topic, err)
+	}
+	for _, m := range metadata {
+		if m.Name != topic {
+			continue
+		}
+		if m.Err != sarama.ErrNoError {
+			return nil, newError("describing topic", topic, m.Err)
+		}
+		return format.FromTopicMetadata(m), nil
 	}
-	return nil
+	return nil, newKindError("describing topic", topic, ErrTopicNotFound)
 }
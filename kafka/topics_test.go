@@ -1,6 +1,7 @@
 package kafka_test
 
 import (
+	"errors"
 	"github.com/izolight/kafkalib/kafka"
 	"testing"
 
@@ -139,3 +140,39 @@ func TestTopic_Delete(t *testing.T) {
 		}
 	}
 }
+
+func TestTopic_Describe(t *testing.T) {
+	testCases := []struct {
+		name       string
+		partitions int
+		leader     int32
+		replicas   int
+		success    bool
+	}{
+		{"simpleTopic", 1, 1, 1, true},
+		{"topicWithPartitionsAndReplicas", 4, 1, 3, true},
+		{"can't find topic", 0, 0, 0, false},
+	}
+	for _, tc := range testCases {
+		c := kafka.Conn{
+			AdminClient: NewTestClient(),
+		}
+		topic, err := c.DescribeTopic(tc.name)
+		if err != nil && tc.success {
+			t.Fatal(err)
+		}
+		if !tc.success {
+			if !errors.Is(err, kafka.ErrTopicNotFound) {
+				t.Errorf("Expected ErrTopicNotFound but got %v", err)
+			}
+			continue
+		}
+		if len(topic.Partitions) != tc.partitions {
+			t.Fatalf("Expected %d partitions but got %d", tc.partitions, len(topic.Partitions))
+		}
+		p := topic.Partitions[0]
+		if p.Leader != tc.leader || len(p.Replicas) != tc.replicas || len(p.ISR) != tc.replicas {
+			t.Errorf("Unexpected partition %+v", p)
+		}
+	}
+}
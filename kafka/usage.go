@@ -0,0 +1,187 @@
+package kafka
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/Shopify/sarama"
+	"github.com/izolight/kafkalib/format"
+)
+
+// UsageOptions controls what GetTopicUsage fetches
+type UsageOptions struct {
+	// Sizes fetches the size of each partition on the brokers with the DescribeLogDirs api
+	Sizes bool
+}
+
+// GetTopicUsage returns the watermarks and message counts of each partition of the topics matched by
+// selector, all topics are used if it is nil. The topics are sorted by name. Sizes need kafka 1.0.0,
+// on older brokers requesting them returns an error matching ErrUnsupported.
+func (c Conn) GetTopicUsage(selector Selector, options UsageOptions) (format.Usage, error) {
+	return c.GetTopicUsageContext(context.Background(), selector, options)
+}
+
+// GetTopicUsageContext is GetTopicUsage with a context
+func (c Conn) GetTopicUsageContext(ctx context.Context, selector Selector, options UsageOptions) (format.Usage, error) {
+	const op = "getting usage"
+	if selector == nil {
+		selector = SelectorFunc(func(string, sarama.TopicDetail) bool { return true })
+	}
+	topics, err := c.GetSelectedTopicsContext(ctx, selector)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(topics))
+	for name := range topics {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	usage := make(format.Usage, 0, len(names))
+	for _, name := range names {
+		var topic format.TopicUsage
+		err := call(ctx, op, name, func() error {
+			var err error
+			topic, err = c.topicUsage(name)
+			return err
+		})
+		if err != nil {
+			return nil, newError(op, name, err)
+		}
+		usage = append(usage, topic)
+	}
+	if options.Sizes {
+		if err := c.addSizes(ctx, usage); err != nil {
+			return nil, err
+		}
+	}
+	return usage, nil
+}
+
+// addSizes fills the sizes of the partitions in usage with a DescribeLogDirs request to each broker
+func (c Conn) addSizes(ctx context.Context, usage format.Usage) error {
+	const op = "getting usage"
+	var brokers []*sarama.Broker
+	err := call(ctx, op, "", func() error {
+		var err error
+		brokers, _, err = c.admin(ctx).DescribeCluster()
+		return err
+	})
+	if err != nil {
+		return newError(op, "", err)
+	}
+	for _, b := range brokers {
+		var sizes map[string]map[int32]int64
+		err := call(ctx, op, b.Addr(), func() error {
+			broker, err := openRawBroker(b.Addr(), c.saramaConfig())
+			if err != nil {
+				return err
+			}
+			defer broker.Close()
+			sizes, err = describeLogDirs(broker, usage)
+			return err
+		})
+		if err != nil {
+			return newError(op, b.Addr(), err)
+		}
+		for i, topic := range usage {
+			for j, p := range topic.Partitions {
+				size, ok := sizes[topic.Topic][p.Partition]
+				if !ok {
+					continue
+				}
+				if p.Sizes == nil {
+					usage[i].Partitions[j].Sizes = map[int32]int64{}
+				}
+				usage[i].Partitions[j].Sizes[b.ID()] = size
+			}
+		}
+	}
+	return nil
+}
+
+// describeLogDirs returns the size of the replicas on broker of the partitions in usage by topic
+// and partition. A replica that is moved to another log dir is only counted once.
+func describeLogDirs(broker *rawBroker, usage format.Usage) (map[string]map[int32]int64, error) {
+	const op = "getting usage"
+	version, ok := broker.version(apiKeyDescribeLogDirs, 1)
+	if !ok {
+		return nil, &Error{Op: op, Resource: broker.addr, Kind: ErrUnsupported, Err: fmt.Errorf("partition sizes need kafka %s", sarama.V1_0_0_0)}
+	}
+	e := &protocolEncoder{}
+	e.arrayLength(len(usage))
+	for _, topic := range usage {
+		e.string(topic.Topic)
+		e.arrayLength(len(topic.Partitions))
+		for _, p := range topic.Partitions {
+			e.int32(p.Partition)
+		}
+	}
+	d, err := broker.request(apiKeyDescribeLogDirs, version, false, e.buf.Bytes())
+	if err != nil {
+		return nil, err
+	}
+	d.int32() // throttle time
+	sizes := map[string]map[int32]int64{}
+	for i := d.arrayLength(); i > 0; i-- {
+		code := d.int16()
+		dir := "log dir " + d.string()
+		if err := kError(code, &dir); err != nil {
+			return nil, err
+		}
+		for j := d.arrayLength(); j > 0; j-- {
+			topic := d.string()
+			for k := d.arrayLength(); k > 0; k-- {
+				partition, size := d.int32(), d.int64()
+				d.int64() // offset lag
+				if future := d.bool(); future {
+					continue
+				}
+				if sizes[topic] == nil {
+					sizes[topic] = map[int32]int64{}
+				}
+				sizes[topic][partition] = size
+			}
+		}
+	}
+	return sizes, d.err
+}
+
+// topicUsage fetches the watermarks of all partitions of a topic
+func (c Conn) topicUsage(topic string) (format.TopicUsage, error) {
+	usage := format.TopicUsage{Topic: topic}
+	partitions, err := c.Client.Partitions(topic)
+	if err != nil {
+		return usage, err
+	}
+	// the client returns its cached slice, sort a copy
+	partitions = append([]int32{}, partitions...)
+	sort.Slice(partitions, func(i, j int) bool { return partitions[i] < partitions[j] })
+	for _, p := range partitions {
+		low, high, err := c.watermarks(topic, p)
+		if err != nil {
+			return usage, err
+		}
+		usage.Partitions = append(usage.Partitions, format.PartitionUsage{
+			Partition:     p,
+			LowWatermark:  low,
+			HighWatermark: high,
+			Messages:      high - low,
+		})
+	}
+	return usage, nil
+}
+
+// watermarks returns the offset of the first message and the offset the next message gets
+func (c Conn) watermarks(topic string, partition int32) (low, high int64, err error) {
+	low, err = c.Client.GetOffset(topic, partition, sarama.OffsetOldest)
+	if err != nil {
+		return 0, 0, err
+	}
+	high, err = c.Client.GetOffset(topic, partition, sarama.OffsetNewest)
+	if err != nil {
+		return 0, 0, err
+	}
+	return low, high, nil
+}
@@ -0,0 +1,136 @@
+package kafka_test
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/Shopify/sarama"
+	"github.com/izolight/kafkalib/kafka"
+)
+
+func TestGetTopicUsage(t *testing.T) {
+	c := kafka.Conn{AdminClient: NewTestClient(), Client: newTestSaramaClient()}
+	selector, err := kafka.ParseSelector("simpleTopic topicWithPartitions")
+	if err != nil {
+		t.Fatal(err)
+	}
+	usage, err := c.GetTopicUsage(selector, kafka.UsageOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	testCases := []struct {
+		topic      string
+		partitions int
+		messages   int64
+	}{
+		{"simpleTopic", 1, 90},
+		{"topicWithPartitions", 4, 70},
+	}
+	if len(usage) != len(testCases) {
+		t.Fatalf("Expected %d topics but got %d", len(testCases), len(usage))
+	}
+	for i, tc := range testCases {
+		if usage[i].Topic != tc.topic || len(usage[i].Partitions) != tc.partitions || usage[i].Messages() != tc.messages {
+			t.Errorf("Expected %s with %d partitions and %d messages but got %s with %d and %d", tc.topic, tc.partitions, tc.messages,
+				usage[i].Topic, len(usage[i].Partitions), usage[i].Messages())
+		}
+	}
+	if p := usage[1].Partitions[3]; p.LowWatermark != 20 || p.HighWatermark != 30 {
+		t.Errorf("Expected watermarks 20 and 30 but got %d and %d", p.LowWatermark, p.HighWatermark)
+	}
+}
+
+func TestGetTopicUsage_Errors(t *testing.T) {
+	c := kafka.Conn{AdminClient: NewTestClient(), Client: newTestSaramaClient()}
+	// topicWithReplicas has no partitions in the sarama client
+	if _, err := c.GetTopicUsage(nil, kafka.UsageOptions{}); !errors.Is(err, kafka.ErrTopicNotFound) {
+		t.Errorf("Expected ErrTopicNotFound but got %v", err)
+	}
+}
+
+// logDirsResponse encodes a DescribeLogDirs response with one log dir holding partitions of
+// simpleTopic with their sizes
+func logDirsResponse(code sarama.KError, sizes map[int32]int64, future int32) []byte {
+	w := (&testWriter{}).int32(0).int32(1).int16(int16(code)).str("/var/lib/kafka")
+	w.int32(1).str("simpleTopic").int32(int32(len(sizes)))
+	for partition, size := range sizes {
+		w.int32(partition).int64(size).int64(0).int8(0)
+	}
+	if future >= 0 {
+		w.int32(future).int64(1).int64(0).int8(1)
+	}
+	return w.Bytes()
+}
+
+func TestGetTopicUsage_Sizes(t *testing.T) {
+	responses := map[int32][]byte{
+		1: logDirsResponse(sarama.ErrNoError, map[int32]int64{0: 1000}, -1),
+		2: logDirsResponse(sarama.ErrNoError, map[int32]int64{0: 1200}, 0),
+		// broker 3 has no replica of simpleTopic
+		3: logDirsResponse(sarama.ErrNoError, nil, -1),
+	}
+	admin := NewTestClient()
+	admin.(*testClient).brokerAddrs = map[int32]string{}
+	brokers := map[int32]*testRawBroker{}
+	for id, response := range responses {
+		response := response
+		broker := newTestRawBroker(t, map[int16]int16{18: 0, 35: 1})
+		defer broker.Close()
+		broker.Handle(35, func(version int16, body []byte) []byte { return response })
+		admin.(*testClient).brokerAddrs[id] = broker.Addr()
+		brokers[id] = broker
+	}
+	c := kafka.Conn{AdminClient: admin, Client: newTestSaramaClient()}
+	selector, err := kafka.ParseSelector("simpleTopic")
+	if err != nil {
+		t.Fatal(err)
+	}
+	usage, err := c.GetTopicUsage(selector, kafka.UsageOptions{Sizes: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	request := (&testWriter{}).int32(1).str("simpleTopic").int32(1).int32(0).Bytes()
+	if requests := brokers[1].Requests(35); len(requests) != 1 || !bytes.Equal(requests[0], request) {
+		t.Errorf("Expected request %x but got %x", request, requests)
+	}
+	expected := map[int32]int64{1: 1000, 2: 1200}
+	if sizes := usage[0].Partitions[0].Sizes; !reflect.DeepEqual(sizes, expected) {
+		t.Errorf("Expected sizes %v but got %v", expected, sizes)
+	}
+	if size, ok := usage[0].Size(); !ok || size != 2200 {
+		t.Errorf("Expected a size of 2200 but got %d", size)
+	}
+}
+
+func TestGetTopicUsage_SizesErrors(t *testing.T) {
+	testCases := []struct {
+		name     string
+		versions map[int16]int16
+		code     sarama.KError
+		err      error
+	}{
+		{"unsupported", map[int16]int16{18: 0}, sarama.ErrNoError, kafka.ErrUnsupported},
+		{"unauthorized", map[int16]int16{18: 0, 35: 0}, sarama.ErrClusterAuthorizationFailed, kafka.ErrAuthorizationDenied},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			broker := newTestRawBroker(t, tc.versions)
+			defer broker.Close()
+			broker.Handle(35, func(version int16, body []byte) []byte {
+				return (&testWriter{}).int32(0).int32(1).int16(int16(tc.code)).str("/var/lib/kafka").int32(0).Bytes()
+			})
+			admin := NewTestClient()
+			admin.(*testClient).brokerAddrs = map[int32]string{1: broker.Addr(), 2: broker.Addr(), 3: broker.Addr()}
+			c := kafka.Conn{AdminClient: admin, Client: newTestSaramaClient()}
+			selector, err := kafka.ParseSelector("simpleTopic")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := c.GetTopicUsage(selector, kafka.UsageOptions{Sizes: true}); !errors.Is(err, tc.err) {
+				t.Errorf("Expected %s but got %v", tc.err, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,110 @@
+package kafka
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Shopify/sarama"
+	log "github.com/sirupsen/logrus"
+)
+
+// versionProbe describes an api version that was introduced with a kafka version
+type versionProbe struct {
+	version    sarama.KafkaVersion
+	apiKey     int16
+	maxVersion int16
+}
+
+// v2_4_0_0 is newer than the versions known by sarama
+var v2_4_0_0, _ = sarama.ParseKafkaVersion("2.4.0")
+
+// versionProbes are checked from newest to oldest, the first one supported by the broker wins.
+// Brokers newer than sarama.MaxVersion are capped to it as that is the highest version both sides support.
+var versionProbes = []versionProbe{
+	{v2_4_0_0, 45, 0},         // AlterPartitionReassignments
+	{sarama.V2_2_0_0, 43, 0},  // ElectPreferredLeaders
+	{sarama.V2_1_0_0, 1, 10},  // Fetch v10
+	{sarama.V2_0_0_0, 1, 8},   // Fetch v8
+	{sarama.V1_1_0_0, 1, 7},   // Fetch v7
+	{sarama.V1_0_0_0, 37, 0},  // CreatePartitions
+	{sarama.V0_11_0_0, 32, 0}, // DescribeConfigs
+	{sarama.V0_10_2_0, 9, 2},  // OffsetFetch v2
+	{sarama.V0_10_1_0, 19, 0}, // CreateTopics
+	{sarama.V0_10_0_0, 18, 0}, // ApiVersions
+}
+
+// autoVersion is the value of Config.Version that requests version negotiation
+const autoVersion = "auto"
+
+// parseVersion returns the explicitly configured version and whether one was set
+func parseVersion(version string) (sarama.KafkaVersion, bool, error) {
+	if len(version) == 0 || strings.EqualFold(version, autoVersion) {
+		return sarama.KafkaVersion{}, false, nil
+	}
+	v, err := sarama.ParseKafkaVersion(version)
+	if err != nil {
+		return v, false, fmt.Errorf("Error parsing kafka version: %s", err)
+	}
+	return v, true, nil
+}
+
+// NegotiateVersion asks the brokers for their supported api versions and returns the highest
+// kafka version supported by both the cluster and sarama. The brokers are tried in order until
+// one of them answers.
+func NegotiateVersion(brokerList []string, cfg *sarama.Config) (sarama.KafkaVersion, error) {
+	probeCfg := *cfg
+	// ApiVersions exists since 0.10.0, which is the lowest version we can detect
+	probeCfg.Version = sarama.V0_10_0_0
+
+	var lastErr error
+	for _, addr := range brokerList {
+		versions, err := brokerAPIVersions(addr, &probeCfg)
+		if err != nil {
+			log.Debugf("Error getting api versions from %s: %s", addr, err)
+			lastErr = err
+			continue
+		}
+		version := versionFromAPIVersions(versions)
+		log.Debugf("Negotiated kafka version %s with %s", version, addr)
+		return version, nil
+	}
+	if lastErr == nil {
+		lastErr = fmt.Errorf("no brokers configured")
+	}
+	return sarama.KafkaVersion{}, fmt.Errorf("Error negotiating kafka version, set Config.Version explicitly for brokers older than 0.10: %w", lastErr)
+}
+
+// brokerAPIVersions returns the max supported version for each api key of a broker
+func brokerAPIVersions(addr string, cfg *sarama.Config) (map[int16]int16, error) {
+	broker := sarama.NewBroker(addr)
+	if err := broker.Open(cfg); err != nil {
+		return nil, err
+	}
+	defer broker.Close()
+
+	resp, err := broker.ApiVersions(&sarama.ApiVersionsRequest{})
+	if err != nil {
+		return nil, err
+	}
+	if resp.Err != sarama.ErrNoError {
+		return nil, resp.Err
+	}
+	versions := make(map[int16]int16, len(resp.ApiVersions))
+	for _, block := range resp.ApiVersions {
+		versions[block.ApiKey] = block.MaxVersion
+	}
+	return versions, nil
+}
+
+// versionFromAPIVersions maps the supported api versions to a kafka version
+func versionFromAPIVersions(versions map[int16]int16) sarama.KafkaVersion {
+	for _, probe := range versionProbes {
+		if max, ok := versions[probe.apiKey]; ok && max >= probe.maxVersion {
+			if probe.version.IsAtLeast(sarama.MaxVersion) {
+				return sarama.MaxVersion
+			}
+			return probe.version
+		}
+	}
+	return sarama.V0_10_0_0
+}
@@ -0,0 +1,70 @@
+package kafka_test
+
+import (
+	"testing"
+
+	"github.com/Shopify/sarama"
+	"github.com/izolight/kafkalib/kafka"
+)
+
+func TestNegotiateVersion(t *testing.T) {
+	testCases := []struct {
+		versions map[int16]int16
+		expected sarama.KafkaVersion
+	}{
+		{map[int16]int16{18: 0}, sarama.V0_10_0_0},
+		{map[int16]int16{1: 3, 9: 1, 18: 0, 19: 0}, sarama.V0_10_1_0},
+		{map[int16]int16{1: 5, 9: 3, 18: 1, 19: 1, 32: 0}, sarama.V0_11_0_0},
+		{map[int16]int16{1: 7, 18: 1, 19: 2, 32: 1, 37: 0}, sarama.V1_1_0_0},
+		{map[int16]int16{1: 10, 18: 2, 19: 3, 32: 2, 37: 1}, sarama.V2_1_0_0},
+		{map[int16]int16{1: 11, 18: 2, 19: 3, 32: 2, 37: 1, 43: 1, 44: 0}, sarama.V2_2_0_0},
+		{map[int16]int16{1: 11, 18: 3, 19: 5, 32: 2, 37: 2, 43: 2, 44: 1, 45: 0}, sarama.MaxVersion},
+	}
+	for _, tc := range testCases {
+		broker := sarama.NewMockBroker(t, 1)
+		broker.SetHandlerByMap(map[string]sarama.MockResponse{
+			"ApiVersionsRequest": newAPIVersionsResponse(tc.versions),
+		})
+		version, err := kafka.NegotiateVersion([]string{broker.Addr()}, sarama.NewConfig())
+		broker.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if version != tc.expected {
+			t.Errorf("Negotiated version %s, expected %s", version, tc.expected)
+		}
+	}
+}
+
+func TestNegotiateVersion_NoBroker(t *testing.T) {
+	broker := sarama.NewMockBroker(t, 1)
+	addr := broker.Addr()
+	broker.Close()
+	if _, err := kafka.NegotiateVersion([]string{addr}, sarama.NewConfig()); err == nil {
+		t.Fatal("Should return an error when no broker is reachable")
+	}
+}
+
+func TestNewSaramaConfig_Version(t *testing.T) {
+	testCases := []struct {
+		version  string
+		expected sarama.KafkaVersion
+		success  bool
+	}{
+		{"2.1.0", sarama.V2_1_0_0, true},
+		{"auto", sarama.NewConfig().Version, true},
+		{"not a version", sarama.KafkaVersion{}, false},
+	}
+	for _, tc := range testCases {
+		cfg, err := kafka.NewSaramaConfig(&kafka.Config{Version: tc.version})
+		if err != nil && tc.success {
+			t.Fatal(err)
+		}
+		if err == nil && !tc.success {
+			t.Fatal("Should return an error")
+		}
+		if tc.success && cfg.Version != tc.expected {
+			t.Errorf("Version is %s, expected %s", cfg.Version, tc.expected)
+		}
+	}
+}